@@ -0,0 +1,496 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// accountData holds the IAM state of an account loaded in a single bulk pass
+type accountData struct {
+	roles    []RoleItem
+	users    []UserItem
+	groups   []GroupItem
+	policies map[string]PolicyItem // Managed policies keyed by ARN, documents included
+}
+
+// UserItem represents an IAM user
+type UserItem struct {
+	userName string
+	userArn  string
+	groups   []string
+	policies []PolicyItem
+}
+
+// GroupItem represents an IAM group
+type GroupItem struct {
+	groupName string
+	groupArn  string
+	policies  []PolicyItem
+}
+
+// accountLoad is a bulk account load in flight, its response is dropped once the load was replaced or stopped
+type accountLoad struct {
+	cancel context.CancelFunc
+}
+
+// accountLoadedMsg is sent when the bulk account load finishes
+type accountLoadedMsg struct {
+	load *accountLoad
+	data *accountData
+	err  error
+}
+
+// findRole returns the bulk-loaded role with the given name
+func (a *accountData) findRole(roleName string) (RoleItem, bool) {
+	for _, role := range a.roles {
+		if role.roleName == roleName {
+			return role, true
+		}
+	}
+	return RoleItem{}, false
+}
+
+// attachBulkDocuments adds the documents the bulk load already fetched to the policies of a role
+func (m model) attachBulkDocuments(roleName string, policies []PolicyItem) {
+	if m.account == nil {
+		return
+	}
+	bulkRole, _ := m.account.findRole(roleName)
+	for i, policy := range policies {
+		if loaded, ok := m.account.policies[policy.policyArn]; ok {
+			policies[i].rawDocument = loaded.rawDocument
+		}
+		if policy.policyType == "Inline" {
+			for _, inline := range bulkRole.policies {
+				if inline.policyType == "Inline" && inline.policyName == policy.policyName {
+					policies[i].rawDocument = inline.rawDocument
+				}
+			}
+		}
+	}
+}
+
+// Load roles, users, groups and all managed policy documents in one paginated pass
+func loadAccountDetailsCmd(ctx context.Context, session awsSession, load *accountLoad) tea.Cmd {
+	return func() tea.Msg {
+		// Load AWS configuration with shared config
+		cfg, err := session.loadConfig(ctx)
+		if err != nil {
+			return accountLoadedMsg{load: load, err: fmt.Errorf("error loading AWS configuration: %w", err)}
+		}
+
+		data, err := loadAccountDetails(ctx, session.iamClient(cfg))
+		if err != nil {
+			return accountLoadedMsg{load: load, err: err}
+		}
+		return accountLoadedMsg{load: load, data: data}
+	}
+}
+
+// startAccountLoad starts the bulk load of a session's account, replacing a running one
+func (m *model) startAccountLoad(session awsSession) tea.Cmd {
+	m.stopAccountLoad()
+	// Opening another account cancels the load too
+	ctx, cancel := context.WithCancel(m.screenContext("roles"))
+	m.accountLoad = &accountLoad{cancel: cancel}
+	return loadAccountDetailsCmd(ctx, session, m.accountLoad)
+}
+
+// stopAccountLoad cancels the running bulk load, its response is dropped
+func (m *model) stopAccountLoad() {
+	if m.accountLoad != nil {
+		m.accountLoad.cancel()
+		m.accountLoad = nil
+	}
+}
+
+// loadAccountDetails pages through GetAccountAuthorizationDetails and builds the account data
+func loadAccountDetails(ctx context.Context, iamClient iamAPI) (*accountData, error) {
+	var (
+		roles    []types.RoleDetail
+		users    []types.UserDetail
+		groups   []types.GroupDetail
+		policies []types.ManagedPolicyDetail
+	)
+	paginator := iam.NewGetAccountAuthorizationDetailsPaginator(iamClient, &iam.GetAccountAuthorizationDetailsInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error getting account authorization details: %w", err)
+		}
+		roles = append(roles, page.RoleDetailList...)
+		users = append(users, page.UserDetailList...)
+		groups = append(groups, page.GroupDetailList...)
+		policies = append(policies, page.Policies...)
+	}
+
+	return buildAccountData(roles, users, groups, policies)
+}
+
+// buildAccountData converts authorization details into the list models used by the UI
+func buildAccountData(roles []types.RoleDetail, users []types.UserDetail, groups []types.GroupDetail, policies []types.ManagedPolicyDetail) (*accountData, error) {
+	data := &accountData{policies: make(map[string]PolicyItem)}
+
+	// Managed policies first so attachments can reference their documents
+	for _, policy := range policies {
+		policyArn := aws.ToString(policy.Arn)
+		item := PolicyItem{
+			policyName:       aws.ToString(policy.PolicyName),
+			policyArn:        policyArn,
+			policyType:       managedPolicyType(policyArn),
+			defaultVersionId: aws.ToString(policy.DefaultVersionId),
+		}
+		for _, version := range policy.PolicyVersionList {
+			if !version.IsDefaultVersion && aws.ToString(version.VersionId) != item.defaultVersionId {
+				continue
+			}
+			doc, err := decodeURLEncodedDocument(aws.ToString(version.Document))
+			if err != nil {
+				return nil, fmt.Errorf("error decoding policy document for %s: %w", policyArn, err)
+			}
+			item.rawDocument = doc
+			break
+		}
+		data.policies[policyArn] = item
+	}
+
+	for _, role := range roles {
+		inline, err := inlinePolicyItems(role.RolePolicyList)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding inline policies for role %s: %w", aws.ToString(role.RoleName), err)
+		}
+		trustPolicy, err := decodeURLEncodedDocument(aws.ToString(role.AssumeRolePolicyDocument))
+		if err != nil {
+			return nil, fmt.Errorf("error decoding trust policy for role %s: %w", aws.ToString(role.RoleName), err)
+		}
+
+		item := RoleItem{
+			roleName:       aws.ToString(role.RoleName),
+			roleArn:        aws.ToString(role.Arn),
+			description:    fmt.Sprintf("ARN: %s", aws.ToString(role.Arn)),
+			path:           aws.ToString(role.Path),
+			trustPolicy:    trustPolicy,
+			tags:           tagMap(role.Tags),
+			policies:       append(data.attachedPolicyItems(role.AttachedManagedPolicies), inline...),
+			policiesLoaded: true,
+		}
+		if role.PermissionsBoundary != nil {
+			item.permissionsBoundary = aws.ToString(role.PermissionsBoundary.PermissionsBoundaryArn)
+		}
+		item.policyCount = len(item.policies)
+		data.roles = append(data.roles, item)
+	}
+
+	for _, user := range users {
+		inline, err := inlinePolicyItems(user.UserPolicyList)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding inline policies for user %s: %w", aws.ToString(user.UserName), err)
+		}
+		data.users = append(data.users, UserItem{
+			userName: aws.ToString(user.UserName),
+			userArn:  aws.ToString(user.Arn),
+			groups:   user.GroupList,
+			policies: append(data.attachedPolicyItems(user.AttachedManagedPolicies), inline...),
+		})
+	}
+
+	for _, group := range groups {
+		inline, err := inlinePolicyItems(group.GroupPolicyList)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding inline policies for group %s: %w", aws.ToString(group.GroupName), err)
+		}
+		data.groups = append(data.groups, GroupItem{
+			groupName: aws.ToString(group.GroupName),
+			groupArn:  aws.ToString(group.Arn),
+			policies:  append(data.attachedPolicyItems(group.AttachedManagedPolicies), inline...),
+		})
+	}
+
+	return data, nil
+}
+
+// attachedPolicyItems resolves attached managed policies against the loaded policy documents
+func (a *accountData) attachedPolicyItems(attached []types.AttachedPolicy) []PolicyItem {
+	var items []PolicyItem
+	for _, policy := range attached {
+		policyArn := aws.ToString(policy.PolicyArn)
+		item, ok := a.policies[policyArn]
+		if !ok {
+			// Policy details can be missing when the caller lacks access to them
+			item = PolicyItem{
+				policyName: aws.ToString(policy.PolicyName),
+				policyArn:  policyArn,
+				policyType: managedPolicyType(policyArn),
+			}
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// inlinePolicyItems converts embedded inline policies into policy items
+func inlinePolicyItems(details []types.PolicyDetail) ([]PolicyItem, error) {
+	var items []PolicyItem
+	for _, detail := range details {
+		doc, err := decodeURLEncodedDocument(aws.ToString(detail.PolicyDocument))
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, PolicyItem{
+			policyName:  aws.ToString(detail.PolicyName),
+			policyType:  "Inline",
+			rawDocument: doc,
+		})
+	}
+	return items, nil
+}
+
+// tagMap converts IAM tags into a map
+func tagMap(tags []types.Tag) map[string]string {
+	if len(tags) == 0 {
+		return nil
+	}
+	result := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		result[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return result
+}
+
+// mergeAccountRoles fills the roles list with bulk-loaded details, keeping list-only fields
+func (m *model) mergeAccountRoles() {
+	if m.account == nil {
+		return
+	}
+
+	existing := make(map[string]*RoleItem)
+	items := m.rolesList.Items()
+	for _, item := range items {
+		if role, ok := item.(*RoleItem); ok {
+			existing[role.roleName] = role
+		}
+	}
+
+	for _, detail := range m.account.roles {
+		role, ok := existing[detail.roleName]
+		if !ok {
+			roleCopy := detail
+			items = append(items, &roleCopy)
+			continue
+		}
+		// Update in place so pointers held by the model stay valid
+		role.path = detail.path
+		role.trustPolicy = detail.trustPolicy
+		role.permissionsBoundary = detail.permissionsBoundary
+		role.tags = detail.tags
+		role.policies = detail.policies
+		role.policiesLoaded = true
+		role.policyCount = detail.policyCount
+	}
+	m.rolesList.SetItems(items)
+}
+
+// accountRecord is the JSON form of accountData used for on-disk storage
+type accountRecord struct {
+	Roles    []roleRecord   `json:"roles"`
+	Users    []userRecord   `json:"users,omitempty"`
+	Groups   []groupRecord  `json:"groups,omitempty"`
+	Policies []policyRecord `json:"policies,omitempty"`
+}
+
+type roleRecord struct {
+	RoleName            string            `json:"roleName"`
+	RoleArn             string            `json:"roleArn"`
+	Description         string            `json:"description,omitempty"`
+	Path                string            `json:"path,omitempty"`
+	TrustPolicy         string            `json:"trustPolicy,omitempty"`
+	PermissionsBoundary string            `json:"permissionsBoundary,omitempty"`
+	MaxSessionDuration  int32             `json:"maxSessionDuration,omitempty"`
+	Tags                map[string]string `json:"tags,omitempty"`
+	PoliciesLoaded      bool              `json:"policiesLoaded"`
+	Policies            []policyRecord    `json:"policies,omitempty"`
+}
+
+type userRecord struct {
+	UserName string         `json:"userName"`
+	UserArn  string         `json:"userArn"`
+	Groups   []string       `json:"groups,omitempty"`
+	Policies []policyRecord `json:"policies,omitempty"`
+}
+
+type groupRecord struct {
+	GroupName string         `json:"groupName"`
+	GroupArn  string         `json:"groupArn"`
+	Policies  []policyRecord `json:"policies,omitempty"`
+}
+
+type policyRecord struct {
+	PolicyName       string `json:"policyName"`
+	PolicyArn        string `json:"policyArn,omitempty"`
+	PolicyType       string `json:"policyType"`
+	DefaultVersionId string `json:"defaultVersionId,omitempty"`
+	Document         string `json:"document,omitempty"`
+}
+
+// currentAccountData combines the roles list with bulk-loaded details
+func (m model) currentAccountData() *accountData {
+	data := &accountData{policies: make(map[string]PolicyItem)}
+	if m.account != nil {
+		data.users = m.account.users
+		data.groups = m.account.groups
+		for policyArn, policy := range m.account.policies {
+			data.policies[policyArn] = policy
+		}
+	}
+
+	for _, item := range m.rolesList.Items() {
+		role, ok := item.(*RoleItem)
+		if !ok {
+			continue
+		}
+		data.roles = append(data.roles, *role)
+		// Keep documents of policies loaded on demand
+		for _, policy := range role.policies {
+			if _, known := data.policies[policy.policyArn]; !known && policy.policyArn != "" && policy.rawDocument != "" {
+				data.policies[policy.policyArn] = policy
+			}
+		}
+	}
+	return data
+}
+
+// record converts account data into its JSON form
+func (a *accountData) record() accountRecord {
+	var record accountRecord
+	for _, policy := range a.policies {
+		record.Policies = append(record.Policies, policyToRecord(policy, nil))
+	}
+	// Map iteration order is random, keep the output stable
+	sort.Slice(record.Policies, func(i, j int) bool {
+		return record.Policies[i].PolicyArn < record.Policies[j].PolicyArn
+	})
+
+	for _, role := range a.roles {
+		record.Roles = append(record.Roles, roleRecord{
+			RoleName:            role.roleName,
+			RoleArn:             role.roleArn,
+			Description:         role.description,
+			Path:                role.path,
+			TrustPolicy:         role.trustPolicy,
+			PermissionsBoundary: role.permissionsBoundary,
+			MaxSessionDuration:  role.maxSessionDuration,
+			Tags:                role.tags,
+			PoliciesLoaded:      role.policiesLoaded,
+			Policies:            a.policyRecords(role.policies),
+		})
+	}
+	for _, user := range a.users {
+		record.Users = append(record.Users, userRecord{
+			UserName: user.userName,
+			UserArn:  user.userArn,
+			Groups:   user.groups,
+			Policies: a.policyRecords(user.policies),
+		})
+	}
+	for _, group := range a.groups {
+		record.Groups = append(record.Groups, groupRecord{
+			GroupName: group.groupName,
+			GroupArn:  group.groupArn,
+			Policies:  a.policyRecords(group.policies),
+		})
+	}
+	return record
+}
+
+// policyRecords converts attached policies, leaving out documents stored with the managed policies
+func (a *accountData) policyRecords(policies []PolicyItem) []policyRecord {
+	var records []policyRecord
+	for _, policy := range policies {
+		records = append(records, policyToRecord(policy, a.policies))
+	}
+	return records
+}
+
+func policyToRecord(policy PolicyItem, managed map[string]PolicyItem) policyRecord {
+	record := policyRecord{
+		PolicyName:       policy.policyName,
+		PolicyArn:        policy.policyArn,
+		PolicyType:       policy.policyType,
+		DefaultVersionId: policy.defaultVersionId,
+		Document:         policy.rawDocument,
+	}
+	if _, ok := managed[policy.policyArn]; ok {
+		record.Document = ""
+	}
+	return record
+}
+
+// data converts a stored record back into account data
+func (r accountRecord) data() *accountData {
+	data := &accountData{policies: make(map[string]PolicyItem)}
+	for _, policy := range r.Policies {
+		data.policies[policy.PolicyArn] = policyFromRecord(policy, nil)
+	}
+
+	for _, role := range r.Roles {
+		item := RoleItem{
+			roleName:            role.RoleName,
+			roleArn:             role.RoleArn,
+			description:         role.Description,
+			path:                role.Path,
+			trustPolicy:         role.TrustPolicy,
+			permissionsBoundary: role.PermissionsBoundary,
+			maxSessionDuration:  role.MaxSessionDuration,
+			tags:                role.Tags,
+			policiesLoaded:      role.PoliciesLoaded,
+			policies:            data.policiesFromRecords(role.Policies),
+		}
+		if item.policiesLoaded {
+			item.policyCount = len(item.policies)
+		}
+		data.roles = append(data.roles, item)
+	}
+	for _, user := range r.Users {
+		data.users = append(data.users, UserItem{
+			userName: user.UserName,
+			userArn:  user.UserArn,
+			groups:   user.Groups,
+			policies: data.policiesFromRecords(user.Policies),
+		})
+	}
+	for _, group := range r.Groups {
+		data.groups = append(data.groups, GroupItem{
+			groupName: group.GroupName,
+			groupArn:  group.GroupArn,
+			policies:  data.policiesFromRecords(group.Policies),
+		})
+	}
+	return data
+}
+
+func (a *accountData) policiesFromRecords(records []policyRecord) []PolicyItem {
+	var policies []PolicyItem
+	for _, record := range records {
+		policies = append(policies, policyFromRecord(record, a.policies))
+	}
+	return policies
+}
+
+func policyFromRecord(record policyRecord, managed map[string]PolicyItem) PolicyItem {
+	if policy, ok := managed[record.PolicyArn]; ok && record.Document == "" {
+		return policy
+	}
+	return PolicyItem{
+		policyName:       record.PolicyName,
+		policyArn:        record.PolicyArn,
+		policyType:       record.PolicyType,
+		defaultVersionId: record.DefaultVersionId,
+		rawDocument:      record.Document,
+	}
+}
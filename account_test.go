@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Test building account data from authorization details
+func TestBuildAccountData(t *testing.T) {
+	managedArn := "arn:aws:iam::123456789012:policy/AppPolicy"
+	roles := []types.RoleDetail{
+		{
+			RoleName:                 aws.String("AppRole"),
+			Arn:                      aws.String("arn:aws:iam::123456789012:role/AppRole"),
+			AssumeRolePolicyDocument: aws.String("%7B%22Version%22%3A%222012-10-17%22%7D"),
+			AttachedManagedPolicies: []types.AttachedPolicy{
+				{PolicyName: aws.String("AppPolicy"), PolicyArn: aws.String(managedArn)},
+				{PolicyName: aws.String("ReadOnlyAccess"), PolicyArn: aws.String("arn:aws:iam::aws:policy/ReadOnlyAccess")},
+			},
+			RolePolicyList: []types.PolicyDetail{
+				{PolicyName: aws.String("Inline"), PolicyDocument: aws.String("%7B%7D")},
+			},
+			PermissionsBoundary: &types.AttachedPermissionsBoundary{PermissionsBoundaryArn: aws.String(managedArn)},
+			Tags:                []types.Tag{{Key: aws.String("env"), Value: aws.String("prod")}},
+		},
+	}
+	users := []types.UserDetail{
+		{UserName: aws.String("alice"), GroupList: []string{"admins"}},
+	}
+	groups := []types.GroupDetail{
+		{GroupName: aws.String("admins"), AttachedManagedPolicies: []types.AttachedPolicy{{PolicyArn: aws.String(managedArn)}}},
+	}
+	policies := []types.ManagedPolicyDetail{
+		{
+			PolicyName:       aws.String("AppPolicy"),
+			Arn:              aws.String(managedArn),
+			DefaultVersionId: aws.String("v2"),
+			PolicyVersionList: []types.PolicyVersion{
+				{VersionId: aws.String("v1"), Document: aws.String("%7B%22old%22%3Atrue%7D")},
+				{VersionId: aws.String("v2"), IsDefaultVersion: true, Document: aws.String("%7B%22new%22%3Atrue%7D")},
+			},
+		},
+	}
+
+	data, err := buildAccountData(roles, users, groups, policies)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if got := data.policies[managedArn].rawDocument; got != `{"new":true}` {
+		t.Errorf("Expected default version document, got '%s'", got)
+	}
+
+	role, ok := data.findRole("AppRole")
+	if !ok {
+		t.Fatalf("Expected AppRole to be loaded")
+	}
+	if role.trustPolicy != `{"Version":"2012-10-17"}` {
+		t.Errorf("Expected decoded trust policy, got '%s'", role.trustPolicy)
+	}
+	if len(role.policies) != 3 || role.policyCount != 3 || !role.policiesLoaded {
+		t.Errorf("Expected 3 loaded policies, got %d (count %d)", len(role.policies), role.policyCount)
+	}
+	if role.policies[0].rawDocument != `{"new":true}` {
+		t.Errorf("Expected attached policy to carry its document")
+	}
+	if role.policies[1].policyType != "AWS" {
+		t.Errorf("Expected AWS managed policy type, got '%s'", role.policies[1].policyType)
+	}
+	if role.policies[2].policyType != "Inline" || role.policies[2].rawDocument != "{}" {
+		t.Errorf("Expected inline policy with document, got %+v", role.policies[2])
+	}
+	if role.permissionsBoundary != managedArn || role.tags["env"] != "prod" {
+		t.Errorf("Expected boundary and tags to be set, got '%s' %v", role.permissionsBoundary, role.tags)
+	}
+
+	if len(data.users) != 1 || data.users[0].groups[0] != "admins" {
+		t.Errorf("Expected user with group membership, got %+v", data.users)
+	}
+	if len(data.groups) != 1 || len(data.groups[0].policies) != 1 {
+		t.Errorf("Expected group with one policy, got %+v", data.groups)
+	}
+}
+
+// Test merging bulk-loaded roles into the roles list
+func TestMergeAccountRoles(t *testing.T) {
+	m := createTestModel()
+	listed := &RoleItem{roleName: "AppRole", description: "Listed description"}
+	m.rolesList.SetItems([]list.Item{listed})
+	m.account = &accountData{
+		roles: []RoleItem{
+			{roleName: "AppRole", policies: []PolicyItem{{policyName: "P"}}, policiesLoaded: true, policyCount: 1},
+			{roleName: "OtherRole", description: "ARN: other"},
+		},
+	}
+
+	m.mergeAccountRoles()
+
+	if len(m.rolesList.Items()) != 2 {
+		t.Fatalf("Expected 2 roles after merge, got %d", len(m.rolesList.Items()))
+	}
+	if !listed.policiesLoaded || len(listed.policies) != 1 {
+		t.Errorf("Expected existing role to be updated in place")
+	}
+	if listed.description != "Listed description" {
+		t.Errorf("Expected listed description to be kept, got '%s'", listed.description)
+	}
+}
+
+// Test that bulk-loaded documents open without a request
+func TestPolicyOpensFromAccountData(t *testing.T) {
+	m := createTestModel()
+	policyArn := "arn:aws:iam::123456789012:policy/AppPolicy"
+	m.account = &accountData{policies: map[string]PolicyItem{
+		policyArn: {policyArn: policyArn, rawDocument: `{"Version":"2012-10-17"}`},
+	}}
+	m.currentScreen = "policies"
+	m.policiesList.SetItems([]list.Item{&PolicyItem{policyName: "AppPolicy", policyArn: policyArn}})
+
+	newModel, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	updatedModel := newModel.(model)
+
+	if cmd != nil || updatedModel.loading() {
+		t.Errorf("Expected document to open without loading")
+	}
+	if !updatedModel.selectedPolicy.documentLoaded {
+		t.Errorf("Expected document to be marked loaded")
+	}
+}
+
+// Test inline documents come from the bulk load when a role is opened, and are loaded when opened otherwise
+func TestInlinePolicyDocuments(t *testing.T) {
+	f := newTestBackend()
+	f.addRole(fakeRole{name: "InlineRole", inline: map[string]string{"a": `{"Statement": []}`}})
+	m := createTestModel()
+	m.backend = f
+	role := &RoleItem{roleName: "InlineRole"}
+	m.selectedRole = role
+	m.account = &accountData{roles: []RoleItem{{roleName: "InlineRole", policies: []PolicyItem{{policyName: "a", policyType: "Inline", rawDocument: `{"Bulk": true}`}}}}}
+
+	newModel, _ := m.Update(loadRolePoliciesCmd(context.Background(), m.session(), "InlineRole")())
+	m = newModel.(model)
+	if len(role.policies) != 1 || role.policies[0].rawDocument != `{"Bulk": true}` || f.callCount("GetRolePolicy") != 0 {
+		t.Fatalf("Expected the bulk-loaded inline document, got %+v", role.policies)
+	}
+
+	// Without the bulk load the document is requested when the policy is opened
+	m.account = nil
+	m.policiesList.SetItems([]list.Item{&PolicyItem{policyName: "a", policyType: "Inline"}})
+	m.currentScreen = "policies"
+	newModel, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = newModel.(model)
+	newModel, _ = m.Update(requestResponse(t, cmd))
+	m = newModel.(model)
+	if !m.selectedPolicy.documentLoaded || !strings.Contains(m.policyDocument, "Statement") || f.callCount("GetRolePolicy") != 1 {
+		t.Errorf("Expected the inline document to be loaded on open, got '%s'", m.policyDocument)
+	}
+}
+
+// Test a bulk load of the previous profile that lands after switching is dropped
+func TestStaleAccountLoadDropped(t *testing.T) {
+	m := createTestModel()
+	m.backend = newTestBackend()
+	stale := m.startAccountLoad(m.session())()
+
+	m.switchProfile("other")
+	m.rolesList.SetItems([]list.Item{&RoleItem{roleName: "OtherRole"}})
+	newModel, _ := m.Update(stale)
+	m = newModel.(model)
+	if m.account != nil || len(m.rolesList.Items()) != 1 {
+		t.Errorf("Expected the previous profile's account to be dropped, got %d roles", len(m.rolesList.Items()))
+	}
+}
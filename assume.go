@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// assumedIdentity is a role session browsed on top of the profile's own identity
+type assumedIdentity struct {
+	session    awsSession // Profile and temporary credentials of the role session
+	roleName   string
+	arn        string // ARN of the assumed role session
+	expiration time.Time
+}
+
+// assumeRequest holds the assume form values
+type assumeRequest struct {
+	roleArn     string
+	roleName    string
+	sessionName string
+	duration    time.Duration
+	mfaSerial   string // Empty when no MFA is required
+	mfaCode     string
+}
+
+// roleAssumedMsg is sent when sts:AssumeRole succeeded or failed
+type roleAssumedMsg struct {
+	identity assumedIdentity
+	err      error
+}
+
+// Fields of the assume form
+const (
+	assumeSessionName = iota
+	assumeDuration
+	assumeMFASerial
+	assumeMFACode
+)
+
+// newAssumeInputs creates the assume form fields with their defaults
+func newAssumeInputs() []textinput.Model {
+	sessionName := textinput.New()
+	sessionName.Prompt = "Session name: "
+	sessionName.SetValue("atui")
+
+	duration := textinput.New()
+	duration.Prompt = "Duration:     "
+	duration.SetValue("1h")
+
+	mfaSerial := textinput.New()
+	mfaSerial.Prompt = "MFA serial:   "
+	mfaSerial.Placeholder = "optional, arn:aws:iam::123456789012:mfa/user"
+
+	mfaCode := textinput.New()
+	mfaCode.Prompt = "MFA code:     "
+	mfaCode.Placeholder = "6 digits"
+	mfaCode.CharLimit = 6
+
+	return []textinput.Model{sessionName, duration, mfaSerial, mfaCode}
+}
+
+// openAssumeForm asks for the session settings of the selected role
+func (m *model) openAssumeForm() tea.Cmd {
+	selected, ok := m.rolesList.SelectedItem().(*RoleItem)
+	if !ok {
+		return nil
+	}
+	if m.offline {
+		m.statusMsg = "Roles cannot be assumed while browsing offline data"
+		return nil
+	}
+	if !m.canLoad(selected) {
+		m.statusMsg = "Roles can only be assumed with the credentials of their account"
+		return nil
+	}
+	m.assumeTarget = selected
+	m.assumeInputs = newAssumeInputs()
+	m.assumeFocus = assumeSessionName
+	m.currentScreen = "assume"
+	updateKeyBindingsForScreen(m.currentScreen)
+	m.statusMsg = ""
+	return m.assumeInputs[m.assumeFocus].Focus()
+}
+
+// focusAssumeInput moves the focus of the assume form by offset fields
+func (m *model) focusAssumeInput(offset int) tea.Cmd {
+	m.assumeInputs[m.assumeFocus].Blur()
+	m.assumeFocus = (m.assumeFocus + offset + len(m.assumeInputs)) % len(m.assumeInputs)
+	return m.assumeInputs[m.assumeFocus].Focus()
+}
+
+// assumeFormRequest validates the assume form
+func (m model) assumeFormRequest() (assumeRequest, error) {
+	value := func(field int) string { return strings.TrimSpace(m.assumeInputs[field].Value()) }
+
+	req := assumeRequest{
+		roleArn:     m.assumeTarget.roleArn,
+		roleName:    m.assumeTarget.roleName,
+		sessionName: value(assumeSessionName),
+		mfaSerial:   value(assumeMFASerial),
+		mfaCode:     value(assumeMFACode),
+	}
+	if req.sessionName == "" {
+		return req, fmt.Errorf("session name is required")
+	}
+	duration, err := time.ParseDuration(value(assumeDuration))
+	if err != nil || duration <= 0 {
+		return req, fmt.Errorf("invalid duration %q, use a value like 1h or 45m", value(assumeDuration))
+	}
+	req.duration = duration
+	if req.mfaSerial != "" && req.mfaCode == "" {
+		return req, fmt.Errorf("MFA code is required with an MFA serial")
+	}
+	return req, nil
+}
+
+// submitAssumeForm starts assuming the role of the form
+func (m *model) submitAssumeForm() tea.Cmd {
+	req, err := m.assumeFormRequest()
+	if err != nil {
+		m.statusMsg = fmt.Sprintf("Cannot assume role: %v", err)
+		return nil
+	}
+	m.statusMsg = fmt.Sprintf("Assuming %s...", req.roleName)
+	return assumeRoleCmd(m.screenContext("assume"), m.roleSession(m.assumeTarget), req)
+}
+
+// Assume a role with the credentials of a session
+func assumeRoleCmd(ctx context.Context, session awsSession, req assumeRequest) tea.Cmd {
+	return func() tea.Msg {
+		cfg, err := session.loadConfig(ctx)
+		if err != nil {
+			return roleAssumedMsg{err: fmt.Errorf("error loading AWS configuration: %w", err)}
+		}
+
+		input := &sts.AssumeRoleInput{
+			RoleArn:         aws.String(req.roleArn),
+			RoleSessionName: aws.String(req.sessionName),
+			DurationSeconds: aws.Int32(int32(req.duration.Seconds())),
+		}
+		if req.mfaSerial != "" {
+			input.SerialNumber = aws.String(req.mfaSerial)
+			input.TokenCode = aws.String(req.mfaCode)
+		}
+
+		result, err := session.stsClient(cfg).AssumeRole(ctx, input)
+		if err != nil {
+			return roleAssumedMsg{err: fmt.Errorf("error assuming role %s: %w", req.roleName, err)}
+		}
+
+		creds := result.Credentials
+		provider := credentials.NewStaticCredentialsProvider(aws.ToString(creds.AccessKeyId), aws.ToString(creds.SecretAccessKey), aws.ToString(creds.SessionToken))
+		return roleAssumedMsg{identity: assumedIdentity{
+			session:    awsSession{profile: session.profile, credentials: provider, backend: session.backend},
+			roleName:   req.roleName,
+			arn:        aws.ToString(result.AssumedRoleUser.Arn),
+			expiration: aws.ToTime(creds.Expiration),
+		}}
+	}
+}
+
+// pushIdentity browses as an assumed role
+func (m *model) pushIdentity(identity assumedIdentity) tea.Cmd {
+	m.identities = append(m.identities, identity)
+	cmd := m.reloadIdentity()
+	m.statusMsg = fmt.Sprintf("Assumed %s, credentials expire at %s", identity.roleName, identity.expiration.Local().Format("15:04"))
+	return cmd
+}
+
+// popIdentity returns to the identity the current role was assumed from
+func (m *model) popIdentity() tea.Cmd {
+	if len(m.identities) == 0 {
+		m.statusMsg = "No assumed role to leave"
+		return nil
+	}
+	left := m.identities[len(m.identities)-1]
+	m.identities = m.identities[:len(m.identities)-1]
+	cmd := m.reloadIdentity()
+	m.statusMsg = fmt.Sprintf("Left %s", left.roleName)
+	return cmd
+}
+
+// reloadIdentity loads the account of the current identity
+func (m *model) reloadIdentity() tea.Cmd {
+	m.resetAccountView()
+	if len(m.identities) == 0 {
+		// The profile's own identity starts from its cache again
+		return tea.Batch(loadUserArnCmd(m.screenContext("roles"), m.session(), m.viewKey()), m.cachedAccountCmd(m.currentProfile))
+	}
+	return tea.Batch(loadUserArnCmd(m.screenContext("roles"), m.session(), m.viewKey()), m.startRefresh())
+}
+
+// identityTrail names the assumed roles for the header
+func (m model) identityTrail() string {
+	var trail strings.Builder
+	for _, identity := range m.identities {
+		trail.WriteString(" › " + identity.roleName)
+	}
+	return trail.String()
+}
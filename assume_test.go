@@ -0,0 +1,116 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Test filling in the assume form
+func TestAssumeForm(t *testing.T) {
+	m := createTestModel()
+	m.rolesList.SetItems([]list.Item{&RoleItem{roleName: "Deployer", roleArn: "arn:aws:iam::111111111111:role/Deployer"}})
+
+	newModel, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'a'}})
+	m = newModel.(model)
+	if m.currentScreen != "assume" || m.assumeTarget == nil {
+		t.Fatalf("Expected the assume form, got screen '%s'", m.currentScreen)
+	}
+
+	// Typed keys go to the focused field, tab moves to the next one
+	m.assumeInputs[assumeDuration].SetValue("")
+	newModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	m = newModel.(model)
+	newModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("15m")})
+	m = newModel.(model)
+	if m.assumeFocus != assumeDuration || m.assumeInputs[assumeDuration].Value() != "15m" {
+		t.Errorf("Expected the duration to be typed, got '%s'", m.assumeInputs[assumeDuration].Value())
+	}
+
+	req, err := m.assumeFormRequest()
+	if err != nil || req.duration != 15*time.Minute || req.sessionName != "atui" || req.roleArn != "arn:aws:iam::111111111111:role/Deployer" {
+		t.Errorf("Unexpected request %+v, error %v", req, err)
+	}
+
+	m.assumeInputs[assumeMFASerial].SetValue("arn:aws:iam::111111111111:mfa/alice")
+	if _, err := m.assumeFormRequest(); err == nil || !strings.Contains(err.Error(), "MFA code") {
+		t.Errorf("Expected the MFA code to be required, got %v", err)
+	}
+	m.assumeInputs[assumeDuration].SetValue("soon")
+	newModel, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd != nil || !strings.Contains(newModel.(model).statusMsg, "invalid duration") {
+		t.Errorf("Expected an invalid duration to be refused, got '%s'", newModel.(model).statusMsg)
+	}
+
+	newModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if newModel.(model).currentScreen != "roles" {
+		t.Errorf("Expected esc to close the form")
+	}
+}
+
+// Test that offline data cannot be assumed into
+func TestAssumeOffline(t *testing.T) {
+	m := createTestModel()
+	m.offline = true
+	m.rolesList.SetItems([]list.Item{&RoleItem{roleName: "Deployer"}})
+
+	newModel, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'a'}})
+	if cmd != nil || newModel.(model).currentScreen != "roles" {
+		t.Errorf("Expected assuming to be refused offline")
+	}
+}
+
+// Test browsing as assumed roles and going back
+func TestIdentityStack(t *testing.T) {
+	m := createTestModel()
+	m.currentProfile = "dev"
+	m.sessionProfile = "dev"
+	m.userArn = "arn:aws:iam::111111111111:user/alice"
+	m.rolesList.SetItems([]list.Item{&RoleItem{roleName: "Deployer"}})
+
+	identity := func(roleName string) assumedIdentity {
+		return assumedIdentity{
+			session:    awsSession{profile: "dev", credentials: credentials.NewStaticCredentialsProvider("AKID", "SECRET", "TOKEN")},
+			roleName:   roleName,
+			arn:        "arn:aws:sts::111111111111:assumed-role/" + roleName + "/atui",
+			expiration: time.Now().Add(time.Hour),
+		}
+	}
+
+	newModel, cmd := m.Update(roleAssumedMsg{identity: identity("Deployer")})
+	m = newModel.(model)
+	newModel, _ = m.Update(roleAssumedMsg{identity: identity("ReadOnly")})
+	m = newModel.(model)
+	if cmd == nil || len(m.identities) != 2 || m.userArn != "" || len(m.rolesList.Items()) != 0 {
+		t.Fatalf("Expected the assumed account to be reloaded")
+	}
+	if m.session().credentials == nil || m.identityTrail() != " › Deployer › ReadOnly" {
+		t.Errorf("Expected the last role to be browsed, got trail '%s'", m.identityTrail())
+	}
+	if m.roleSession(nil).credentials == nil {
+		t.Errorf("Expected role details to load with the assumed credentials")
+	}
+
+	// Assumed sessions are not cached
+	m.accountID = "111111111111"
+	m.lastRefresh = time.Now()
+	if m.saveCurrentCacheCmd() != nil {
+		t.Errorf("Expected no cache for an assumed role")
+	}
+
+	newModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'z'}})
+	m = newModel.(model)
+	newModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'z'}})
+	m = newModel.(model)
+	if len(m.identities) != 0 || m.session().credentials != nil || m.session().profile != "dev" {
+		t.Errorf("Expected to be back to the profile's identity")
+	}
+	newModel, cmd = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'z'}})
+	if cmd != nil || !strings.Contains(newModel.(model).statusMsg, "No assumed role") {
+		t.Errorf("Expected nothing to pop")
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Sample output of `aws iam get-account-authorization-details` mixing CLI and API document forms
+const sampleAuthzDetails = `{
+    "UserDetailList": [
+        {
+            "Path": "/",
+            "UserName": "alice",
+            "UserId": "AIDAEXAMPLE",
+            "Arn": "arn:aws:iam::123456789012:user/alice",
+            "CreateDate": "2021-03-04T10:11:12+00:00",
+            "UserPolicyList": [
+                {"PolicyName": "UserInline", "PolicyDocument": {"Version": "2012-10-17", "Statement": []}}
+            ],
+            "GroupList": ["admins"],
+            "AttachedManagedPolicies": []
+        }
+    ],
+    "GroupDetailList": [
+        {
+            "GroupName": "admins",
+            "Arn": "arn:aws:iam::123456789012:group/admins",
+            "GroupPolicyList": [],
+            "AttachedManagedPolicies": [
+                {"PolicyName": "AppPolicy", "PolicyArn": "arn:aws:iam::123456789012:policy/AppPolicy"}
+            ]
+        }
+    ],
+    "RoleDetailList": [
+        {
+            "Path": "/service/",
+            "RoleName": "AppRole",
+            "Arn": "arn:aws:iam::123456789012:role/service/AppRole",
+            "CreateDate": "2020-01-02T03:04:05Z",
+            "AssumeRolePolicyDocument": {
+                "Version": "2012-10-17",
+                "Statement": [{"Effect": "Allow", "Principal": {"Service": "ec2.amazonaws.com"}, "Action": "sts:AssumeRole"}]
+            },
+            "InstanceProfileList": [
+                {"InstanceProfileName": "AppRole", "Roles": [{"RoleName": "AppRole", "AssumeRolePolicyDocument": {"Version": "2012-10-17"}}]}
+            ],
+            "RolePolicyList": [
+                {"PolicyName": "Encoded", "PolicyDocument": "%7B%22Version%22%3A%222012-10-17%22%7D"}
+            ],
+            "AttachedManagedPolicies": [
+                {"PolicyName": "AppPolicy", "PolicyArn": "arn:aws:iam::123456789012:policy/AppPolicy"}
+            ],
+            "PermissionsBoundary": {
+                "PermissionsBoundaryType": "Policy",
+                "PermissionsBoundaryArn": "arn:aws:iam::123456789012:policy/Boundary"
+            },
+            "Tags": [{"Key": "env", "Value": "prod"}],
+            "RoleLastUsed": {"LastUsedDate": "2024-05-06T07:08:09+00:00", "Region": "us-east-1"}
+        }
+    ],
+    "Policies": [
+        {
+            "PolicyName": "AppPolicy",
+            "PolicyId": "ANPAEXAMPLE",
+            "Arn": "arn:aws:iam::123456789012:policy/AppPolicy",
+            "Path": "/",
+            "DefaultVersionId": "v2",
+            "AttachmentCount": 2,
+            "IsAttachable": true,
+            "PolicyVersionList": [
+                {"Document": {"Statement": [{"Effect": "Allow", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::a b+c"}]}, "VersionId": "v2", "IsDefaultVersion": true},
+                {"Document": "%7B%7D", "VersionId": "v1", "IsDefaultVersion": false}
+            ]
+        }
+    ]
+}`
+
+// Test importing get-account-authorization-details output
+func TestReadAuthzDetails(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "authz.json")
+	if err := os.WriteFile(path, []byte(sampleAuthzDetails), 0600); err != nil {
+		t.Fatalf("Failed to write sample: %v", err)
+	}
+
+	data, err := readAuthzDetails(path)
+	if err != nil {
+		t.Fatalf("Expected details to be read, got %v", err)
+	}
+
+	role, ok := data.findRole("AppRole")
+	if !ok {
+		t.Fatalf("Expected AppRole to be imported")
+	}
+	if role.path != "/service/" || role.tags["env"] != "prod" || role.permissionsBoundary != "arn:aws:iam::123456789012:policy/Boundary" {
+		t.Errorf("Expected role metadata, got %+v", role)
+	}
+	if role.trustPolicy != `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"Service":"ec2.amazonaws.com"},"Action":"sts:AssumeRole"}]}` {
+		t.Errorf("Expected trust policy from JSON object, got '%s'", role.trustPolicy)
+	}
+	if len(role.policies) != 2 || role.policies[1].rawDocument != `{"Version":"2012-10-17"}` {
+		t.Errorf("Expected URL-encoded inline policy to be decoded, got %+v", role.policies)
+	}
+
+	policy := data.policies["arn:aws:iam::123456789012:policy/AppPolicy"]
+	if policy.defaultVersionId != "v2" || policy.rawDocument != `{"Statement":[{"Effect":"Allow","Action":"s3:GetObject","Resource":"arn:aws:s3:::a b+c"}]}` {
+		t.Errorf("Expected default version document, got %+v", policy)
+	}
+	if role.policies[0].rawDocument != policy.rawDocument {
+		t.Errorf("Expected attached policy to carry its document")
+	}
+
+	if len(data.users) != 1 || data.users[0].policies[0].rawDocument != `{"Version":"2012-10-17","Statement":[]}` {
+		t.Errorf("Expected user with inline policy, got %+v", data.users)
+	}
+	if len(data.groups) != 1 || len(data.groups[0].policies) != 1 {
+		t.Errorf("Expected group with attached policy, got %+v", data.groups)
+	}
+}
+
+// Test rejecting files without authorization details
+func TestReadAuthzDetailsEmpty(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "empty.json")
+	if err := os.WriteFile(path, []byte(`{"Roles": []}`), 0600); err != nil {
+		t.Fatalf("Failed to write sample: %v", err)
+	}
+
+	if _, err := readAuthzDetails(path); err == nil {
+		t.Errorf("Expected an error for a file without authorization details")
+	}
+}
+
+// Test browsing imported details offline
+func TestOpenAuthzDetails(t *testing.T) {
+	m := createTestModel()
+	data := &accountData{roles: []RoleItem{{roleName: "AppRole", roleArn: "arn:aws:iam::123456789012:role/AppRole"}}}
+
+	m.openAuthzDetails("/archive/authz.json", data)
+
+	if !m.offline || m.sourceLabel != "Authz details: authz.json" || m.accountID != "123456789012" {
+		t.Errorf("Expected offline import mode, got label '%s' account '%s'", m.sourceLabel, m.accountID)
+	}
+	if len(m.rolesList.Items()) != 1 {
+		t.Errorf("Expected imported roles in the list")
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	appconfig "github.com/vlkyrylenko/atui/config"
+)
+
+// cacheEntry is the on-disk form of cached account data
+type cacheEntry struct {
+	SavedAt   time.Time     `json:"savedAt"`
+	Profile   string        `json:"profile"`
+	AccountID string        `json:"accountId"`
+	Account   accountRecord `json:"account"`
+}
+
+// cachedAccountLoadedMsg is sent when the cache lookup on startup finishes
+type cachedAccountLoadedMsg struct {
+	view      string       // Key of the account view the cache was looked up for
+	data      *accountData // nil when nothing is cached for the profile
+	accountID string
+	savedAt   time.Time
+}
+
+// cacheSavedMsg reports the result of writing the cache
+type cacheSavedMsg struct {
+	err error
+}
+
+// cacheDir returns the directory holding cache files for a profile
+func cacheDir(profile string) (string, error) {
+	configDir, err := appconfig.Dir()
+	if err != nil {
+		return "", err
+	}
+	// Profile names may contain path separators
+	safeProfile := strings.NewReplacer("/", "_", "\\", "_").Replace(profile)
+	return filepath.Join(configDir, "cache", safeProfile), nil
+}
+
+// Load the most recently cached account data for a profile, tagged with the key of the account view it is loaded for
+func loadCachedAccountCmd(profile, view string) tea.Cmd {
+	return func() tea.Msg {
+		dir, err := cacheDir(profile)
+		if err != nil {
+			return cachedAccountLoadedMsg{view: view}
+		}
+
+		// Each file holds one account, pick the newest one
+		paths, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+		var newest string
+		var newestTime time.Time
+		for _, path := range paths {
+			info, err := os.Stat(path)
+			if err == nil && info.ModTime().After(newestTime) {
+				newest, newestTime = path, info.ModTime()
+			}
+		}
+		if newest == "" {
+			return cachedAccountLoadedMsg{view: view}
+		}
+
+		data, err := os.ReadFile(newest)
+		if err != nil {
+			return cachedAccountLoadedMsg{view: view}
+		}
+		var entry cacheEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			// A corrupt cache is ignored and rewritten after the refresh
+			return cachedAccountLoadedMsg{view: view}
+		}
+
+		return cachedAccountLoadedMsg{
+			view:      view,
+			data:      entry.Account.data(),
+			accountID: entry.AccountID,
+			savedAt:   entry.SavedAt,
+		}
+	}
+}
+
+// Write account data to the cache for a profile and account
+func saveCacheCmd(profile, accountID string, account *accountData) tea.Cmd {
+	return func() tea.Msg {
+		dir, err := cacheDir(profile)
+		if err != nil {
+			return cacheSavedMsg{err: err}
+		}
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return cacheSavedMsg{err: fmt.Errorf("failed to create cache directory: %w", err)}
+		}
+
+		data, err := json.Marshal(cacheEntry{
+			SavedAt:   time.Now(),
+			Profile:   profile,
+			AccountID: accountID,
+			Account:   account.record(),
+		})
+		if err != nil {
+			return cacheSavedMsg{err: fmt.Errorf("failed to marshal cache: %w", err)}
+		}
+
+		// Write to a temporary file first so a crash never leaves a truncated cache
+		path := filepath.Join(dir, accountID+".json")
+		tmpPath := path + ".tmp"
+		if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+			return cacheSavedMsg{err: fmt.Errorf("failed to write cache: %w", err)}
+		}
+		if err := os.Rename(tmpPath, path); err != nil {
+			return cacheSavedMsg{err: fmt.Errorf("failed to write cache: %w", err)}
+		}
+		return cacheSavedMsg{}
+	}
+}
+
+// accountIDFromArn extracts the account ID from an ARN
+func accountIDFromArn(arn string) string {
+	parts := strings.Split(arn, ":")
+	if len(parts) < 6 {
+		return ""
+	}
+	return parts[4]
+}
+
+// cacheAgeText describes how long ago the data was cached
+func cacheAgeText(savedAt, now time.Time) string {
+	age := now.Sub(savedAt)
+	switch {
+	case age < time.Minute:
+		return "cached just now"
+	case age < time.Hour:
+		minutes := int(age.Minutes())
+		if minutes == 1 {
+			return "cached 1 minute ago"
+		}
+		return fmt.Sprintf("cached %d minutes ago", minutes)
+	default:
+		hours := int(age.Hours())
+		if hours == 1 {
+			return "cached 1 hour ago"
+		}
+		return fmt.Sprintf("cached %d hours ago", hours)
+	}
+}
+
+// cacheable reports whether data of the current backend is cached
+func (m model) cacheable() bool {
+	return m.backend == nil || m.backend.cacheable()
+}
+
+// cachedAccountCmd loads the cached data of a profile, or reports an empty cache when data is not cached
+func (m model) cachedAccountCmd(profile string) tea.Cmd {
+	view := m.viewKey()
+	if !m.cacheable() {
+		return func() tea.Msg { return cachedAccountLoadedMsg{view: view} }
+	}
+	return loadCachedAccountCmd(profile, view)
+}
+
+// saveCurrentCacheCmd caches the current data once both the account and its roles are known
+func (m model) saveCurrentCacheCmd() tea.Cmd {
+	// Assumed role sessions are temporary and not cached
+	if !m.cacheable() || m.accountID == "" || m.aggregated() || len(m.identities) > 0 || m.refreshPending > 0 || m.lastRefresh.IsZero() || !m.cachedAt.IsZero() {
+		return nil
+	}
+	return saveCacheCmd(m.currentProfile, m.accountID, m.currentAccountData())
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+)
+
+// Test writing and reading the cache for a profile
+func TestCacheRoundTrip(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	policyArn := "arn:aws:iam::123456789012:policy/AppPolicy"
+	managed := PolicyItem{policyName: "AppPolicy", policyArn: policyArn, policyType: "Customer", rawDocument: `{"Version":"2012-10-17"}`}
+	account := &accountData{
+		roles: []RoleItem{{
+			roleName:       "AppRole",
+			roleArn:        "arn:aws:iam::123456789012:role/AppRole",
+			description:    "Application role",
+			tags:           map[string]string{"env": "prod"},
+			policies:       []PolicyItem{managed, {policyName: "Inline", policyType: "Inline", rawDocument: "{}"}},
+			policiesLoaded: true,
+		}},
+		users:    []UserItem{{userName: "alice", groups: []string{"admins"}}},
+		policies: map[string]PolicyItem{policyArn: managed},
+	}
+
+	if msg := saveCacheCmd("dev/team", "123456789012", account)().(cacheSavedMsg); msg.err != nil {
+		t.Fatalf("Expected cache to be saved, got %v", msg.err)
+	}
+
+	msg := loadCachedAccountCmd("dev/team", "")().(cachedAccountLoadedMsg)
+	if msg.data == nil {
+		t.Fatalf("Expected cached data to be loaded")
+	}
+	if msg.accountID != "123456789012" || time.Since(msg.savedAt) > time.Minute {
+		t.Errorf("Expected cache metadata, got account '%s' saved at %v", msg.accountID, msg.savedAt)
+	}
+
+	role, ok := msg.data.findRole("AppRole")
+	if !ok {
+		t.Fatalf("Expected cached role")
+	}
+	if role.description != "Application role" || role.tags["env"] != "prod" || role.policyCount != 2 {
+		t.Errorf("Expected role details to survive the cache, got %+v", role)
+	}
+	if role.policies[0].rawDocument != managed.rawDocument || role.policies[1].rawDocument != "{}" {
+		t.Errorf("Expected policy documents to survive the cache")
+	}
+	if len(msg.data.users) != 1 || msg.data.policies[policyArn].policyName != "AppPolicy" {
+		t.Errorf("Expected users and managed policies to be cached")
+	}
+}
+
+// Test that a missing cache reports no data
+func TestLoadMissingCache(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	msg := loadCachedAccountCmd("default", "")().(cachedAccountLoadedMsg)
+	if msg.data != nil {
+		t.Errorf("Expected no cached data")
+	}
+}
+
+// Test cached data display and refresh on startup
+func TestCachedAccountLoadedMsg(t *testing.T) {
+	m := createTestModel()
+	data := &accountData{roles: []RoleItem{{roleName: "CachedRole"}}}
+
+	// Fresh cache is shown without refreshing
+	newModel, cmd := m.Update(cachedAccountLoadedMsg{view: m.viewKey(), data: data, accountID: "1", savedAt: time.Now()})
+	updatedModel := newModel.(model)
+	if cmd != nil || updatedModel.refreshPending != 0 {
+		t.Errorf("Expected no refresh for fresh cache")
+	}
+	if len(updatedModel.rolesList.Items()) != 1 || updatedModel.cachedAt.IsZero() {
+		t.Errorf("Expected cached roles to be shown")
+	}
+
+	// Stale cache is shown and refreshed
+	newModel, cmd = m.Update(cachedAccountLoadedMsg{view: m.viewKey(), data: data, accountID: "1", savedAt: time.Now().Add(-2 * cacheTTL)})
+	updatedModel = newModel.(model)
+	if cmd == nil || updatedModel.refreshPending != 2 {
+		t.Errorf("Expected refresh for stale cache")
+	}
+
+	// The refresh replaces the cached data
+	newModel, _ = updatedModel.Update(rolesLoadedMsg{{roleName: "FreshRole"}})
+	newModel, _ = newModel.(model).Update(accountLoadedMsg{load: updatedModel.accountLoad, data: &accountData{}})
+	updatedModel = newModel.(model)
+	if !updatedModel.cachedAt.IsZero() || updatedModel.refreshPending != 0 {
+		t.Errorf("Expected cache indicator to be cleared after refresh")
+	}
+}
+
+// Test that cached data of another account is dropped
+func TestCachedAccountMismatch(t *testing.T) {
+	m := createTestModel()
+	m.cachedAt = time.Now()
+	m.cachedAccountID = "111111111111"
+	m.rolesList.SetItems([]list.Item{&RoleItem{roleName: "CachedRole"}})
+
+	newModel, cmd := m.Update(userArnLoadedMsg{view: m.viewKey(), arn: "arn:aws:iam::222222222222:user/alice"})
+	updatedModel := newModel.(model)
+
+	if len(updatedModel.rolesList.Items()) != 0 || cmd == nil {
+		t.Errorf("Expected cached roles to be dropped and refreshed")
+	}
+}
+
+// Test the cache age text
+func TestCacheAgeText(t *testing.T) {
+	now := time.Now()
+	testCases := []struct {
+		age      time.Duration
+		expected string
+	}{
+		{10 * time.Second, "cached just now"},
+		{time.Minute, "cached 1 minute ago"},
+		{12 * time.Minute, "cached 12 minutes ago"},
+		{3 * time.Hour, "cached 3 hours ago"},
+	}
+
+	for _, tc := range testCases {
+		if result := cacheAgeText(now.Add(-tc.age), now); result != tc.expected {
+			t.Errorf("Expected '%s', got '%s'", tc.expected, result)
+		}
+	}
+}
+
+// Test account ID extraction from ARNs
+func TestAccountIDFromArn(t *testing.T) {
+	if id := accountIDFromArn("arn:aws:sts::123456789012:assumed-role/Admin/session"); id != "123456789012" {
+		t.Errorf("Expected account ID, got '%s'", id)
+	}
+	if id := accountIDFromArn("not-an-arn"); id != "" {
+		t.Errorf("Expected empty account ID, got '%s'", id)
+	}
+}
+
+// Test the identity and cache of the previous profile are dropped when they land after switching
+func TestStaleIdentityDropped(t *testing.T) {
+	m := createTestModel()
+	view := m.viewKey()
+	m.switchProfile("other")
+
+	newModel, cmd := m.Update(userArnLoadedMsg{view: view, arn: "arn:aws:iam::111111111111:user/alice"})
+	m = newModel.(model)
+	if cmd != nil || m.userArn != "" || m.accountID != "" {
+		t.Errorf("Expected the previous profile's identity to be dropped, got '%s'", m.userArn)
+	}
+
+	data := &accountData{roles: []RoleItem{{roleName: "PreviousRole"}}}
+	newModel, _ = m.Update(cachedAccountLoadedMsg{view: view, data: data, accountID: "111111111111", savedAt: time.Now()})
+	m = newModel.(model)
+	if len(m.rolesList.Items()) != 0 {
+		t.Errorf("Expected the previous profile's cache to be dropped")
+	}
+
+	newModel, _ = m.Update(userArnLoadedMsg{view: m.viewKey(), arn: "arn:aws:iam::222222222222:user/bob"})
+	m = newModel.(model)
+	if m.accountID != "222222222222" {
+		t.Errorf("Expected the identity of the profile, got '%s'", m.accountID)
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/aws/ratelimit"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/smithy-go/middleware"
+	tea "github.com/charmbracelet/bubbletea"
+	appconfig "github.com/vlkyrylenko/atui/config"
+)
+
+// throttleNoticeDuration is how long the header reports throttling after the last throttled call
+const throttleNoticeDuration = 5 * time.Second
+
+// apiCalls is the call layer of every AWS client, configured on startup
+var apiCalls = newCallLayer(appconfig.DefaultConfig.CallTimeout(), appconfig.DefaultConfig.MaxAttempts(), appconfig.DefaultConfig.RequestRates())
+
+// callLayer limits the request rate of every API, bounds each attempt with a timeout and reports throttling.
+// Limits are shared by all sessions since IAM and Organizations quotas apply to the whole account.
+type callLayer struct {
+	timeout     time.Duration
+	maxAttempts int
+	limiters    map[string]*tokenBucket // By lowercase service ID, e.g. "iam"
+	throttled   chan throttledMsg       // Latest throttled call not yet shown
+}
+
+// throttledMsg is sent when AWS throttled a call that is retried
+type throttledMsg struct {
+	operation string
+}
+
+// throttleClearedMsg is sent when a throttling notice may have expired
+type throttleClearedMsg struct{}
+
+// newCallLayer creates a call layer with rates in requests per second by API
+func newCallLayer(timeout time.Duration, maxAttempts int, rates map[string]float64) *callLayer {
+	l := &callLayer{
+		timeout:     timeout,
+		maxAttempts: maxAttempts,
+		limiters:    make(map[string]*tokenBucket),
+		throttled:   make(chan throttledMsg, 1),
+	}
+	for api, rate := range rates {
+		l.limiters[api] = newTokenBucket(rate)
+	}
+	return l
+}
+
+// retryer retries with adaptive mode, which slows down further when AWS throttles
+func (l *callLayer) retryer() aws.Retryer {
+	return retry.NewAdaptiveMode(func(o *retry.AdaptiveModeOptions) {
+		o.StandardOptions = append(o.StandardOptions, func(so *retry.StandardOptions) {
+			so.MaxAttempts = l.maxAttempts
+			// Throttled calls wait for the limiters and the backoff instead of failing once the retry quota is spent
+			so.RateLimiter = ratelimit.None
+		})
+	})
+}
+
+// addMiddleware installs the timeout, the rate limiter and throttling reports on a client's stack
+func (l *callLayer) addMiddleware(stack *middleware.Stack) error {
+	// Every attempt of a call waits for the limiter, so retries count against the rate too
+	if err := stack.Finalize.Insert(middleware.FinalizeMiddlewareFunc("RateLimit", l.handleAttempt), "Retry", middleware.After); err != nil {
+		return err
+	}
+	// The timeout comes after signing, so credentials that wait for an MFA code are not bounded by it
+	return stack.Finalize.Add(middleware.FinalizeMiddlewareFunc("AttemptTimeout", l.handleTimeout), middleware.After)
+}
+
+// handleTimeout bounds one attempt of a call, from sending the signed request to reading the response
+func (l *callLayer) handleTimeout(ctx context.Context, in middleware.FinalizeInput, next middleware.FinalizeHandler) (middleware.FinalizeOutput, middleware.Metadata, error) {
+	if l.timeout <= 0 {
+		return next.HandleFinalize(ctx, in)
+	}
+	callCtx, cancel := context.WithTimeout(ctx, l.timeout)
+	defer cancel()
+	out, metadata, err := next.HandleFinalize(callCtx, in)
+	if err != nil && ctx.Err() == nil && errors.Is(callCtx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("no response within %v: %w", l.timeout, err)
+	}
+	return out, metadata, err
+}
+
+// handleAttempt waits for the limiter of the API and reports throttled attempts
+func (l *callLayer) handleAttempt(ctx context.Context, in middleware.FinalizeInput, next middleware.FinalizeHandler) (middleware.FinalizeOutput, middleware.Metadata, error) {
+	if limiter := l.limiters[strings.ToLower(awsmiddleware.GetServiceID(ctx))]; limiter != nil {
+		if err := limiter.wait(ctx); err != nil {
+			return middleware.FinalizeOutput{}, middleware.Metadata{}, err
+		}
+	}
+	out, metadata, err := next.HandleFinalize(ctx, in)
+	if err != nil && retry.IsErrorThrottles(retry.DefaultThrottles).IsErrorThrottle(err) == aws.TrueTernary {
+		l.reportThrottled(awsmiddleware.GetOperationName(ctx))
+	}
+	return out, metadata, err
+}
+
+// reportThrottled queues a throttling notice for the UI, dropping it when one is already queued
+func (l *callLayer) reportThrottled(operation string) {
+	select {
+	case l.throttled <- throttledMsg{operation: operation}:
+	default:
+	}
+}
+
+// Wait for the next throttling notice of the call layer
+func waitForThrottleCmd() tea.Cmd {
+	throttled := apiCalls.throttled
+	return func() tea.Msg {
+		return <-throttled
+	}
+}
+
+// handleThrottled shows a throttling notice and clears it once no call was throttled for a while
+func (m *model) handleThrottled(msg throttledMsg) tea.Cmd {
+	m.throttledAt = time.Now()
+	m.throttledOperation = msg.operation
+	return tea.Batch(waitForThrottleCmd(), tea.Tick(throttleNoticeDuration, func(time.Time) tea.Msg {
+		return throttleClearedMsg{}
+	}))
+}
+
+// throttleText is the header notice while calls are throttled, empty otherwise
+func (m model) throttleText() string {
+	if m.throttledAt.IsZero() || time.Since(m.throttledAt) >= throttleNoticeDuration {
+		return ""
+	}
+	return fmt.Sprintf("%s throttled, retrying…", m.throttledOperation)
+}
+
+// tokenBucket allows rate requests per second on average with bursts of up to one second of requests
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64) *tokenBucket {
+	burst := max(rate, 1)
+	return &tokenBucket{rate: rate, burst: burst, tokens: burst, last: time.Now()}
+}
+
+// wait takes a token, sleeping until one is available or ctx is done
+func (b *tokenBucket) wait(ctx context.Context) error {
+	if b.rate <= 0 {
+		return nil
+	}
+	b.mu.Lock()
+	now := time.Now()
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	// Taking the token up front reserves it, later callers queue behind
+	b.tokens--
+	delay := time.Duration(-b.tokens / b.rate * float64(time.Second))
+	b.mu.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		// The reserved token is given back for the callers behind
+		b.mu.Lock()
+		b.tokens++
+		b.mu.Unlock()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+)
+
+// useCallLayer replaces the call layer of every client for a test
+func useCallLayer(t *testing.T, layer *callLayer) {
+	previous := apiCalls
+	apiCalls = layer
+	t.Cleanup(func() { apiCalls = previous })
+}
+
+// testIAMConfig returns a configuration calling IAM at url through the call layer
+func testIAMConfig(url string) aws.Config {
+	return aws.Config{
+		Region:       "us-east-1",
+		Credentials:  credentials.NewStaticCredentialsProvider("AKIATEST", "secret", ""),
+		BaseEndpoint: aws.String(url),
+		Retryer:      apiCalls.retryer,
+	}
+}
+
+// Test throttled calls are reported to the UI and counted as attempts
+func TestCallLayerReportsThrottling(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "text/xml")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`<ErrorResponse><Error><Type>Sender</Type><Code>Throttling</Code><Message>Rate exceeded</Message></Error><RequestId>1</RequestId></ErrorResponse>`))
+	}))
+	defer server.Close()
+	useCallLayer(t, newCallLayer(time.Minute, 1, map[string]float64{"iam": 100}))
+
+	_, err := newIAMClient(testIAMConfig(server.URL)).ListAccountAliases(context.Background(), &iam.ListAccountAliasesInput{})
+	if err == nil || !strings.Contains(err.Error(), "Throttling") || requests.Load() != 1 {
+		t.Fatalf("Expected one throttled attempt, got %d attempts and %v", requests.Load(), err)
+	}
+
+	m := createTestModel()
+	msg, ok := waitForThrottleCmd()().(throttledMsg)
+	if !ok || msg.operation != "ListAccountAliases" {
+		t.Fatalf("Expected a throttling notice, got %+v", msg)
+	}
+	if m.handleThrottled(msg) == nil || m.throttleText() != "ListAccountAliases throttled, retrying…" {
+		t.Errorf("Expected the notice in the header, got '%s'", m.throttleText())
+	}
+	m.throttledAt = time.Now().Add(-throttleNoticeDuration)
+	if m.throttleText() != "" {
+		t.Errorf("Expected the notice to expire, got '%s'", m.throttleText())
+	}
+}
+
+// Test calls that get no response fail after the configured timeout instead of hanging
+func TestCallLayerTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+	useCallLayer(t, newCallLayer(50*time.Millisecond, 3, nil))
+
+	start := time.Now()
+	_, err := newIAMClient(testIAMConfig(server.URL)).ListAccountAliases(context.Background(), &iam.ListAccountAliasesInput{})
+	if err == nil || !strings.Contains(err.Error(), "no response within 50ms") {
+		t.Fatalf("Expected a timeout error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("Expected every attempt to time out, took %v", elapsed)
+	}
+}
+
+// Test credentials that take longer than the timeout, like a pending MFA prompt, do not fail the call
+func TestCallLayerTimeoutLeavesOutCredentials(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/xml")
+		_, _ = w.Write([]byte(`<ListAccountAliasesResponse><ListAccountAliasesResult><AccountAliases></AccountAliases><IsTruncated>false</IsTruncated></ListAccountAliasesResult></ListAccountAliasesResponse>`))
+	}))
+	defer server.Close()
+	useCallLayer(t, newCallLayer(50*time.Millisecond, 1, nil))
+
+	cfg := testIAMConfig(server.URL)
+	cfg.Credentials = aws.NewCredentialsCache(aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) {
+		select {
+		case <-time.After(200 * time.Millisecond):
+			return aws.Credentials{AccessKeyID: "ASIATEST", SecretAccessKey: "secret", SessionToken: "token"}, nil
+		case <-ctx.Done():
+			return aws.Credentials{}, ctx.Err()
+		}
+	}))
+	if _, err := newIAMClient(cfg).ListAccountAliases(context.Background(), &iam.ListAccountAliasesInput{}); err != nil {
+		t.Errorf("Expected the call to wait for the credentials, got %v", err)
+	}
+}
+
+// Test the token bucket allows a burst and then spaces out requests
+func TestTokenBucket(t *testing.T) {
+	bucket := newTokenBucket(20)
+	ctx := context.Background()
+	start := time.Now()
+	for range 20 {
+		if err := bucket.wait(ctx); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 20*time.Millisecond {
+		t.Errorf("Expected the burst without waiting, took %v", elapsed)
+	}
+	if err := bucket.wait(ctx); err != nil || time.Since(start) < 40*time.Millisecond {
+		t.Errorf("Expected to wait for the next token, took %v", time.Since(start))
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := bucket.wait(cancelled); err == nil {
+		t.Errorf("Expected a cancelled wait to fail")
+	}
+	if err := newTokenBucket(0).wait(ctx); err != nil {
+		t.Errorf("Expected no limit for a zero rate, got %v", err)
+	}
+}
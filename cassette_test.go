@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// Test the loaders against the recorded responses of an account
+func TestReplayLoaders(t *testing.T) {
+	backend, err := newReplayBackend(filepath.Join("testdata", "cassettes", "roles"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	session := awsSession{backend: backend}
+
+	arnMsg, ok := loadUserArnCmd(context.Background(), session, "")().(userArnLoadedMsg)
+	if !ok || !strings.HasSuffix(arnMsg.arn, "/jane.doe@example.com") {
+		t.Errorf("Expected the recorded caller identity, got %+v", arnMsg)
+	}
+
+	roles := collectRolePages(t, loadIAMRolesCmd(context.Background(), session, nil))
+	if len(roles) != 3 {
+		t.Fatalf("Expected 3 roles from 2 recorded pages, got %+v", roles)
+	}
+	if roles[1].roleName != "build-agent" || roles[1].description != "Role of the CI build agents & runners" || roles[0].maxSessionDuration != 43200 {
+		t.Errorf("Expected the recorded role details, got %+v", roles[:2])
+	}
+
+	policies, ok := loadRolePoliciesCmd(context.Background(), session, "build-agent")().(policiesLoadedMsg)
+	if !ok || len(policies.policies) != 2 || policies.policies[1].policyType != "Customer" {
+		t.Errorf("Expected an AWS and a customer managed policy, got %+v", policies)
+	}
+
+	document, ok := loadPolicyDocumentCmd(context.Background(), session, "arn:aws:iam::111122223333:policy/ci/BuildArtifactsAccess")().(policyDocumentLoadedMsg)
+	if !ok || !strings.Contains(document.document, `"kms:ViaService":"s3.eu-west-1.amazonaws.com"`) {
+		t.Errorf("Expected the decoded default version, got %+v", document)
+	}
+
+	// Requests that were not recorded fail without network
+	if _, ok := loadRolePoliciesCmd(context.Background(), session, "orders-api-lambda")().(errorMsg); !ok {
+		t.Errorf("Expected an error for a request missing from the cassette")
+	}
+}
+
+// Test recordings leave out signatures, session tokens, MFA codes and returned credentials
+func TestRecordRedactsCredentials(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/xml")
+		_, _ = w.Write([]byte(`<AssumeRoleResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/"><AssumeRoleResult>
+<AssumedRoleUser><Arn>arn:aws:sts::111122223333:assumed-role/Admin/atui</Arn><AssumedRoleId>AROAEXAMPLE:atui</AssumedRoleId></AssumedRoleUser>
+<Credentials><AccessKeyId>ASIARETURNEDKEY</AccessKeyId><SecretAccessKey>returned-secret</SecretAccessKey><SessionToken>returned-token</SessionToken><Expiration>2030-01-01T00:00:00Z</Expiration></Credentials>
+</AssumeRoleResult></AssumeRoleResponse>`))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	recorder := &cassetteRecorder{dir: dir}
+	cfg := aws.Config{
+		Region:       "eu-west-1",
+		Credentials:  credentials.NewStaticCredentialsProvider("AKIACALLERKEY", "caller-secret", "caller-token"),
+		HTTPClient:   recordingClient{recorder: recorder, next: http.DefaultClient},
+		BaseEndpoint: aws.String(server.URL),
+	}
+	input := &sts.AssumeRoleInput{
+		RoleArn:         aws.String("arn:aws:iam::111122223333:role/Admin"),
+		RoleSessionName: aws.String("atui"),
+		SerialNumber:    aws.String("arn:aws:iam::111122223333:mfa/jane"),
+		TokenCode:       aws.String("123456"),
+	}
+	result, err := sts.NewFromConfig(cfg).AssumeRole(context.Background(), input)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if aws.ToString(result.Credentials.SecretAccessKey) != "returned-secret" {
+		t.Errorf("Expected the caller to get the real response")
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "0001-AssumeRole.json"))
+	if err != nil {
+		t.Fatalf("Expected the recorded request: %v", err)
+	}
+	for _, secret := range []string{"caller-secret", "caller-token", "Signature=", "123456", "ASIARETURNEDKEY", "returned-secret", "returned-token"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("Expected %q to be left out of the recording", secret)
+		}
+	}
+
+	// The replay answers any MFA code and needs no credentials
+	backend, err := newReplayBackend(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	replayCfg, _ := backend.loadConfig(context.Background(), "", "")
+	input.TokenCode = aws.String("654321")
+	replayed, err := newSTSClient(replayCfg).AssumeRole(context.Background(), input)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if aws.ToString(replayed.AssumedRoleUser.Arn) != "arn:aws:sts::111122223333:assumed-role/Admin/atui" || aws.ToString(replayed.Credentials.SessionToken) != "REDACTED" {
+		t.Errorf("Expected the redacted recorded response, got %+v", replayed)
+	}
+}
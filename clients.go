@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/organizations"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// iamAPI is the part of the IAM API atui calls, satisfied by the SDK client and the fake backend
+type iamAPI interface {
+	ListRoles(ctx context.Context, params *iam.ListRolesInput, optFns ...func(*iam.Options)) (*iam.ListRolesOutput, error)
+	GetRole(ctx context.Context, params *iam.GetRoleInput, optFns ...func(*iam.Options)) (*iam.GetRoleOutput, error)
+	ListAttachedRolePolicies(ctx context.Context, params *iam.ListAttachedRolePoliciesInput, optFns ...func(*iam.Options)) (*iam.ListAttachedRolePoliciesOutput, error)
+	ListRolePolicies(ctx context.Context, params *iam.ListRolePoliciesInput, optFns ...func(*iam.Options)) (*iam.ListRolePoliciesOutput, error)
+	GetRolePolicy(ctx context.Context, params *iam.GetRolePolicyInput, optFns ...func(*iam.Options)) (*iam.GetRolePolicyOutput, error)
+	GetPolicy(ctx context.Context, params *iam.GetPolicyInput, optFns ...func(*iam.Options)) (*iam.GetPolicyOutput, error)
+	GetPolicyVersion(ctx context.Context, params *iam.GetPolicyVersionInput, optFns ...func(*iam.Options)) (*iam.GetPolicyVersionOutput, error)
+	GetAccountAuthorizationDetails(ctx context.Context, params *iam.GetAccountAuthorizationDetailsInput, optFns ...func(*iam.Options)) (*iam.GetAccountAuthorizationDetailsOutput, error)
+	ListAccountAliases(ctx context.Context, params *iam.ListAccountAliasesInput, optFns ...func(*iam.Options)) (*iam.ListAccountAliasesOutput, error)
+}
+
+// stsAPI is the part of the STS API atui calls, satisfied by the SDK client and the fake backend
+type stsAPI interface {
+	GetCallerIdentity(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error)
+	AssumeRole(ctx context.Context, params *sts.AssumeRoleInput, optFns ...func(*sts.Options)) (*sts.AssumeRoleOutput, error)
+	GetSessionToken(ctx context.Context, params *sts.GetSessionTokenInput, optFns ...func(*sts.Options)) (*sts.GetSessionTokenOutput, error)
+	DecodeAuthorizationMessage(ctx context.Context, params *sts.DecodeAuthorizationMessageInput, optFns ...func(*sts.Options)) (*sts.DecodeAuthorizationMessageOutput, error)
+}
+
+// organizationsAPI is the part of the Organizations API atui calls, satisfied by the SDK client and the fake backend
+type organizationsAPI interface {
+	ListAccounts(ctx context.Context, params *organizations.ListAccountsInput, optFns ...func(*organizations.Options)) (*organizations.ListAccountsOutput, error)
+}
+
+// awsBackend loads the configuration of sessions and creates their clients
+type awsBackend interface {
+	loadConfig(ctx context.Context, profile, region string) (aws.Config, error)
+	iamClient(cfg aws.Config) iamAPI
+	stsClient(cfg aws.Config) stsAPI
+	organizationsClient(cfg aws.Config) organizationsAPI
+	cacheable() bool // Whether loaded data is cached per profile
+}
+
+// sdkBackend calls AWS with the shared configuration and the SDK clients
+type sdkBackend struct{}
+
+func (sdkBackend) loadConfig(ctx context.Context, profile, region string) (aws.Config, error) {
+	return loadAWSConfig(ctx, profile, region)
+}
+
+func (sdkBackend) iamClient(cfg aws.Config) iamAPI { return newIAMClient(cfg) }
+
+func (sdkBackend) stsClient(cfg aws.Config) stsAPI { return newSTSClient(cfg) }
+
+func (sdkBackend) organizationsClient(cfg aws.Config) organizationsAPI {
+	return newOrganizationsClient(cfg)
+}
+
+func (sdkBackend) cacheable() bool { return true }
@@ -0,0 +1,508 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// markedRoles returns the roles marked on the roles screen
+func (m model) markedRoles() []*RoleItem {
+	var roles []*RoleItem
+	for _, item := range m.rolesList.Items() {
+		if role, ok := item.(*RoleItem); ok && role.marked {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+// roleLabel names a role, with its account when roles of several accounts are shown
+func roleLabel(role *RoleItem) string {
+	if role.accountID == "" {
+		return role.roleName
+	}
+	return fmt.Sprintf("%s (%s)", role.roleName, role.accountLabel())
+}
+
+// comparedLoadedMsg is sent when policies and documents missing from a comparison were loaded
+type comparedLoadedMsg struct {
+	roles     [2]*RoleItem
+	policies  [2][]PolicyItem      // Policies of roles that had none loaded, nil otherwise
+	inline    [2]map[string]string // Inline policy documents by policy name
+	documents map[string]string    // Managed policy and boundary documents by ARN
+}
+
+// compareJob is what a comparison misses of one role
+type compareJob struct {
+	session  awsSession
+	roleName string
+	policies bool     // The policies of the role are not loaded
+	inline   []string // Inline policies without a document
+	managed  []string // Managed policies and boundary without a document, by ARN
+}
+
+// openCompare shows the comparison of the two marked roles and loads what it misses
+func (m *model) openCompare() tea.Cmd {
+	marked := m.markedRoles()
+	if len(marked) != 2 {
+		m.statusMsg = fmt.Sprintf("Mark exactly two roles with space to compare them, %d marked", len(marked))
+		return nil
+	}
+
+	m.compared = [2]*RoleItem{marked[0], marked[1]}
+	m.compareDocuments = nil
+	m.compareView.SetContent(compareRoles(marked[0], marked[1], m.compareManaged()))
+	m.compareView.GotoTop()
+	m.currentScreen = "compare"
+	updateKeyBindingsForScreen(m.currentScreen)
+	m.statusMsg = ""
+	return m.loadCompared()
+}
+
+// compareManaged returns the managed policy documents known to the comparison by ARN
+func (m model) compareManaged() map[string]PolicyItem {
+	managed := map[string]PolicyItem{}
+	if m.account != nil {
+		maps.Copy(managed, m.account.policies)
+	}
+	for arn, document := range m.compareDocuments {
+		managed[arn] = PolicyItem{policyArn: arn, rawDocument: document}
+	}
+	return managed
+}
+
+// loadCompared loads the policies the compared roles miss first, then the documents their policies miss
+func (m *model) loadCompared() tea.Cmd {
+	managed := m.compareManaged()
+	var jobs [2]compareJob
+	missing := false
+	for i, role := range m.compared {
+		if !m.canLoad(role) {
+			continue
+		}
+		job := compareJob{session: m.roleSession(role), roleName: role.roleName, policies: !role.policiesLoaded}
+		if role.policiesLoaded {
+			for _, policy := range role.policies {
+				switch {
+				case policy.rawDocument != "" || managed[policy.policyArn].rawDocument != "":
+				case policy.policyType == "Inline":
+					job.inline = append(job.inline, policy.policyName)
+				default:
+					job.managed = append(job.managed, policy.policyArn)
+				}
+			}
+		}
+		if role.permissionsBoundary != "" && managed[role.permissionsBoundary].rawDocument == "" {
+			job.managed = append(job.managed, role.permissionsBoundary)
+		}
+		jobs[i] = job
+		missing = missing || job.policies || len(job.inline) > 0 || len(job.managed) > 0
+	}
+	if !missing {
+		return nil
+	}
+
+	roles := m.compared
+	return m.startRequest("compare", "Loading policies to compare...", func(ctx context.Context) tea.Cmd {
+		return loadComparedCmd(ctx, roles, jobs)
+	})
+}
+
+// loadComparedCmd loads what the compared roles miss with the loaders of the policies screen
+func loadComparedCmd(ctx context.Context, roles [2]*RoleItem, jobs [2]compareJob) tea.Cmd {
+	return func() tea.Msg {
+		result := comparedLoadedMsg{roles: roles, documents: make(map[string]string)}
+		for i, job := range jobs {
+			if job.policies {
+				msg := loadRolePoliciesCmd(ctx, job.session, job.roleName)()
+				loaded, ok := msg.(policiesLoadedMsg)
+				if !ok {
+					return msg
+				}
+				result.policies[i] = loaded.policies
+			}
+			result.inline[i] = make(map[string]string)
+			for _, policyName := range job.inline {
+				msg := loadInlinePolicyDocumentCmd(ctx, job.session, job.roleName, policyName)()
+				loaded, ok := msg.(policyDocumentLoadedMsg)
+				if !ok {
+					return msg
+				}
+				result.inline[i][policyName] = loaded.document
+			}
+			for _, policyArn := range job.managed {
+				msg := loadPolicyDocumentCmd(ctx, job.session, policyArn)()
+				loaded, ok := msg.(policyDocumentLoadedMsg)
+				if !ok {
+					return msg
+				}
+				result.documents[policyArn] = loaded.document
+			}
+		}
+		return result
+	}
+}
+
+// applyCompared adds what was loaded to the compared roles and loads what they still miss
+func (m *model) applyCompared(msg comparedLoadedMsg) tea.Cmd {
+	if msg.roles != m.compared {
+		return nil
+	}
+	for i, role := range m.compared {
+		if msg.policies[i] != nil {
+			m.attachBulkDocuments(role.roleName, msg.policies[i])
+			role.policies = msg.policies[i]
+			role.policiesLoaded = true
+			role.policyCount = len(msg.policies[i])
+		}
+		for j, policy := range role.policies {
+			if document, ok := msg.inline[i][policy.policyName]; ok && policy.policyType == "Inline" {
+				role.policies[j].rawDocument = document
+			}
+		}
+	}
+	if m.compareDocuments == nil {
+		m.compareDocuments = make(map[string]string)
+	}
+	maps.Copy(m.compareDocuments, msg.documents)
+
+	m.compareView.SetContent(compareRoles(m.compared[0], m.compared[1], m.compareManaged()))
+	return m.loadCompared()
+}
+
+// compareRoles describes the differences between two roles
+func compareRoles(a, b *RoleItem, managed map[string]PolicyItem) string {
+	labelA, labelB := roleLabel(a), roleLabel(b)
+	var sections []string
+
+	// Attached and inline policies
+	policyLines := []string{compareSectionStyle.Render("Policies")}
+	if !a.policiesLoaded || !b.policiesLoaded {
+		policyLines = append(policyLines, "  Policies are not loaded yet for both roles")
+	} else {
+		namesA, namesB := comparePolicyNames(a.policies), comparePolicyNames(b.policies)
+		var shared, onlyA, onlyB []string
+		for _, name := range sortedKeys(namesA, namesB) {
+			_, inA := namesA[name]
+			_, inB := namesB[name]
+			switch {
+			case inA && inB:
+				shared = append(shared, "  = "+name)
+			case inA:
+				onlyA = append(onlyA, diffRemovedStyle.Render("  - "+name))
+			default:
+				onlyB = append(onlyB, diffAddedStyle.Render("  + "+name))
+			}
+		}
+		policyLines = append(policyLines, fmt.Sprintf("Shared (%d):", len(shared)))
+		policyLines = append(policyLines, shared...)
+		policyLines = append(policyLines, fmt.Sprintf("Only %s (%d):", labelA, len(onlyA)))
+		policyLines = append(policyLines, onlyA...)
+		policyLines = append(policyLines, fmt.Sprintf("Only %s (%d):", labelB, len(onlyB)))
+		policyLines = append(policyLines, onlyB...)
+	}
+	sections = append(sections, strings.Join(policyLines, "\n"))
+
+	// Trust policies
+	trustLines := []string{compareSectionStyle.Render("Trust policy")}
+	switch {
+	case a.trustPolicy == "" || b.trustPolicy == "":
+		trustLines = append(trustLines, "  Trust policies are not loaded yet for both roles")
+	case prettyJSON(a.trustPolicy) == prettyJSON(b.trustPolicy):
+		trustLines = append(trustLines, "  Identical")
+	default:
+		trustLines = append(trustLines, fmt.Sprintf("  - %s, + %s", labelA, labelB), documentDiff(a.trustPolicy, b.trustPolicy))
+	}
+	sections = append(sections, strings.Join(trustLines, "\n"))
+
+	// Metadata side by side, differences highlighted
+	rows := [][3]string{
+		{"Path", valueOrNone(a.path), valueOrNone(b.path)},
+		{"Permissions boundary", valueOrNone(a.permissionsBoundary), valueOrNone(b.permissionsBoundary)},
+		{"Max session duration", sessionDurationText(a.maxSessionDuration), sessionDurationText(b.maxSessionDuration)},
+	}
+	for _, tagKey := range sortedKeys(a.tags, b.tags) {
+		rows = append(rows, [3]string{"Tag " + tagKey, tagValue(a.tags, tagKey), tagValue(b.tags, tagKey)})
+	}
+	width := len(labelA)
+	for _, row := range rows {
+		width = max(width, len(row[1]))
+	}
+	metadataLines := []string{
+		compareSectionStyle.Render("Metadata"),
+		fmt.Sprintf("  %-22s %-*s  %s", "", width, labelA, labelB),
+	}
+	for _, row := range rows {
+		line := fmt.Sprintf("  %-22s %-*s  %s", row[0], width, row[1], row[2])
+		if row[1] != row[2] {
+			line = diffChangedStyle.Render(line)
+		}
+		metadataLines = append(metadataLines, line)
+	}
+	sections = append(sections, strings.Join(metadataLines, "\n"))
+
+	// Actions allowed by the policies and boundary of each role, evaluated for the actions either role names
+	actionLines := []string{compareSectionStyle.Render("Effective actions")}
+	statementsA, boundaryA, missingA := roleStatements(a, managed)
+	statementsB, boundaryB, missingB := roleStatements(b, managed)
+	candidates := namedActions(statementsA, statementsB)
+	actionsA := effectiveActions(statementsA, boundaryA, candidates)
+	actionsB := effectiveActions(statementsB, boundaryB, candidates)
+	if len(missingA) > 0 || len(missingB) > 0 {
+		missing := append(missingA, missingB...)
+		actionLines = append(actionLines, fmt.Sprintf("  Documents not loaded, left out: %s", strings.Join(missing, ", ")))
+	}
+	var sharedCount int
+	var onlyA, onlyB []string
+	for _, action := range sortedKeys(actionsA, actionsB) {
+		_, inA := actionsA[action]
+		_, inB := actionsB[action]
+		switch {
+		case inA && inB:
+			sharedCount++
+		case inA:
+			onlyA = append(onlyA, diffRemovedStyle.Render("  - "+actionsA[action]))
+		default:
+			onlyB = append(onlyB, diffAddedStyle.Render("  + "+actionsB[action]))
+		}
+	}
+	actionLines = append(actionLines, fmt.Sprintf("Allowed for both: %d actions", sharedCount))
+	actionLines = append(actionLines, fmt.Sprintf("Only %s (%d):", labelA, len(onlyA)))
+	actionLines = append(actionLines, onlyA...)
+	actionLines = append(actionLines, fmt.Sprintf("Only %s (%d):", labelB, len(onlyB)))
+	actionLines = append(actionLines, onlyB...)
+	sections = append(sections, strings.Join(actionLines, "\n"))
+
+	return strings.Join(sections, "\n\n")
+}
+
+var (
+	compareSectionStyle = lipgloss.NewStyle().Bold(true).Underline(true)
+	diffChangedStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("220"))
+)
+
+// comparePolicyNames indexes policies by a name shared across accounts, since customer policy ARNs differ per account
+func comparePolicyNames(policies []PolicyItem) map[string]PolicyItem {
+	names := make(map[string]PolicyItem)
+	for _, policy := range policies {
+		names[fmt.Sprintf("%s [%s]", policy.policyName, policy.policyType)] = policy
+	}
+	return names
+}
+
+// sessionDurationText formats a max session duration given in seconds
+func sessionDurationText(seconds int32) string {
+	if seconds == 0 {
+		return "(unknown)"
+	}
+	return (time.Duration(seconds) * time.Second).String()
+}
+
+// tagValue returns a tag value or a placeholder when the tag is missing
+func tagValue(tags map[string]string, tagKey string) string {
+	value, ok := tags[tagKey]
+	if !ok {
+		return "(none)"
+	}
+	return value
+}
+
+// roleStatements returns the statements of a role's policies and of its permissions boundary, nil without one.
+// Policies and boundaries whose documents are not loaded are returned by name.
+func roleStatements(role *RoleItem, managed map[string]PolicyItem) ([]policyStatement, []policyStatement, []string) {
+	var statements []policyStatement
+	var missing []string
+	for _, policy := range role.policies {
+		document := policy.rawDocument
+		if document == "" {
+			document = managed[policy.policyArn].rawDocument
+		}
+		if document == "" {
+			missing = append(missing, policy.policyName)
+			continue
+		}
+		statements = append(statements, policyStatements(document)...)
+	}
+
+	var boundary []policyStatement
+	if role.permissionsBoundary != "" {
+		if document := managed[role.permissionsBoundary].rawDocument; document != "" {
+			// A boundary without statements allows nothing, unlike a role without one
+			boundary = append([]policyStatement{}, policyStatements(document)...)
+		} else {
+			missing = append(missing, "boundary "+role.permissionsBoundary)
+		}
+	}
+	return statements, boundary, missing
+}
+
+// namedActions returns the actions and action wildcards named by statements, keyed in lower case
+func namedActions(statements ...[]policyStatement) map[string]string {
+	named := make(map[string]string)
+	for _, list := range statements {
+		for _, statement := range list {
+			for _, action := range statement.actions {
+				named[strings.ToLower(action)] = action
+			}
+		}
+	}
+	return named
+}
+
+// effectiveActions returns the actions of candidates a role is allowed by its policies and its boundary and not
+// denied, keyed in lower case. Wildcards of the candidates are kept as such, e.g. s3:* stays apart from s3:GetObject,
+// and actions denied only for some resources or under conditions are marked.
+func effectiveActions(statements, boundary []policyStatement, candidates map[string]string) map[string]string {
+	effective := make(map[string]string)
+	for key, action := range candidates {
+		if !allowedBy(statements, key) || boundary != nil && !allowedBy(boundary, key) {
+			continue
+		}
+		denied, partly := deniedBy(statements, key)
+		boundaryDenied, boundaryPartly := deniedBy(boundary, key)
+		if denied || boundaryDenied {
+			continue
+		}
+		if partly || boundaryPartly {
+			action += " (partly denied)"
+		}
+		effective[key] = action
+	}
+
+	// Allow statements with NotAction grant every action not named, which the candidates cannot list
+	for _, statement := range statements {
+		if statement.effect == "Allow" && len(statement.notActions) > 0 {
+			label := "all actions except " + strings.Join(statement.notActions, ", ")
+			effective[strings.ToLower(label)] = label
+		}
+	}
+	return effective
+}
+
+// allowedBy reports whether an Allow statement applies to an action
+func allowedBy(statements []policyStatement, action string) bool {
+	for _, statement := range statements {
+		if statement.effect == "Allow" && statement.appliesTo(action) {
+			return true
+		}
+	}
+	return false
+}
+
+// deniedBy reports whether a Deny statement applies to an action for every request, or only partly: for some
+// resources, under conditions or for some of the actions of a wildcard
+func deniedBy(statements []policyStatement, action string) (denied, partly bool) {
+	for _, statement := range statements {
+		if statement.effect != "Deny" {
+			continue
+		}
+		if statement.appliesTo(action) {
+			if statement.unconditional {
+				return true, false
+			}
+			partly = true
+		}
+		for _, deniedAction := range statement.actions {
+			// A wildcard covering a denied action, e.g. s3:* with s3:DeleteObject denied
+			if actionMatches(action, strings.ToLower(deniedAction)) {
+				partly = true
+			}
+		}
+	}
+	return false, partly
+}
+
+// appliesTo reports whether the actions of a statement include an action or wildcard
+func (s policyStatement) appliesTo(action string) bool {
+	if len(s.actions) > 0 {
+		for _, pattern := range s.actions {
+			if actionMatches(strings.ToLower(pattern), action) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(s.notActions) > 0 {
+		for _, pattern := range s.notActions {
+			if actionMatches(strings.ToLower(pattern), action) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// actionMatches reports whether an IAM action pattern with * and ? wildcards matches an action, both in lower case
+func actionMatches(pattern, action string) bool {
+	matched, err := path.Match(pattern, action)
+	return err == nil && matched
+}
+
+// policyStatement is the effect and actions of a policy statement
+type policyStatement struct {
+	effect        string
+	actions       []string
+	notActions    []string // Actions of a NotAction element, the statement applies to all others
+	unconditional bool     // Applies to all resources without conditions
+}
+
+// policyStatements extracts the statements of a policy document, which may hold a single statement or a list,
+// each with a single action or a list
+func policyStatements(document string) []policyStatement {
+	var doc struct {
+		Statement json.RawMessage
+	}
+	if err := json.Unmarshal([]byte(document), &doc); err != nil || len(doc.Statement) == 0 {
+		return nil
+	}
+
+	type rawStatement struct {
+		Effect      string
+		Action      json.RawMessage
+		NotAction   json.RawMessage
+		Resource    json.RawMessage
+		NotResource json.RawMessage
+		Condition   json.RawMessage
+	}
+	var raw []rawStatement
+	if err := json.Unmarshal(doc.Statement, &raw); err != nil {
+		var single rawStatement
+		if err := json.Unmarshal(doc.Statement, &single); err != nil {
+			return nil
+		}
+		raw = []rawStatement{single}
+	}
+
+	var statements []policyStatement
+	for _, statement := range raw {
+		resources := stringOrList(statement.Resource)
+		statements = append(statements, policyStatement{
+			effect:        statement.Effect,
+			actions:       stringOrList(statement.Action),
+			notActions:    stringOrList(statement.NotAction),
+			unconditional: (len(resources) == 0 || slices.Contains(resources, "*")) && len(statement.NotResource) == 0 && len(statement.Condition) == 0,
+		})
+	}
+	return statements
+}
+
+// stringOrList decodes a JSON value that is either a string or a list of strings
+func stringOrList(value json.RawMessage) []string {
+	var single string
+	if err := json.Unmarshal(value, &single); err == nil {
+		return []string{single}
+	}
+	var list []string
+	_ = json.Unmarshal(value, &list)
+	return list
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Test extracting actions from the different statement forms
+func TestPolicyStatements(t *testing.T) {
+	single := policyStatements(`{"Statement": {"Effect": "Allow", "Action": "s3:GetObject"}}`)
+	if len(single) != 1 || single[0].actions[0] != "s3:GetObject" {
+		t.Errorf("Expected a single statement, got %+v", single)
+	}
+
+	statements := policyStatements(`{"Statement": [{"Effect": "Allow", "Action": ["s3:GetObject", "s3:PutObject"]}, {"Effect": "Deny", "Action": "s3:PutObject"}]}`)
+	if len(statements) != 2 || len(statements[0].actions) != 2 || statements[1].effect != "Deny" {
+		t.Errorf("Expected two statements, got %+v", statements)
+	}
+}
+
+// Test comparing two roles
+func TestCompareRoles(t *testing.T) {
+	managedArn := "arn:aws:iam::123456789012:policy/Shared"
+	managed := map[string]PolicyItem{
+		managedArn: {policyName: "Shared", policyArn: managedArn, policyType: "Customer", rawDocument: `{"Statement": [{"Effect": "Allow", "Action": "s3:GetObject"}]}`},
+	}
+	prod := &RoleItem{
+		roleName:           "app-prod",
+		trustPolicy:        `{"Principal": "ecs-tasks.amazonaws.com"}`,
+		maxSessionDuration: 3600,
+		tags:               map[string]string{"env": "prod"},
+		policiesLoaded:     true,
+		policies: []PolicyItem{
+			{policyName: "Shared", policyArn: managedArn, policyType: "Customer"},
+			{policyName: "Writes", policyType: "Inline", rawDocument: `{"Statement": [{"Effect": "Allow", "Action": ["s3:PutObject", "sqs:SendMessage"]}, {"Effect": "Deny", "Action": "SQS:SendMessage"}]}`},
+		},
+	}
+	staging := &RoleItem{
+		roleName:           "app-staging",
+		trustPolicy:        `{"Principal": "ecs-tasks.amazonaws.com"}`,
+		maxSessionDuration: 7200,
+		tags:               map[string]string{"env": "staging"},
+		policiesLoaded:     true,
+		policies: []PolicyItem{
+			{policyName: "Shared", policyArn: managedArn, policyType: "Customer"},
+			{policyName: "Unknown", policyArn: "arn:aws:iam::123456789012:policy/Unknown", policyType: "Customer"},
+		},
+	}
+
+	report := stripAnsiCodes(compareRoles(prod, staging, managed))
+
+	for _, expected := range []string{
+		"Shared (1):\n  = Shared [Customer]",
+		"Only app-prod (1):\n  - Writes [Inline]",
+		"Only app-staging (1):\n  + Unknown [Customer]",
+		"Trust policy\n  Identical",
+		"1h0m0s",
+		"2h0m0s",
+		"Documents not loaded, left out: Unknown",
+		"Allowed for both: 1 actions",
+		"Only app-prod (1):\n  - s3:PutObject",
+	} {
+		if !strings.Contains(report, expected) {
+			t.Errorf("Expected report to contain %q, got:\n%s", expected, report)
+		}
+	}
+	if strings.Contains(report, "sqs:SendMessage") {
+		t.Errorf("Expected denied action to be left out")
+	}
+}
+
+// Test marking two roles and opening the comparison
+func TestOpenCompare(t *testing.T) {
+	m := createTestModel()
+	m.rolesList.SetItems([]list.Item{&RoleItem{roleName: "a"}, &RoleItem{roleName: "b"}, &RoleItem{roleName: "c"}})
+
+	space := tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}}
+	compare := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'c'}}
+
+	newModel, _ := m.Update(space)
+	m = newModel.(model)
+	newModel, _ = m.Update(compare)
+	m = newModel.(model)
+	if m.currentScreen != "roles" || !strings.Contains(m.statusMsg, "1 marked") {
+		t.Errorf("Expected comparison to need two roles, got '%s'", m.statusMsg)
+	}
+
+	m.rolesList.Select(2)
+	newModel, _ = m.Update(space)
+	m = newModel.(model)
+	newModel, _ = m.Update(compare)
+	m = newModel.(model)
+	if m.currentScreen != "compare" || m.compared[0].roleName != "a" || m.compared[1].roleName != "c" {
+		t.Fatalf("Expected a and c to be compared")
+	}
+
+	newModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if newModel.(model).currentScreen != "roles" {
+		t.Errorf("Expected esc to return to the roles")
+	}
+}
+
+// Test wildcards, NotAction, resource-scoped denies and the permissions boundary in the effective actions
+func TestEffectiveActions(t *testing.T) {
+	boundaryArn := "arn:aws:iam::123456789012:policy/Boundary"
+	managed := map[string]PolicyItem{
+		boundaryArn: {policyArn: boundaryArn, rawDocument: `{"Statement": {"Effect": "Allow", "Action": ["s3:*", "sqs:*"], "Resource": "*"}}`},
+	}
+	wide := &RoleItem{roleName: "wide", policiesLoaded: true, policies: []PolicyItem{{policyName: "P", policyType: "Inline", rawDocument: `{"Statement": [
+		{"Effect": "Allow", "Action": "s3:*", "Resource": "*"},
+		{"Effect": "Deny", "Action": "s3:DeleteBucket", "Resource": "*"},
+		{"Effect": "Deny", "Action": "s3:PutObject", "Resource": "arn:aws:s3:::logs/*"},
+		{"Effect": "Allow", "NotAction": "iam:*", "Resource": "*"}
+	]}`}}}
+	narrow := &RoleItem{roleName: "narrow", permissionsBoundary: boundaryArn, policiesLoaded: true, policies: []PolicyItem{{policyName: "P", policyType: "Inline", rawDocument: `{"Statement": [
+		{"Effect": "Allow", "Action": ["s3:GetObject", "s3:DeleteBucket", "ec2:RunInstances"], "Resource": "*"},
+		{"Effect": "Deny", "NotAction": "s3:*", "Resource": "*"}
+	]}`}}}
+
+	statementsA, boundaryA, _ := roleStatements(wide, managed)
+	statementsB, boundaryB, missing := roleStatements(narrow, managed)
+	if len(missing) != 0 {
+		t.Fatalf("Expected no missing documents, got %v", missing)
+	}
+	candidates := namedActions(statementsA, statementsB)
+	actionsA := effectiveActions(statementsA, boundaryA, candidates)
+	actionsB := effectiveActions(statementsB, boundaryB, candidates)
+
+	for action, expected := range map[string]string{
+		"s3:*":                     "s3:* (partly denied)",
+		"s3:getobject":             "s3:GetObject",
+		"s3:putobject":             "s3:PutObject (partly denied)",
+		"ec2:runinstances":         "ec2:RunInstances",
+		"all actions except iam:*": "all actions except iam:*",
+	} {
+		if actionsA[action] != expected {
+			t.Errorf("Expected %q for the wide role, got %q", expected, actionsA[action])
+		}
+	}
+	if _, ok := actionsA["s3:deletebucket"]; ok {
+		t.Errorf("Expected the denied action to be left out")
+	}
+
+	// The boundary and the NotAction deny leave only S3 actions the boundary allows
+	if len(actionsB) != 2 || actionsB["s3:getobject"] != "s3:GetObject" || actionsB["s3:deletebucket"] != "s3:DeleteBucket" {
+		t.Errorf("Expected s3:GetObject and s3:DeleteBucket for the narrow role, got %v", actionsB)
+	}
+
+	// A boundary without its document is reported
+	if _, _, missing := roleStatements(narrow, nil); len(missing) != 1 || missing[0] != "boundary "+boundaryArn {
+		t.Errorf("Expected the missing boundary, got %v", missing)
+	}
+}
+
+// Test the comparison loads policies and documents that are not loaded yet
+func TestCompareLoadsPolicies(t *testing.T) {
+	f := newTestBackend()
+	boundaryArn := f.customerPolicyArn("Boundary")
+	f.addPolicy(boundaryArn, `{"Statement": {"Effect": "Allow", "Action": "*", "Resource": "*"}}`)
+	f.addRole(fakeRole{name: "OtherRole", boundary: boundaryArn, inline: map[string]string{"Queue": `{"Statement": {"Effect": "Allow", "Action": "sqs:SendMessage", "Resource": "*"}}`}})
+
+	m := createTestModel()
+	m.backend = f
+	m.rolesList.SetItems([]list.Item{
+		&RoleItem{roleName: "TestRole", marked: true},
+		&RoleItem{roleName: "OtherRole", permissionsBoundary: boundaryArn, marked: true},
+	})
+	cmd := m.openCompare()
+	if !strings.Contains(stripAnsiCodes(m.compareView.View()), "Policies are not loaded yet") {
+		t.Errorf("Expected the comparison to show while loading")
+	}
+
+	// Policies first, then the documents they miss
+	for range 2 {
+		if cmd == nil {
+			t.Fatalf("Expected a load of what the comparison misses")
+		}
+		newModel, next := m.Update(requestResponse(t, cmd))
+		m = newModel.(model)
+		cmd = next
+	}
+	if cmd != nil {
+		t.Errorf("Expected nothing left to load")
+	}
+	if !m.compared[1].policiesLoaded || m.compareDocuments[boundaryArn] == "" {
+		t.Fatalf("Expected the policies and the boundary to be loaded")
+	}
+	m.compareView.Height = 100
+	report := stripAnsiCodes(m.compareView.View())
+	if strings.Contains(report, "Documents not loaded") || !strings.Contains(report, "+ sqs:SendMessage") {
+		t.Errorf("Expected the actions of the loaded documents, got:\n%s", report)
+	}
+}
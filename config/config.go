@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/charmbracelet/lipgloss"
 )
@@ -25,9 +26,37 @@ type ThemeColors struct {
 	Debug           string `json:"debug"`           // Debug message color
 }
 
+// CacheSettings holds settings for the on-disk IAM cache
+type CacheSettings struct {
+	TTLMinutes int `json:"ttlMinutes"` // Minutes before cached data is refreshed on startup
+}
+
+// OrgScanSettings holds settings for scanning every account of an organization
+type OrgScanSettings struct {
+	RoleName    string `json:"roleName"`    // Role assumed in each member account
+	Concurrency int    `json:"concurrency"` // Accounts scanned at the same time
+}
+
+// DriftSettings holds settings for comparing a role across profiles
+type DriftSettings struct {
+	Environments map[string]string `json:"environments,omitempty"` // Environment name by profile name, filling {env} in role names
+}
+
+// APISettings holds settings for calls to AWS
+type APISettings struct {
+	TimeoutSeconds    int                `json:"timeoutSeconds"`    // Seconds an attempt of a call may wait for its response
+	MaxAttempts       int                `json:"maxAttempts"`       // Attempts of a call before it fails
+	RequestsPerSecond map[string]float64 `json:"requestsPerSecond"` // Client-side rate limit by API, e.g. "iam"
+}
+
 // Config holds application configuration
 type Config struct {
-	Colors ThemeColors `json:"colors"`
+	Colors    ThemeColors       `json:"colors"`
+	Cache     CacheSettings     `json:"cache"`
+	OrgScan   OrgScanSettings   `json:"orgScan"`
+	API       APISettings       `json:"api"`
+	Drift     DriftSettings     `json:"drift"`
+	Endpoints map[string]string `json:"endpoints,omitempty"` // AWS endpoint URL by profile name, e.g. LocalStack
 }
 
 // Default configuration
@@ -46,6 +75,23 @@ var DefaultConfig = Config{
 		JsonServiceName: "35",  // Pink
 		Debug:           "#FF00FF",
 	},
+	Cache: CacheSettings{
+		TTLMinutes: 60,
+	},
+	OrgScan: OrgScanSettings{
+		RoleName:    "OrganizationAccountAccessRole",
+		Concurrency: 5,
+	},
+	API: APISettings{
+		TimeoutSeconds: 30,
+		MaxAttempts:    10,
+		// Below the account-wide IAM and Organizations quotas, leaving room for other tools
+		RequestsPerSecond: map[string]float64{
+			"iam":           10,
+			"sts":           20,
+			"organizations": 5,
+		},
+	},
 }
 
 // Load reads config from file or creates a default if not exist
@@ -106,14 +152,93 @@ func SaveDefaultConfig() error {
 	return nil
 }
 
-// getConfigPath returns the path to the configuration file
-func getConfigPath() (string, error) {
+// Dir returns the atui configuration directory
+func Dir() (string, error) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		return "", fmt.Errorf("failed to get user home directory: %w", err)
 	}
 
-	return filepath.Join(homeDir, ".config", "atui", "config.json"), nil
+	return filepath.Join(homeDir, ".config", "atui"), nil
+}
+
+// getConfigPath returns the path to the configuration file
+func getConfigPath() (string, error) {
+	configDir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(configDir, "config.json"), nil
+}
+
+// CacheTTL returns how long cached IAM data stays fresh, falling back to the default
+func (c *Config) CacheTTL() time.Duration {
+	minutes := c.Cache.TTLMinutes
+	if minutes <= 0 {
+		minutes = DefaultConfig.Cache.TTLMinutes
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// OrgScanRoleName returns the role assumed in member accounts, falling back to the default
+func (c *Config) OrgScanRoleName() string {
+	if c.OrgScan.RoleName == "" {
+		return DefaultConfig.OrgScan.RoleName
+	}
+	return c.OrgScan.RoleName
+}
+
+// EndpointURL returns the AWS endpoint URL configured for a profile, empty for the public endpoints
+func (c *Config) EndpointURL(profile string) string {
+	return c.Endpoints[profile]
+}
+
+// DriftEnvironment returns the environment name of a profile in drift role names, the profile name unless configured
+func (c *Config) DriftEnvironment(profile string) string {
+	if env, ok := c.Drift.Environments[profile]; ok {
+		return env
+	}
+	return profile
+}
+
+// OrgScanConcurrency returns how many accounts are scanned at the same time, falling back to the default
+func (c *Config) OrgScanConcurrency() int {
+	if c.OrgScan.Concurrency <= 0 {
+		return DefaultConfig.OrgScan.Concurrency
+	}
+	return c.OrgScan.Concurrency
+}
+
+// CallTimeout returns how long an attempt of an AWS call may wait for its response, falling back to the default
+func (c *Config) CallTimeout() time.Duration {
+	seconds := c.API.TimeoutSeconds
+	if seconds <= 0 {
+		seconds = DefaultConfig.API.TimeoutSeconds
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// MaxAttempts returns how many times an AWS call is attempted, falling back to the default
+func (c *Config) MaxAttempts() int {
+	if c.API.MaxAttempts <= 0 {
+		return DefaultConfig.API.MaxAttempts
+	}
+	return c.API.MaxAttempts
+}
+
+// RequestRates returns the client-side rate limit of every API, configured rates replacing the defaults
+func (c *Config) RequestRates() map[string]float64 {
+	rates := make(map[string]float64)
+	for api, rate := range DefaultConfig.API.RequestsPerSecond {
+		rates[api] = rate
+	}
+	for api, rate := range c.API.RequestsPerSecond {
+		if rate > 0 {
+			rates[api] = rate
+		}
+	}
+	return rates
 }
 
 // GetTheme creates a lipgloss theme from the configuration
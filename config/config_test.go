@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 )
 
 // Test DefaultConfig values
@@ -383,3 +384,78 @@ func TestPartialConfigLoading(t *testing.T) {
 		t.Errorf("Expected status to be empty (zero value), got '%s'", config.Colors.Status)
 	}
 }
+
+// Test cache TTL fallback to the default
+func TestCacheTTL(t *testing.T) {
+	config := Config{}
+	if ttl := config.CacheTTL(); ttl != time.Duration(DefaultConfig.Cache.TTLMinutes)*time.Minute {
+		t.Errorf("Expected default cache TTL, got %v", ttl)
+	}
+
+	config.Cache.TTLMinutes = 5
+	if ttl := config.CacheTTL(); ttl != 5*time.Minute {
+		t.Errorf("Expected cache TTL to be 5m, got %v", ttl)
+	}
+}
+
+// Test endpoint URLs configured by profile
+func TestEndpointURL(t *testing.T) {
+	config := Config{}
+	if config.EndpointURL("localstack") != "" {
+		t.Errorf("Expected public endpoints by default")
+	}
+
+	if err := json.Unmarshal([]byte(`{"endpoints": {"localstack": "http://localhost:4566"}}`), &config); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.EndpointURL("localstack") != "http://localhost:4566" || config.EndpointURL("prod") != "" {
+		t.Errorf("Expected the endpoint of the localstack profile only, got '%s'", config.EndpointURL("localstack"))
+	}
+}
+
+// Test organization scan settings fallback to the defaults
+func TestOrgScanSettings(t *testing.T) {
+	config := Config{}
+	if config.OrgScanRoleName() != "OrganizationAccountAccessRole" || config.OrgScanConcurrency() != 5 {
+		t.Errorf("Expected default scan settings, got '%s' and %d", config.OrgScanRoleName(), config.OrgScanConcurrency())
+	}
+
+	config.OrgScan = OrgScanSettings{RoleName: "audit", Concurrency: 2}
+	if config.OrgScanRoleName() != "audit" || config.OrgScanConcurrency() != 2 {
+		t.Errorf("Expected configured scan settings, got '%s' and %d", config.OrgScanRoleName(), config.OrgScanConcurrency())
+	}
+}
+
+// Test AWS call settings fallback to the defaults, with configured rates replacing single APIs
+func TestAPISettings(t *testing.T) {
+	config := Config{}
+	if config.CallTimeout() != 30*time.Second || config.MaxAttempts() != 10 || config.RequestRates()["iam"] != 10 {
+		t.Errorf("Expected default call settings, got %v, %d and %v", config.CallTimeout(), config.MaxAttempts(), config.RequestRates())
+	}
+
+	if err := json.Unmarshal([]byte(`{"api": {"timeoutSeconds": 5, "maxAttempts": 3, "requestsPerSecond": {"iam": 2}}}`), &config); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	rates := config.RequestRates()
+	if config.CallTimeout() != 5*time.Second || config.MaxAttempts() != 3 || rates["iam"] != 2 || rates["sts"] != 20 {
+		t.Errorf("Expected configured call settings, got %v, %d and %v", config.CallTimeout(), config.MaxAttempts(), rates)
+	}
+	if DefaultConfig.API.RequestsPerSecond["iam"] != 10 {
+		t.Errorf("Expected the defaults to stay unchanged")
+	}
+}
+
+// Test drift environments default to the profile name
+func TestDriftEnvironment(t *testing.T) {
+	config := Config{}
+	if config.DriftEnvironment("acme-prod") != "acme-prod" {
+		t.Errorf("Expected the profile name by default")
+	}
+
+	if err := json.Unmarshal([]byte(`{"drift": {"environments": {"acme-prod": "prod"}}}`), &config); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.DriftEnvironment("acme-prod") != "prod" || config.DriftEnvironment("dev") != "dev" {
+		t.Errorf("Expected the environment of acme-prod only, got '%s'", config.DriftEnvironment("acme-prod"))
+	}
+}
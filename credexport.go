@@ -0,0 +1,282 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	appconfig "github.com/vlkyrylenko/atui/config"
+)
+
+// credentialFormatItem is a way to write out temporary credentials
+type credentialFormatItem struct {
+	format      string // "shell", "profile" or "process"
+	title       string
+	description string
+}
+
+func (i credentialFormatItem) Title() string       { return i.title }
+func (i credentialFormatItem) Description() string { return i.description }
+func (i credentialFormatItem) FilterValue() string { return i.title }
+
+// credentialsExportedMsg is sent when credentials were written out
+type credentialsExportedMsg struct {
+	text string // Where the credentials went and how to use them
+	err  error
+}
+
+// openCredentialsExport lists the ways to write out the credentials of the current assumed role or session
+func (m *model) openCredentialsExport() {
+	if m.offline || m.aggregated() {
+		m.statusMsg = "Switch to one profile to export its credentials"
+		return
+	}
+	identity := m.exportedIdentity()
+	// The expiration of the profile's own session is only known once its credentials are read
+	expires := "expires with the session"
+	if !identity.expiration.IsZero() {
+		expires = fmt.Sprintf("expires %s", expirationText(identity.expiration, time.Now()))
+	}
+	m.credentialsList.SetItems([]list.Item{
+		&credentialFormatItem{format: "shell", title: "Shell exports", description: fmt.Sprintf("%s for eval or source, %s", credentialsFileName(identity.roleName, "sh"), expires)},
+		&credentialFormatItem{format: "profile", title: "Credentials file profile", description: fmt.Sprintf("[%s] in the shared credentials file, %s", credentialsProfileName(identity.roleName), expires)},
+		&credentialFormatItem{format: "process", title: "credential_process JSON", description: fmt.Sprintf("%s for credential_process, %s", credentialsFileName(identity.roleName, "json"), expires)},
+	})
+	m.currentScreen = "credentials"
+	updateKeyBindingsForScreen(m.currentScreen)
+	m.statusMsg = fmt.Sprintf("Temporary credentials of %s", identity.arn)
+}
+
+// exportedIdentity returns the identity whose credentials are exported: the last assumed role, or the profile's own
+// session, e.g. an MFA session, named after the profile
+func (m model) exportedIdentity() assumedIdentity {
+	if len(m.identities) > 0 {
+		return m.identities[len(m.identities)-1]
+	}
+	name := m.sessionProfile
+	if name == "" {
+		name = currentProfileName()
+	}
+	return assumedIdentity{session: m.session(), roleName: name, arn: m.userArn}
+}
+
+// temporaryCredentials reads the credentials of an identity, long-term access keys of a profile are refused
+func temporaryCredentials(ctx context.Context, identity assumedIdentity) (aws.Credentials, error) {
+	if identity.session.credentials != nil {
+		creds, err := identity.session.credentials.Retrieve(ctx)
+		if err != nil {
+			return creds, fmt.Errorf("error reading credentials: %w", err)
+		}
+		// Assumed role credentials are static, their expiration is known from AssumeRole
+		creds.CanExpire = true
+		creds.Expires = identity.expiration
+		return creds, nil
+	}
+
+	cfg, err := identity.session.loadConfig(ctx)
+	if err != nil {
+		return aws.Credentials{}, fmt.Errorf("error loading AWS configuration: %w", err)
+	}
+	creds, err := cfg.Credentials.Retrieve(ctx)
+	if err != nil {
+		return creds, fmt.Errorf("error reading credentials: %w", err)
+	}
+	if !creds.CanExpire {
+		return aws.Credentials{}, fmt.Errorf("profile %s uses long-term access keys, only temporary credentials like an MFA session or an assumed role can be exported", identity.roleName)
+	}
+	return creds, nil
+}
+
+// Write out the temporary credentials of an identity in a format
+func exportCredentialsCmd(format string, identity assumedIdentity) tea.Cmd {
+	return func() tea.Msg {
+		creds, err := temporaryCredentials(context.Background(), identity)
+		if err != nil {
+			return credentialsExportedMsg{err: err}
+		}
+		expires := creds.Expires.Local().Format("2006-01-02 15:04 MST")
+
+		switch format {
+		case "shell":
+			path, err := writeCredentialsFile(credentialsFileName(identity.roleName, "sh"), shellExports(creds))
+			if err != nil {
+				return credentialsExportedMsg{err: err}
+			}
+			return credentialsExportedMsg{text: fmt.Sprintf("Wrote %s, load it with: source %s (expires %s)", path, path, expires)}
+
+		case "profile":
+			path, err := sharedCredentialsFile()
+			if err != nil {
+				return credentialsExportedMsg{err: err}
+			}
+			profile := credentialsProfileName(identity.roleName)
+			if err := upsertCredentialsProfile(path, profile, creds); err != nil {
+				return credentialsExportedMsg{err: err}
+			}
+			return credentialsExportedMsg{text: fmt.Sprintf("Saved profile %s to %s, use it with AWS_PROFILE=%s (expires %s)", profile, path, profile, expires)}
+
+		default:
+			document, err := credentialProcessJSON(creds)
+			if err != nil {
+				return credentialsExportedMsg{err: err}
+			}
+			path, err := writeCredentialsFile(credentialsFileName(identity.roleName, "json"), document)
+			if err != nil {
+				return credentialsExportedMsg{err: err}
+			}
+			// Nothing refreshes the file, tools reading it get the same credentials until they expire
+			return credentialsExportedMsg{text: fmt.Sprintf("Wrote %s in the credential_process format, not refreshed (expires %s)", path, expires)}
+		}
+	}
+}
+
+// shellExports formats credentials as export lines for a POSIX shell
+func shellExports(creds aws.Credentials) string {
+	return fmt.Sprintf(
+		"# Expires %s\nexport AWS_ACCESS_KEY_ID=%s\nexport AWS_SECRET_ACCESS_KEY=%s\nexport AWS_SESSION_TOKEN=%s\nexport AWS_CREDENTIAL_EXPIRATION=%s\n",
+		creds.Expires.UTC().Format(time.RFC3339), creds.AccessKeyID, creds.SecretAccessKey, creds.SessionToken, creds.Expires.UTC().Format(time.RFC3339),
+	)
+}
+
+// credentialProcessJSON formats credentials as the output expected from a credential_process
+func credentialProcessJSON(creds aws.Credentials) (string, error) {
+	document, err := json.MarshalIndent(struct {
+		Version         int
+		AccessKeyId     string
+		SecretAccessKey string
+		SessionToken    string
+		Expiration      string
+	}{1, creds.AccessKeyID, creds.SecretAccessKey, creds.SessionToken, creds.Expires.UTC().Format(time.RFC3339)}, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(document) + "\n", nil
+}
+
+// credentialsProfileName returns the profile name credentials of a role are saved under
+func credentialsProfileName(roleName string) string {
+	return "atui-" + roleName
+}
+
+// credentialsFileName returns the file name credentials of a role are written to
+func credentialsFileName(roleName, extension string) string {
+	return fmt.Sprintf("atui-credentials-%s.%s", roleName, extension)
+}
+
+// credentialsDir returns the directory exported credential files are written to, readable by the user only
+func credentialsDir() (string, error) {
+	configDir, err := appconfig.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "credentials"), nil
+}
+
+// writeCredentialsFile writes an exported credentials file to the credentials directory and returns its path
+func writeCredentialsFile(name, content string) (string, error) {
+	dir, err := credentialsDir()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create credentials directory: %w", err)
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return path, nil
+}
+
+// upsertCredentialsProfile writes a profile to a credentials file, replacing a section of the same name. A symlinked
+// file is written through the link and keeps its permissions.
+func upsertCredentialsProfile(path, profile string, creds aws.Credentials) error {
+	var lines []string
+	if file, err := os.Open(path); err == nil {
+		scanner := bufio.NewScanner(file)
+		inProfile := false
+		for scanner.Scan() {
+			line := scanner.Text()
+			trimmed := strings.TrimSpace(line)
+			if strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") {
+				inProfile = trimmed == "["+profile+"]"
+			}
+			if !inProfile {
+				lines = append(lines, line)
+			}
+		}
+		_ = file.Close()
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("error reading %s: %w", path, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("error reading %s: %w", path, err)
+	}
+
+	// Keep one blank line between the existing sections and the new one
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) > 0 {
+		lines = append(lines, "")
+	}
+	lines = append(lines,
+		"["+profile+"]",
+		"# Written by atui, expires "+creds.Expires.UTC().Format(time.RFC3339),
+		"aws_access_key_id = "+creds.AccessKeyID,
+		"aws_secret_access_key = "+creds.SecretAccessKey,
+		"aws_session_token = "+creds.SessionToken,
+	)
+
+	// Replace the file a symlink points to rather than the link itself
+	target, err := filepath.EvalSymlinks(path)
+	if os.IsNotExist(err) {
+		target = path
+	} else if err != nil {
+		return fmt.Errorf("error resolving %s: %w", path, err)
+	}
+	mode := os.FileMode(0600)
+	if info, err := os.Stat(target); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+		return err
+	}
+
+	// Write to a temporary file next to the target first so a crash never leaves a truncated credentials file
+	tmp, err := os.CreateTemp(filepath.Dir(target), "."+filepath.Base(target)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("error writing %s: %w", path, err)
+	}
+	_, err = tmp.WriteString(strings.Join(lines, "\n") + "\n")
+	if err == nil {
+		err = tmp.Chmod(mode)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), target)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("error writing %s: %w", path, err)
+	}
+	return nil
+}
+
+// expirationText describes when credentials expire
+func expirationText(expiration, now time.Time) string {
+	if !expiration.After(now) {
+		return "already expired"
+	}
+	return fmt.Sprintf("at %s, in %s", expiration.Local().Format("15:04"), expiration.Sub(now).Round(time.Minute))
+}
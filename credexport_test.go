@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+var testCredentials = aws.Credentials{
+	AccessKeyID:     "ASIAEXAMPLE",
+	SecretAccessKey: "secret",
+	SessionToken:    "token",
+	CanExpire:       true,
+	Expires:         time.Date(2025, 1, 1, 13, 0, 0, 0, time.UTC),
+}
+
+// Test the shell and credential_process formats
+func TestCredentialFormats(t *testing.T) {
+	exports := shellExports(testCredentials)
+	for _, expected := range []string{
+		"# Expires 2025-01-01T13:00:00Z",
+		"export AWS_ACCESS_KEY_ID=ASIAEXAMPLE",
+		"export AWS_SESSION_TOKEN=token",
+	} {
+		if !strings.Contains(exports, expected) {
+			t.Errorf("Expected exports to contain %q, got:\n%s", expected, exports)
+		}
+	}
+
+	document, err := credentialProcessJSON(testCredentials)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var parsed map[string]any
+	if err := json.Unmarshal([]byte(document), &parsed); err != nil {
+		t.Fatalf("Expected valid JSON, got %v", err)
+	}
+	if parsed["Version"] != float64(1) || parsed["AccessKeyId"] != "ASIAEXAMPLE" || parsed["Expiration"] != "2025-01-01T13:00:00Z" {
+		t.Errorf("Unexpected credential_process output: %s", document)
+	}
+}
+
+// Test saving a profile to the credentials file
+func TestUpsertCredentialsProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials")
+	existing := "[default]\naws_access_key_id = AKIADEFAULT\n\n[atui-Deployer]\naws_access_key_id = OLD\n\n[other]\nregion = eu-west-1\n"
+	if err := os.WriteFile(path, []byte(existing), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := upsertCredentialsProfile(path, "atui-Deployer", testCredentials); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	content := string(data)
+	if strings.Contains(content, "OLD") || strings.Count(content, "[atui-Deployer]") != 1 {
+		t.Errorf("Expected the old profile to be replaced, got:\n%s", content)
+	}
+	for _, expected := range []string{"aws_access_key_id = AKIADEFAULT", "[other]\nregion = eu-west-1", "aws_session_token = token", "expires 2025-01-01T13:00:00Z"} {
+		if !strings.Contains(content, expected) {
+			t.Errorf("Expected credentials file to contain %q, got:\n%s", expected, content)
+		}
+	}
+	// The file is replaced by renaming, no temporary file is left behind
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("Expected only the credentials file, got %d files", len(entries))
+	}
+
+	// A missing file is created
+	newPath := filepath.Join(t.TempDir(), "aws", "credentials")
+	if err := upsertCredentialsProfile(newPath, "atui-Deployer", testCredentials); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if data, _ := os.ReadFile(newPath); !strings.HasPrefix(string(data), "[atui-Deployer]") {
+		t.Errorf("Expected a new credentials file, got:\n%s", data)
+	}
+	if info, _ := os.Stat(newPath); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected a new file readable by the user only, got %v", info.Mode().Perm())
+	}
+}
+
+// Test a symlinked credentials file is written through the link and keeps its permissions
+func TestUpsertCredentialsProfileSymlink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "dotfiles", "credentials")
+	if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(target, []byte("[default]\nregion = eu-west-1\n"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "credentials")
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := upsertCredentialsProfile(link, "atui-Deployer", testCredentials); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("Expected the symlink to be kept, got %v", err)
+	}
+	info, err := os.Stat(target)
+	if err != nil || info.Mode().Perm() != 0640 {
+		t.Fatalf("Expected the target to keep its permissions, got %v", err)
+	}
+	if data, _ := os.ReadFile(target); !strings.Contains(string(data), "region = eu-west-1") || !strings.Contains(string(data), "[atui-Deployer]") {
+		t.Errorf("Expected the profile added to the target, got:\n%s", data)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(target)); len(entries) != 1 {
+		t.Errorf("Expected no temporary file next to the target, got %d files", len(entries))
+	}
+}
+
+// Test exporting the credentials of the assumed role
+func TestExportCredentials(t *testing.T) {
+	m := createTestModel()
+	path := filepath.Join(t.TempDir(), "credentials")
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", path)
+	var newModel tea.Model
+	m.identities = []assumedIdentity{{
+		session:    awsSession{credentials: credentials.NewStaticCredentialsProvider("ASIAEXAMPLE", "secret", "token")},
+		roleName:   "Deployer",
+		expiration: time.Now().Add(time.Hour),
+	}}
+	newModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'E'}})
+	m = newModel.(model)
+	if m.currentScreen != "credentials" || len(m.credentialsList.Items()) != 3 {
+		t.Fatalf("Expected the export formats, got screen '%s'", m.currentScreen)
+	}
+
+	m.credentialsList.Select(1)
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	msg := cmd().(credentialsExportedMsg)
+	if msg.err != nil || !strings.Contains(msg.text, "AWS_PROFILE=atui-Deployer") {
+		t.Errorf("Unexpected export result %+v", msg)
+	}
+	if data, _ := os.ReadFile(path); !strings.Contains(string(data), "aws_access_key_id = ASIAEXAMPLE") {
+		t.Errorf("Expected the profile to be saved, got:\n%s", data)
+	}
+}
+
+// Test credential files are written to the configuration directory, readable by the user only
+func TestExportCredentialsFile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	identity := assumedIdentity{
+		session:    awsSession{credentials: credentials.NewStaticCredentialsProvider("ASIAEXAMPLE", "secret", "token")},
+		roleName:   "Deployer",
+		expiration: time.Now().Add(time.Hour),
+	}
+
+	path := filepath.Join(home, ".config", "atui", "credentials", "atui-credentials-Deployer.sh")
+	msg := exportCredentialsCmd("shell", identity)().(credentialsExportedMsg)
+	if msg.err != nil || !strings.Contains(msg.text, "source "+path) {
+		t.Fatalf("Expected the absolute path in the result, got %+v", msg)
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("Expected the exports readable by the user only, got %v", err)
+	}
+	if info, _ := os.Stat(filepath.Dir(path)); info.Mode().Perm() != 0700 {
+		t.Errorf("Expected the credentials directory private, got %v", info.Mode().Perm())
+	}
+
+	// The credential_process file is not refreshed, the result tells when it stops working
+	msg = exportCredentialsCmd("process", identity)().(credentialsExportedMsg)
+	if msg.err != nil || !strings.Contains(msg.text, "not refreshed (expires ") {
+		t.Errorf("Expected the expiration in the result, got %+v", msg)
+	}
+}
+
+// sessionBackend is a fake account whose profile credentials are a temporary session, like an MFA session
+type sessionBackend struct {
+	*fakeBackend
+}
+
+func (b sessionBackend) loadConfig(ctx context.Context, profile, region string) (aws.Config, error) {
+	cfg, err := b.fakeBackend.loadConfig(ctx, profile, region)
+	cfg.Credentials = aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) { return testCredentials, nil })
+	return cfg, err
+}
+
+// Test exporting the temporary session of the profile itself, and refusing long-term keys
+func TestExportSessionCredentials(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials")
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", path)
+	m := createTestModel()
+	m.sessionProfile = "mfa"
+	m.backend = sessionBackend{newTestBackend()}
+
+	newModel, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'E'}})
+	m = newModel.(model)
+	if m.currentScreen != "credentials" {
+		t.Fatalf("Expected the export formats for the profile's session, got screen '%s'", m.currentScreen)
+	}
+	m.credentialsList.Select(1)
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if msg := cmd().(credentialsExportedMsg); msg.err != nil || !strings.Contains(msg.text, "AWS_PROFILE=atui-mfa") {
+		t.Errorf("Unexpected export result %+v", msg)
+	}
+
+	// The fake backend's profile credentials are long-term keys
+	m.backend = newTestBackend()
+	_, cmd = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if msg := cmd().(credentialsExportedMsg); msg.err == nil || !strings.Contains(msg.err.Error(), "long-term access keys") {
+		t.Errorf("Expected long-term keys to be refused, got %+v", msg)
+	}
+}
+
+// Test describing the expiration
+func TestExpirationText(t *testing.T) {
+	now := time.Now()
+	if text := expirationText(now.Add(-time.Minute), now); text != "already expired" {
+		t.Errorf("Expected expired credentials, got '%s'", text)
+	}
+	if text := expirationText(now.Add(90*time.Minute), now); !strings.HasSuffix(text, "in 1h30m0s") {
+		t.Errorf("Expected the remaining time, got '%s'", text)
+	}
+}
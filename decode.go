@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// authorizationDecodedMsg is sent when STS decoded an authorization failure message
+type authorizationDecodedMsg struct {
+	decoded string // JSON document of the authorization decision
+}
+
+// decodedList is a list of a decoded authorization message, which wraps every list in an object
+type decodedList[T any] struct {
+	Items []T `json:"items"`
+}
+
+// authorizationDecision is the part of a decoded authorization message summarized above the document
+type authorizationDecision struct {
+	Allowed           bool                          `json:"allowed"`
+	ExplicitDeny      bool                          `json:"explicitDeny"`
+	MatchedStatements decodedList[decodedStatement] `json:"matchedStatements"`
+	Context           struct {
+		Principal struct {
+			Arn string `json:"arn"`
+		} `json:"principal"`
+		Action     string                        `json:"action"`
+		Resource   string                        `json:"resource"`
+		Conditions decodedList[decodedCondition] `json:"conditions"`
+	} `json:"context"`
+}
+
+// decodedStatement is a policy statement that matched the request
+type decodedStatement struct {
+	StatementID    string `json:"statementId"`
+	Effect         string `json:"effect"`
+	SourcePolicyID string `json:"sourcePolicyId"`
+}
+
+// decodedCondition is a context key of the request with its values
+type decodedCondition struct {
+	Key    string `json:"key"`
+	Values decodedList[struct {
+		Value string `json:"value"`
+	}] `json:"values"`
+}
+
+// encodedMessage returns the encoded message of pasted text, which may be the whole error, e.g.
+// "... Encoded authorization failure message: AbC..."
+func encodedMessage(text string) string {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return ""
+	}
+	// The message is the last word of the error
+	return strings.Trim(fields[len(fields)-1], `"'.,`)
+}
+
+// decodeAuthorizationMessageCmd decodes an encoded authorization failure message with STS
+func decodeAuthorizationMessageCmd(ctx context.Context, session awsSession, encoded string) tea.Cmd {
+	return func() tea.Msg {
+		cfg, err := session.loadConfig(ctx)
+		if err != nil {
+			return errorMsg(fmt.Errorf("error loading AWS configuration: %w", err))
+		}
+
+		result, err := session.stsClient(cfg).DecodeAuthorizationMessage(ctx, &sts.DecodeAuthorizationMessageInput{
+			EncodedMessage: aws.String(encoded),
+		})
+		if err != nil {
+			return errorMsg(fmt.Errorf("error decoding authorization message: %w", err))
+		}
+		return authorizationDecodedMsg{decoded: aws.ToString(result.DecodedMessage)}
+	}
+}
+
+// openDecodePrompt asks for an encoded authorization failure message
+func (m *model) openDecodePrompt() tea.Cmd {
+	m.decodeInput.SetValue("")
+	m.currentScreen = "decode_prompt"
+	updateKeyBindingsForScreen(m.currentScreen)
+	m.statusMsg = "Paste the encoded authorization failure message of an AWS error"
+	return m.decodeInput.Focus()
+}
+
+// startDecode decodes the pasted message and shows the decoded document once STS answers
+func (m *model) startDecode(text string) tea.Cmd {
+	encoded := encodedMessage(text)
+	if encoded == "" {
+		return nil
+	}
+	m.decodeInput.Blur()
+	m.currentScreen = "decoded"
+	updateKeyBindingsForScreen(m.currentScreen)
+	m.statusMsg = ""
+	m.decodeSummary = nil
+	m.policyDocument = ""
+	m.policyView.SetContent("")
+	m.searchMode = false
+	m.searchQuery = ""
+	m.searchResults = []int{}
+	m.currentMatch = 0
+
+	session := m.session()
+	return m.startRequest("decoded", "Decoding authorization message...", func(ctx context.Context) tea.Cmd {
+		return decodeAuthorizationMessageCmd(ctx, session, encoded)
+	})
+}
+
+// showDecoded shows a decoded authorization message with its summary
+func (m *model) showDecoded(decoded string) {
+	m.showDocument(decoded)
+	var decision authorizationDecision
+	if err := json.Unmarshal([]byte(decoded), &decision); err != nil {
+		m.decodeSummary = nil
+		return
+	}
+	m.decodeSummary = decision.summary()
+}
+
+// summary describes the decision in a few lines: the action on the resource, who asked, the statements that
+// decided it and the request context conditions are evaluated against
+func (d authorizationDecision) summary() []string {
+	verdict := "Denied"
+	if d.Allowed {
+		verdict = "Allowed"
+	} else if d.ExplicitDeny {
+		verdict = "Explicitly denied"
+	}
+	lines := []string{fmt.Sprintf("%s %s on %s", verdict, d.Context.Action, d.Context.Resource)}
+	if d.Context.Principal.Arn != "" {
+		lines = append(lines, "Principal: "+d.Context.Principal.Arn)
+	}
+
+	statements := "none, no policy allows the action"
+	if len(d.MatchedStatements.Items) > 0 {
+		var matched []string
+		for _, statement := range d.MatchedStatements.Items {
+			text := statement.Effect
+			if statement.StatementID != "" {
+				text += " " + statement.StatementID
+			}
+			if statement.SourcePolicyID != "" {
+				text += " in " + statement.SourcePolicyID
+			}
+			matched = append(matched, text)
+		}
+		statements = strings.Join(matched, "; ")
+	}
+	lines = append(lines, "Matched statements: "+statements)
+
+	if len(d.Context.Conditions.Items) > 0 {
+		var conditions []string
+		for _, condition := range d.Context.Conditions.Items {
+			var values []string
+			for _, value := range condition.Values.Items {
+				values = append(values, value.Value)
+			}
+			conditions = append(conditions, condition.Key+"="+strings.Join(values, ","))
+		}
+		lines = append(lines, "Context: "+strings.Join(conditions, " "))
+	}
+	return lines
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/aws/smithy-go"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// decodedDenial is a decoded message of a denied ec2:RunInstances request
+const decodedDenial = `{"allowed":false,"explicitDeny":true,"matchedStatements":{"items":[{"statementId":"DenyLargeInstances","effect":"DENY","sourcePolicyId":"arn:aws:iam::123456789012:policy/Guardrails"}]},"failures":{"items":[]},"context":{"principal":{"id":"AROAEXAMPLE:jane","arn":"arn:aws:sts::123456789012:assumed-role/Developer/jane"},"action":"ec2:RunInstances","resource":"arn:aws:ec2:eu-west-1:123456789012:instance/*","conditions":{"items":[{"key":"ec2:InstanceType","values":{"items":[{"value":"p4d.24xlarge"}]}},{"key":"aws:Region","values":{"items":[{"value":"eu-west-1"}]}}]}}}`
+
+// Test the encoded message is found in a pasted error
+func TestEncodedMessage(t *testing.T) {
+	tests := map[string]string{
+		"AbC-123_x": "AbC-123_x",
+		"  AbC123 ": "AbC123",
+		"":          "",
+		`"AbC123".`: "AbC123",
+		"An error occurred (UnauthorizedOperation) when calling the RunInstances operation: You are not authorized to perform this operation. Encoded authorization failure message: AbC123": "AbC123",
+	}
+	for text, expected := range tests {
+		if got := encodedMessage(text); got != expected {
+			t.Errorf("Expected '%s' for '%s', got '%s'", expected, text, got)
+		}
+	}
+}
+
+// Test a pasted message is decoded, summarized and searchable like a policy document
+func TestDecodeAuthorizationMessage(t *testing.T) {
+	f := newTestBackend()
+	m := createTestModel()
+	m.backend = f
+
+	newModel, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("e")})
+	m = newModel.(model)
+	if m.currentScreen != "decode_prompt" {
+		t.Fatalf("Expected the decode prompt, got '%s'", m.currentScreen)
+	}
+	pasted := "Encoded authorization failure message: " + base64.StdEncoding.EncodeToString([]byte(decodedDenial))
+	newModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(pasted), Paste: true})
+	m = newModel.(model)
+	newModel, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = newModel.(model)
+	if m.currentScreen != "decoded" || !m.loading() {
+		t.Fatalf("Expected the message to be decoding on the decoded screen")
+	}
+	newModel, _ = m.Update(requestResponse(t, cmd))
+	m = newModel.(model)
+
+	summary := strings.Join(m.decodeSummary, "\n")
+	for _, expected := range []string{
+		"Explicitly denied ec2:RunInstances on arn:aws:ec2:eu-west-1:123456789012:instance/*",
+		"Principal: arn:aws:sts::123456789012:assumed-role/Developer/jane",
+		"Matched statements: DENY DenyLargeInstances in arn:aws:iam::123456789012:policy/Guardrails",
+		"Context: ec2:InstanceType=p4d.24xlarge aws:Region=eu-west-1",
+	} {
+		if !strings.Contains(summary, expected) {
+			t.Errorf("Expected the summary to contain %q, got:\n%s", expected, summary)
+		}
+	}
+	if !strings.Contains(stripAnsiCodes(m.policyDocument), `"action": "ec2:RunInstances"`) {
+		t.Errorf("Expected the pretty-printed document, got:\n%s", m.policyDocument)
+	}
+
+	// The document is searched like a policy document
+	for _, r := range "/guardrails" {
+		newModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+		m = newModel.(model)
+	}
+	newModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = newModel.(model)
+	if len(m.searchResults) != 1 {
+		t.Errorf("Expected one match of the policy, got %d", len(m.searchResults))
+	}
+
+	newModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	m = newModel.(model)
+	if m.currentScreen != "roles" {
+		t.Errorf("Expected Esc to return to the roles, got '%s'", m.currentScreen)
+	}
+}
+
+// Test allowed decisions and decisions without matched statements are summarized
+func TestDecisionSummary(t *testing.T) {
+	var d authorizationDecision
+	d.Context.Action = "s3:GetObject"
+	d.Context.Resource = "arn:aws:s3:::bucket/key"
+	summary := d.summary()
+	if summary[0] != "Denied s3:GetObject on arn:aws:s3:::bucket/key" || summary[1] != "Matched statements: none, no policy allows the action" {
+		t.Errorf("Expected an implicit deny, got %q", summary)
+	}
+	d.Allowed = true
+	if summary := d.summary(); summary[0] != "Allowed s3:GetObject on arn:aws:s3:::bucket/key" {
+		t.Errorf("Expected an allowed decision, got %q", summary)
+	}
+}
+
+// Test a message STS cannot decode fails on the decoded screen and can be retried
+func TestDecodeInvalidMessage(t *testing.T) {
+	f := newTestBackend()
+	f.failOn("DecodeAuthorizationMessage", &smithy.GenericAPIError{Code: "InvalidAuthorizationMessageException", Message: "The encoded authorization message is invalid"})
+	m := createTestModel()
+	m.backend = f
+	m.openDecodePrompt()
+
+	cmd := m.startDecode("not-a-message")
+	newModel, _ := m.Update(requestResponse(t, cmd))
+	m = newModel.(model)
+	if m.failure == nil || m.failure.screen != "decoded" || m.failure.retry == nil || m.decodeSummary != nil {
+		t.Fatalf("Expected a retryable failure on the decoded screen, got %+v", m.failure)
+	}
+}
+
+// Test decoding is not offered for offline data
+func TestDecodeOffline(t *testing.T) {
+	m := createTestModel()
+	m.offline = true
+	newModel, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("e")})
+	m = newModel.(model)
+	if cmd != nil || m.currentScreen != "roles" {
+		t.Errorf("Expected no decode prompt offline")
+	}
+}
@@ -0,0 +1,365 @@
+package main
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+)
+
+// demoRoleCount is the number of roles of the --demo account by default
+const demoRoleCount = 3000
+
+var (
+	demoTeams        = []string{"payments", "orders", "search", "identity", "data", "platform", "mobile", "billing", "analytics", "ml", "growth", "support"}
+	demoEnvironments = []string{"dev", "staging", "prod"}
+	demoServices     = []string{"api", "worker", "ingest", "scheduler", "reports", "gateway", "etl", "notifier", "exporter", "sync"}
+	demoFirstNames   = []string{"alex", "sam", "maria", "chen", "priya", "jonas", "fatima", "lucas", "aiko", "omar", "nina", "diego", "emma", "ivan", "zoe"}
+	demoLastNames    = []string{"smith", "garcia", "wang", "patel", "muller", "khan", "silva", "tanaka", "haddad", "novak", "rossi", "kim", "okafor", "berg"}
+	demoPermSets     = []string{"AdministratorAccess", "PowerUserAccess", "ReadOnlyAccess", "Billing", "DataScientist", "SecurityAudit"}
+)
+
+// demoServicePrincipal is the trust and the AWS managed policy of a kind of service role
+type demoServicePrincipal struct {
+	service string
+	policy  string
+}
+
+var demoServicePrincipals = []demoServicePrincipal{
+	{"lambda.amazonaws.com", "arn:aws:iam::aws:policy/service-role/AWSLambdaBasicExecutionRole"},
+	{"ecs-tasks.amazonaws.com", "arn:aws:iam::aws:policy/service-role/AmazonECSTaskExecutionRolePolicy"},
+	{"ec2.amazonaws.com", "arn:aws:iam::aws:policy/AmazonSSMManagedInstanceCore"},
+	{"glue.amazonaws.com", "arn:aws:iam::aws:policy/service-role/AWSGlueServiceRole"},
+	{"states.amazonaws.com", "arn:aws:iam::aws:policy/service-role/AWSLambdaRole"},
+}
+
+// demoStatement is a statement of a generated policy document
+type demoStatement struct {
+	sid, effect, actions, resources, condition string // actions and resources are JSON values
+}
+
+// demoDocument builds a policy document from statements
+func demoDocument(statements ...demoStatement) string {
+	var parts []string
+	for _, s := range statements {
+		part := fmt.Sprintf(`{"Effect":%q,"Action":%s,"Resource":%s`, s.effect, s.actions, s.resources)
+		if s.sid != "" {
+			part = fmt.Sprintf(`{"Sid":%q,`, s.sid) + part[1:]
+		}
+		if s.condition != "" {
+			part += `,"Condition":` + s.condition
+		}
+		parts = append(parts, part+"}")
+	}
+	return `{"Version":"2012-10-17","Statement":[` + strings.Join(parts, ",") + `]}`
+}
+
+// demoTrust builds a trust policy for a principal
+func demoTrust(principal, action, condition string) string {
+	statement := fmt.Sprintf(`{"Effect":"Allow","Principal":%s,"Action":%q`, principal, action)
+	if condition != "" {
+		statement += `,"Condition":` + condition
+	}
+	return `{"Version":"2012-10-17","Statement":[` + statement + `}]}`
+}
+
+// demoGenerator fills a fake account with reproducible random data
+type demoGenerator struct {
+	f   *fakeBackend
+	rng *rand.Rand
+}
+
+// pick returns a random element
+func pick[T any](rng *rand.Rand, items []T) T {
+	return items[rng.IntN(len(items))]
+}
+
+// chance returns true with probability p
+func (g *demoGenerator) chance(p float64) bool {
+	return g.rng.Float64() < p
+}
+
+// newDemoBackend generates an account with the given number of roles, the same seed gives the same account
+func newDemoBackend(roleCount int, seed uint64) *fakeBackend {
+	g := &demoGenerator{f: newFakeBackend("210987654321"), rng: rand.New(rand.NewPCG(seed, seed))}
+	g.f.alias = "acme-demo"
+	g.addAWSPolicies()
+	g.addTeamPolicies()
+	for len(g.f.roles) < roleCount {
+		g.addRole()
+	}
+	g.addUsersAndGroups()
+	return g.f
+}
+
+// addAWSPolicies adds the AWS managed policies attached to generated roles
+func (g *demoGenerator) addAWSPolicies() {
+	all := `"*"`
+	policies := map[string]string{
+		"AdministratorAccess":                      demoDocument(demoStatement{"", "Allow", all, all, ""}),
+		"PowerUserAccess":                          demoDocument(demoStatement{"", "Allow", all, all, ""}, demoStatement{"", "Deny", `["iam:*","organizations:*","account:*"]`, all, ""}),
+		"ReadOnlyAccess":                           demoDocument(demoStatement{"", "Allow", `["*:Describe*","*:Get*","*:List*"]`, all, ""}),
+		"SecurityAudit":                            demoDocument(demoStatement{"", "Allow", `["iam:GenerateCredentialReport","iam:Get*","iam:List*","cloudtrail:Describe*","config:Describe*"]`, all, ""}),
+		"AmazonS3ReadOnlyAccess":                   demoDocument(demoStatement{"", "Allow", `["s3:Get*","s3:List*"]`, all, ""}),
+		"AmazonDynamoDBFullAccess":                 demoDocument(demoStatement{"", "Allow", `["dynamodb:*","dax:*"]`, all, ""}),
+		"AmazonSQSFullAccess":                      demoDocument(demoStatement{"", "Allow", `"sqs:*"`, all, ""}),
+		"CloudWatchAgentServerPolicy":              demoDocument(demoStatement{"", "Allow", `["cloudwatch:PutMetricData","logs:CreateLogStream","logs:PutLogEvents"]`, all, ""}),
+		"AmazonSSMManagedInstanceCore":             demoDocument(demoStatement{"", "Allow", `["ssm:UpdateInstanceInformation","ssmmessages:*","ec2messages:*"]`, all, ""}),
+		"service-role/AWSLambdaBasicExecutionRole": demoDocument(demoStatement{"", "Allow", `["logs:CreateLogGroup","logs:CreateLogStream","logs:PutLogEvents"]`, all, ""}),
+		"service-role/AWSLambdaRole":               demoDocument(demoStatement{"", "Allow", `"lambda:InvokeFunction"`, all, ""}),
+		"service-role/AWSGlueServiceRole":          demoDocument(demoStatement{"", "Allow", `["glue:*","s3:GetBucketLocation","s3:ListBucket"]`, all, ""}),
+		"service-role/AmazonECSTaskExecutionRolePolicy": demoDocument(demoStatement{"", "Allow",
+			`["ecr:GetAuthorizationToken","ecr:BatchGetImage","ecr:GetDownloadUrlForLayer","logs:CreateLogStream","logs:PutLogEvents"]`, all, ""}),
+	}
+	for name, document := range policies {
+		g.f.addPolicy("arn:aws:iam::aws:policy/"+name, document)
+	}
+}
+
+// addTeamPolicies adds the customer managed policies of every team, several with a version history
+func (g *demoGenerator) addTeamPolicies() {
+	for _, team := range demoTeams {
+		bucket := fmt.Sprintf(`["arn:aws:s3:::acme-%s-data","arn:aws:s3:::acme-%s-data/*"]`, team, team)
+		table := fmt.Sprintf(`"arn:aws:dynamodb:*:%s:table/%s-*"`, g.f.accountID, team)
+		queue := fmt.Sprintf(`"arn:aws:sqs:*:%s:%s-*"`, g.f.accountID, team)
+
+		// Each version grants a little more, the way policies grow over time
+		g.f.addPolicy(g.f.customerPolicyArn(team+"-s3-access"),
+			demoDocument(demoStatement{"Read", "Allow", `["s3:GetObject","s3:ListBucket"]`, bucket, ""}),
+			demoDocument(demoStatement{"ReadWrite", "Allow", `["s3:GetObject","s3:PutObject","s3:ListBucket"]`, bucket, ""}),
+			demoDocument(demoStatement{"ReadWrite", "Allow", `["s3:GetObject","s3:PutObject","s3:DeleteObject","s3:ListBucket"]`, bucket, ""},
+				demoStatement{"Encryption", "Allow", `["kms:Decrypt","kms:GenerateDataKey"]`, `"*"`, `{"StringLike":{"kms:ViaService":"s3.*.amazonaws.com"}}`}))
+		g.f.addPolicy(g.f.customerPolicyArn(team+"-dynamodb"),
+			demoDocument(demoStatement{"Tables", "Allow", `["dynamodb:GetItem","dynamodb:Query","dynamodb:PutItem","dynamodb:UpdateItem"]`, table, ""}),
+			demoDocument(demoStatement{"Tables", "Allow", `["dynamodb:GetItem","dynamodb:Query","dynamodb:PutItem","dynamodb:UpdateItem","dynamodb:BatchWriteItem"]`, table, ""}))
+		g.f.addPolicy(g.f.customerPolicyArn(team+"-sqs"),
+			demoDocument(demoStatement{"Queues", "Allow", `["sqs:SendMessage","sqs:ReceiveMessage","sqs:DeleteMessage","sqs:GetQueueAttributes"]`, queue, ""}))
+		g.f.addPolicy(g.f.customerPolicyArn(team+"-boundary"),
+			demoDocument(demoStatement{"TeamResources", "Allow", `"*"`, `"*"`, fmt.Sprintf(`{"StringEquals":{"aws:ResourceTag/team":%q}}`, team)},
+				demoStatement{"NoIAM", "Deny", `["iam:*","organizations:*"]`, `"*"`, ""}))
+	}
+
+	// Risky customer policies, found in most real accounts
+	g.f.addPolicy(g.f.customerPolicyArn("legacy-full-access"),
+		demoDocument(demoStatement{"", "Allow", `"*"`, `"*"`, ""}))
+	g.f.addPolicy(g.f.customerPolicyArn("deploy-pass-role"),
+		demoDocument(demoStatement{"PassAnyRole", "Allow", `["iam:PassRole","iam:CreateRole","iam:AttachRolePolicy"]`, `"*"`, ""}),
+		demoDocument(demoStatement{"PassAnyRole", "Allow", `["iam:PassRole","iam:CreateRole","iam:AttachRolePolicy","iam:PutRolePolicy"]`, `"*"`, ""}))
+}
+
+// tags returns the tags of a team's resource
+func (g *demoGenerator) tags(team, env string) map[string]string {
+	tags := map[string]string{
+		"team":        team,
+		"owner":       fmt.Sprintf("%s.%s@acme.example", pick(g.rng, demoFirstNames), pick(g.rng, demoLastNames)),
+		"cost-center": fmt.Sprintf("CC-%04d", 1000+g.rng.IntN(40)*10),
+		"managed-by":  pick(g.rng, []string{"terraform", "terraform", "cdk", "cloudformation"}),
+	}
+	if env != "" {
+		tags["env"] = env
+	}
+	return tags
+}
+
+// uniqueRoleName appends a number to names already taken
+func (g *demoGenerator) uniqueRoleName(name string) string {
+	unique := name
+	for i := 2; g.f.roles[unique] != nil; i++ {
+		unique = fmt.Sprintf("%s-%d", name, i)
+	}
+	return unique
+}
+
+// addRole adds a role of a random kind
+func (g *demoGenerator) addRole() {
+	team, env := pick(g.rng, demoTeams), pick(g.rng, demoEnvironments)
+	switch roll := g.rng.IntN(100); {
+	case roll < 65:
+		g.addServiceRole(team, env)
+	case roll < 75:
+		g.addCIRole(team)
+	case roll < 87:
+		g.addCrossAccountRole(team, env)
+	case roll < 92:
+		g.addSSORole()
+	default:
+		g.addHumanRole(team)
+	}
+}
+
+// addServiceRole adds the role of a team's workload, trusted by an AWS service
+func (g *demoGenerator) addServiceRole(team, env string) {
+	principal := pick(g.rng, demoServicePrincipals)
+	service := pick(g.rng, demoServices)
+	kind := strings.Split(principal.service, ".")[0]
+	role := fakeRole{
+		name:        g.uniqueRoleName(fmt.Sprintf("%s-%s-%s-%s", team, service, kind, env)),
+		path:        pick(g.rng, []string{"/", "/service-role/", "/" + team + "/"}),
+		trustPolicy: demoTrust(fmt.Sprintf(`{"Service":%q}`, principal.service), "sts:AssumeRole", ""),
+		tags:        g.tags(team, env),
+		attached:    []string{principal.policy},
+	}
+	if g.chance(0.4) {
+		role.description = fmt.Sprintf("%s %s running on %s in %s", team, service, kind, env)
+	}
+	for _, suffix := range []string{"-s3-access", "-dynamodb", "-sqs"} {
+		if g.chance(0.45) {
+			role.attached = append(role.attached, g.f.customerPolicyArn(team+suffix))
+		}
+	}
+	if g.chance(0.3) {
+		role.attached = append(role.attached, "arn:aws:iam::aws:policy/CloudWatchAgentServerPolicy")
+	}
+	if g.chance(0.35) {
+		role.inline = map[string]string{
+			"secrets": demoDocument(demoStatement{"ReadSecrets", "Allow", `"secretsmanager:GetSecretValue"`,
+				fmt.Sprintf(`"arn:aws:secretsmanager:*:%s:secret:%s/%s/*"`, g.f.accountID, team, env), ""}),
+		}
+	}
+	if g.chance(0.2) {
+		role.boundary = g.f.customerPolicyArn(team + "-boundary")
+	}
+
+	// Risky patterns: broad managed policies and wildcard inline policies
+	switch {
+	case g.chance(0.02):
+		role.attached = append(role.attached, "arn:aws:iam::aws:policy/AdministratorAccess")
+	case g.chance(0.02):
+		role.attached = append(role.attached, g.f.customerPolicyArn("legacy-full-access"))
+	case g.chance(0.03):
+		if role.inline == nil {
+			role.inline = make(map[string]string)
+		}
+		role.inline["quick-fix"] = demoDocument(demoStatement{"", "Allow", `["s3:*","dynamodb:*"]`, `"*"`, ""})
+	}
+	g.f.addRole(role)
+}
+
+// addCIRole adds a role assumed by GitHub Actions deployments
+func (g *demoGenerator) addCIRole(team string) {
+	repo := fmt.Sprintf("%s-%s", team, pick(g.rng, demoServices))
+	condition := fmt.Sprintf(`{"StringEquals":{"token.actions.githubusercontent.com:aud":"sts.amazonaws.com"},"StringLike":{"token.actions.githubusercontent.com:sub":"repo:acme/%s:ref:refs/heads/main"}}`, repo)
+	if g.chance(0.1) {
+		// Risky: any repository of any owner can assume the role
+		condition = `{"StringEquals":{"token.actions.githubusercontent.com:aud":"sts.amazonaws.com"}}`
+	}
+	role := fakeRole{
+		name:               g.uniqueRoleName("github-actions-" + repo),
+		path:               "/ci/",
+		description:        "Deployments of acme/" + repo,
+		trustPolicy:        demoTrust(fmt.Sprintf(`{"Federated":"arn:aws:iam::%s:oidc-provider/token.actions.githubusercontent.com"}`, g.f.accountID), "sts:AssumeRoleWithWebIdentity", condition),
+		tags:               g.tags(team, ""),
+		attached:           []string{g.f.customerPolicyArn("deploy-pass-role"), g.f.customerPolicyArn(team + "-s3-access")},
+		maxSessionDuration: 7200,
+	}
+	g.f.addRole(role)
+}
+
+// addCrossAccountRole adds a role trusted by another account
+func (g *demoGenerator) addCrossAccountRole(team, env string) {
+	partner := fmt.Sprintf("%012d", 100000000000+g.rng.Int64N(899999999999))
+	condition := fmt.Sprintf(`{"StringEquals":{"sts:ExternalId":"%s-%08x"}}`, team, g.rng.Uint32())
+	if g.chance(0.25) {
+		// Risky: third parties without an external ID
+		condition = ""
+	}
+	principal := fmt.Sprintf(`{"AWS":"arn:aws:iam::%s:root"}`, partner)
+	if g.chance(0.02) {
+		// Risky: anyone can assume the role
+		principal = `{"AWS":"*"}`
+	}
+	role := fakeRole{
+		name:        g.uniqueRoleName(fmt.Sprintf("%s-%s-%s-access", team, pick(g.rng, []string{"vendor", "partner", "audit", "backup", "monitoring"}), env)),
+		trustPolicy: demoTrust(principal, "sts:AssumeRole", condition),
+		tags:        g.tags(team, env),
+		attached:    []string{pick(g.rng, []string{"arn:aws:iam::aws:policy/ReadOnlyAccess", "arn:aws:iam::aws:policy/SecurityAudit", "arn:aws:iam::aws:policy/AmazonS3ReadOnlyAccess"})},
+	}
+	g.f.addRole(role)
+}
+
+// addSSORole adds a role of an IAM Identity Center permission set
+func (g *demoGenerator) addSSORole() {
+	permSet := pick(g.rng, demoPermSets)
+	role := fakeRole{
+		name:               g.uniqueRoleName(fmt.Sprintf("AWSReservedSSO_%s_%016x", permSet, g.rng.Uint64())),
+		path:               "/aws-reserved/sso.amazonaws.com/eu-west-1/",
+		trustPolicy:        demoTrust(fmt.Sprintf(`{"Federated":"arn:aws:iam::%s:saml-provider/AWSSSO_2c8e4f1a9b3d5e70_DO_NOT_DELETE"}`, g.f.accountID), "sts:AssumeRoleWithSAML", `{"StringEquals":{"SAML:aud":"https://signin.aws.amazon.com/saml"}}`),
+		maxSessionDuration: 43200,
+	}
+	switch permSet {
+	case "Billing", "DataScientist":
+		role.attached = []string{"arn:aws:iam::aws:policy/ReadOnlyAccess"}
+		role.inline = map[string]string{"AwsSSOInlinePolicy": demoDocument(demoStatement{"", "Allow", `["ce:*","budgets:View*","athena:*","glue:Get*"]`, `"*"`, ""})}
+	default:
+		role.attached = []string{"arn:aws:iam::aws:policy/" + permSet}
+	}
+	g.f.addRole(role)
+}
+
+// addHumanRole adds a role engineers switch to from the console
+func (g *demoGenerator) addHumanRole(team string) {
+	level := pick(g.rng, []string{"readonly", "developer", "oncall", "admin"})
+	attached := map[string][]string{
+		"readonly":  {"arn:aws:iam::aws:policy/ReadOnlyAccess"},
+		"developer": {"arn:aws:iam::aws:policy/ReadOnlyAccess", g.f.customerPolicyArn(team + "-s3-access"), g.f.customerPolicyArn(team + "-dynamodb")},
+		"oncall":    {"arn:aws:iam::aws:policy/PowerUserAccess"},
+		"admin":     {"arn:aws:iam::aws:policy/AdministratorAccess"},
+	}[level]
+	condition := `{"Bool":{"aws:MultiFactorAuthPresent":"true"}}`
+	if level == "admin" && g.chance(0.3) {
+		// Risky: administrator access without MFA
+		condition = ""
+	}
+	role := fakeRole{
+		name:        g.uniqueRoleName(fmt.Sprintf("%s-%s", team, level)),
+		path:        "/people/",
+		description: fmt.Sprintf("%s access for the %s team", level, team),
+		trustPolicy: demoTrust(fmt.Sprintf(`{"AWS":"arn:aws:iam::%s:root"}`, g.f.accountID), "sts:AssumeRole", condition),
+		tags:        g.tags(team, ""),
+		attached:    attached,
+	}
+	if level == "developer" {
+		role.boundary = g.f.customerPolicyArn(team + "-boundary")
+	}
+	g.f.addRole(role)
+}
+
+// addUsersAndGroups adds team groups and users in them, a few with their own policies
+func (g *demoGenerator) addUsersAndGroups() {
+	g.f.addGroup(fakeGroup{name: "admins", attached: []string{"arn:aws:iam::aws:policy/AdministratorAccess"}})
+	g.f.addGroup(fakeGroup{name: "readonly", attached: []string{"arn:aws:iam::aws:policy/ReadOnlyAccess"}})
+	g.f.addGroup(fakeGroup{name: "security-audit", attached: []string{"arn:aws:iam::aws:policy/SecurityAudit"}})
+	for _, team := range demoTeams {
+		g.f.addGroup(fakeGroup{
+			name:     team + "-engineers",
+			attached: []string{g.f.customerPolicyArn(team + "-s3-access"), g.f.customerPolicyArn(team + "-sqs")},
+			inline: map[string]string{"assume-team-roles": demoDocument(demoStatement{"", "Allow", `"sts:AssumeRole"`,
+				fmt.Sprintf(`"arn:aws:iam::%s:role/people/%s-*"`, g.f.accountID, team), ""})},
+		})
+	}
+
+	for len(g.f.users) < 150 {
+		name := fmt.Sprintf("%s.%s", pick(g.rng, demoFirstNames), pick(g.rng, demoLastNames))
+		if g.f.users[name] != nil {
+			continue
+		}
+		user := fakeUser{name: name, groups: []string{pick(g.rng, demoTeams) + "-engineers"}}
+		switch {
+		case g.chance(0.05):
+			user.groups = append(user.groups, "admins")
+		case g.chance(0.3):
+			user.groups = append(user.groups, "readonly")
+		}
+		if g.chance(0.03) {
+			// Risky: administrator access attached to the user directly
+			user.attached = []string{"arn:aws:iam::aws:policy/AdministratorAccess"}
+		}
+		g.f.addUser(user)
+	}
+
+	// Service users with long-term keys
+	for _, service := range []string{"legacy-backup", "datadog-integration", "jenkins"} {
+		g.f.addUser(fakeUser{name: "svc-" + service, inline: map[string]string{
+			"access": demoDocument(demoStatement{"", "Allow", `["s3:*","ec2:Describe*"]`, `"*"`, ""}),
+		}})
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Test the demo account is reproducible and has the requested size
+func TestDemoBackendReproducible(t *testing.T) {
+	a, b := newDemoBackend(500, 7), newDemoBackend(500, 7)
+	if len(a.roles) != 500 {
+		t.Fatalf("Expected 500 roles, got %d", len(a.roles))
+	}
+	for name, role := range a.roles {
+		if other, ok := b.roles[name]; !ok || other.trustPolicy != role.trustPolicy || !slices.Equal(other.attached, role.attached) {
+			t.Fatalf("Expected the same role %s from the same seed", name)
+		}
+	}
+	if c := newDemoBackend(500, 8); slices.Equal(slices.Sorted(maps.Keys(a.roles)), slices.Sorted(maps.Keys(c.roles))) {
+		t.Errorf("Expected another account from another seed")
+	}
+}
+
+// Test the demo account loads like a real one, with valid documents and the patterns it is meant to show
+func TestDemoBackendAccount(t *testing.T) {
+	data, err := loadAccountDetails(context.Background(), newDemoBackend(demoRoleCount, 1))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(data.roles) != demoRoleCount || len(data.users) < 150 || len(data.groups) < 10 {
+		t.Fatalf("Expected %d roles, users and groups, got %d roles, %d users and %d groups", demoRoleCount, len(data.roles), len(data.users), len(data.groups))
+	}
+
+	paths := make(map[string]bool)
+	policyTypes := make(map[string]int)
+	var tagged, bounded, admin, anyone, noExternalID int
+	for _, role := range data.roles {
+		if !json.Valid([]byte(role.trustPolicy)) {
+			t.Fatalf("Expected a valid trust policy for %s, got %s", role.roleName, role.trustPolicy)
+		}
+		paths[role.path] = true
+		if role.tags["team"] != "" {
+			tagged++
+		}
+		if role.permissionsBoundary != "" {
+			bounded++
+		}
+		if strings.Contains(role.trustPolicy, `{"AWS":"*"}`) {
+			anyone++
+		}
+		// Cross-account roles are named <team>-<purpose>-<env>-access
+		if strings.Contains(role.roleName, "-access") && role.path == "/" && !strings.Contains(role.trustPolicy, "sts:ExternalId") {
+			noExternalID++
+		}
+		for _, policy := range role.policies {
+			policyTypes[policy.policyType]++
+			if policy.policyArn == "arn:aws:iam::aws:policy/AdministratorAccess" {
+				admin++
+			}
+			if policy.rawDocument != "" && !json.Valid([]byte(policy.rawDocument)) {
+				t.Fatalf("Expected a valid document for %s of %s", policy.policyName, role.roleName)
+			}
+		}
+	}
+	if len(paths) < 5 || tagged == 0 || bounded == 0 {
+		t.Errorf("Expected varied paths, tags and boundaries, got %d paths, %d tagged and %d bounded roles", len(paths), tagged, bounded)
+	}
+	if policyTypes["AWS"] == 0 || policyTypes["Customer"] == 0 || policyTypes["Inline"] == 0 {
+		t.Errorf("Expected AWS, customer managed and inline policies, got %v", policyTypes)
+	}
+	if admin == 0 || anyone == 0 || noExternalID == 0 {
+		t.Errorf("Expected risky roles, got %d admins, %d open trusts and %d trusts without external ID", admin, anyone, noExternalID)
+	}
+
+	versioned := 0
+	for _, policy := range newDemoBackend(10, 1).policies {
+		if len(policy.versions) > 1 {
+			versioned++
+		}
+	}
+	if versioned == 0 {
+		t.Errorf("Expected policies with several versions")
+	}
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// diffChange is a single difference between two IAM states
+type diffChange struct {
+	subject string // Role or policy the change belongs to
+	kind    string // What changed, e.g. "Policy attached"
+	summary string // Short detail shown in the list
+	detail  string // Full detail shown in the viewport
+}
+
+func (c diffChange) Title() string { return c.subject }
+func (c diffChange) Description() string {
+	if c.summary == "" {
+		return c.kind
+	}
+	return fmt.Sprintf("%s: %s", c.kind, c.summary)
+}
+func (c diffChange) FilterValue() string { return c.subject + " " + c.kind + " " + c.summary }
+
+// computeDiff lists the changes needed to go from the old account state to the new one
+func computeDiff(oldData, newData *accountData) []diffChange {
+	var changes []diffChange
+
+	oldRoles := make(map[string]RoleItem)
+	for _, role := range oldData.roles {
+		oldRoles[role.roleName] = role
+	}
+	newRoles := make(map[string]RoleItem)
+	for _, role := range newData.roles {
+		newRoles[role.roleName] = role
+	}
+
+	for _, name := range sortedKeys(oldRoles, newRoles) {
+		oldRole, inOld := oldRoles[name]
+		newRole, inNew := newRoles[name]
+		switch {
+		case !inOld:
+			changes = append(changes, diffChange{subject: name, kind: "Role added", summary: newRole.roleArn, detail: roleSummary(newRole)})
+		case !inNew:
+			changes = append(changes, diffChange{subject: name, kind: "Role removed", summary: oldRole.roleArn, detail: roleSummary(oldRole)})
+		default:
+			changes = append(changes, roleChanges(oldRole, newRole)...)
+		}
+	}
+
+	// Managed policy documents changed independently of attachments
+	for _, policyArn := range sortedKeys(oldData.policies, newData.policies) {
+		oldPolicy, inOld := oldData.policies[policyArn]
+		newPolicy, inNew := newData.policies[policyArn]
+		switch {
+		case !inOld && newPolicy.policyType == "Customer":
+			changes = append(changes, diffChange{subject: newPolicy.policyName, kind: "Policy created", summary: policyArn, detail: prettyJSON(newPolicy.rawDocument)})
+		case !inNew && oldPolicy.policyType == "Customer":
+			changes = append(changes, diffChange{subject: oldPolicy.policyName, kind: "Policy deleted", summary: policyArn, detail: prettyJSON(oldPolicy.rawDocument)})
+		case inOld && inNew && oldPolicy.rawDocument != "" && newPolicy.rawDocument != "":
+			if oldPolicy.defaultVersionId == newPolicy.defaultVersionId && prettyJSON(oldPolicy.rawDocument) == prettyJSON(newPolicy.rawDocument) {
+				continue
+			}
+			changes = append(changes, diffChange{
+				subject: newPolicy.policyName,
+				kind:    "Policy version changed",
+				summary: fmt.Sprintf("%s → %s", oldPolicy.defaultVersionId, newPolicy.defaultVersionId),
+				detail:  documentDiff(oldPolicy.rawDocument, newPolicy.rawDocument),
+			})
+		}
+	}
+
+	return changes
+}
+
+// roleChanges compares two versions of the same role
+func roleChanges(oldRole, newRole RoleItem) []diffChange {
+	var changes []diffChange
+	name := newRole.roleName
+
+	if prettyJSON(oldRole.trustPolicy) != prettyJSON(newRole.trustPolicy) {
+		changes = append(changes, diffChange{subject: name, kind: "Trust policy changed", detail: documentDiff(oldRole.trustPolicy, newRole.trustPolicy)})
+	}
+
+	if oldRole.permissionsBoundary != newRole.permissionsBoundary {
+		summary := fmt.Sprintf("%s → %s", valueOrNone(oldRole.permissionsBoundary), valueOrNone(newRole.permissionsBoundary))
+		changes = append(changes, diffChange{subject: name, kind: "Permissions boundary changed", summary: summary, detail: summary})
+	}
+
+	// Only compare policies when both sides know them
+	if oldRole.policiesLoaded && newRole.policiesLoaded {
+		oldManaged, oldInline := splitPolicies(oldRole.policies)
+		newManaged, newInline := splitPolicies(newRole.policies)
+
+		for _, policyArn := range sortedKeys(oldManaged, newManaged) {
+			oldPolicy, inOld := oldManaged[policyArn]
+			newPolicy, inNew := newManaged[policyArn]
+			if !inOld {
+				changes = append(changes, diffChange{subject: name, kind: "Policy attached", summary: newPolicy.policyName, detail: policyArn})
+			} else if !inNew {
+				changes = append(changes, diffChange{subject: name, kind: "Policy detached", summary: oldPolicy.policyName, detail: policyArn})
+			}
+		}
+
+		for _, policyName := range sortedKeys(oldInline, newInline) {
+			oldPolicy, inOld := oldInline[policyName]
+			newPolicy, inNew := newInline[policyName]
+			switch {
+			case !inOld:
+				changes = append(changes, diffChange{subject: name, kind: "Inline policy added", summary: policyName, detail: prettyJSON(newPolicy.rawDocument)})
+			case !inNew:
+				changes = append(changes, diffChange{subject: name, kind: "Inline policy removed", summary: policyName, detail: prettyJSON(oldPolicy.rawDocument)})
+			case prettyJSON(oldPolicy.rawDocument) != prettyJSON(newPolicy.rawDocument):
+				changes = append(changes, diffChange{subject: name, kind: "Inline policy changed", summary: policyName, detail: documentDiff(oldPolicy.rawDocument, newPolicy.rawDocument)})
+			}
+		}
+	}
+
+	if tagLines := tagDiff(oldRole.tags, newRole.tags); len(tagLines) > 0 {
+		changes = append(changes, diffChange{subject: name, kind: "Tags changed", summary: fmt.Sprintf("%d tags", len(tagLines)), detail: strings.Join(tagLines, "\n")})
+	}
+
+	return changes
+}
+
+// splitPolicies indexes managed policies by ARN and inline policies by name
+func splitPolicies(policies []PolicyItem) (map[string]PolicyItem, map[string]PolicyItem) {
+	managed := make(map[string]PolicyItem)
+	inline := make(map[string]PolicyItem)
+	for _, policy := range policies {
+		if policy.policyType == "Inline" {
+			inline[policy.policyName] = policy
+		} else {
+			managed[policy.policyArn] = policy
+		}
+	}
+	return managed, inline
+}
+
+// tagDiff describes added, removed and changed tags
+func tagDiff(oldTags, newTags map[string]string) []string {
+	var lines []string
+	for _, tagKey := range sortedKeys(oldTags, newTags) {
+		oldValue, inOld := oldTags[tagKey]
+		newValue, inNew := newTags[tagKey]
+		switch {
+		case !inOld:
+			lines = append(lines, diffAddedStyle.Render(fmt.Sprintf("+ %s = %s", tagKey, newValue)))
+		case !inNew:
+			lines = append(lines, diffRemovedStyle.Render(fmt.Sprintf("- %s = %s", tagKey, oldValue)))
+		case oldValue != newValue:
+			lines = append(lines, fmt.Sprintf("~ %s: %s → %s", tagKey, oldValue, newValue))
+		}
+	}
+	return lines
+}
+
+// roleSummary describes a role that was added or removed
+func roleSummary(role RoleItem) string {
+	lines := []string{fmt.Sprintf("ARN: %s", role.roleArn)}
+	for _, policy := range role.policies {
+		lines = append(lines, fmt.Sprintf("Policy: %s [%s]", policy.policyName, policy.policyType))
+	}
+	if role.trustPolicy != "" {
+		lines = append(lines, "", "Trust policy:", prettyJSON(role.trustPolicy))
+	}
+	return strings.Join(lines, "\n")
+}
+
+var (
+	diffAddedStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("42"))
+	diffRemovedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+)
+
+// documentDiff renders a line diff of two JSON documents
+func documentDiff(oldDoc, newDoc string) string {
+	var lines []string
+	for _, line := range lineDiff(prettyJSON(oldDoc), prettyJSON(newDoc)) {
+		switch {
+		case strings.HasPrefix(line, "+"):
+			lines = append(lines, diffAddedStyle.Render(line))
+		case strings.HasPrefix(line, "-"):
+			lines = append(lines, diffRemovedStyle.Render(line))
+		default:
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// lineDiff returns the lines of both texts prefixed with "+", "-" or " " based on their longest common subsequence
+func lineDiff(oldText, newText string) []string {
+	oldLines := strings.Split(oldText, "\n")
+	newLines := strings.Split(newText, "\n")
+
+	// lcs[i][j] is the LCS length of oldLines[i:] and newLines[j:]
+	lcs := make([][]int, len(oldLines)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(newLines)+1)
+	}
+	for i := len(oldLines) - 1; i >= 0; i-- {
+		for j := len(newLines) - 1; j >= 0; j-- {
+			if oldLines[i] == newLines[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var result []string
+	i, j := 0, 0
+	for i < len(oldLines) && j < len(newLines) {
+		switch {
+		case oldLines[i] == newLines[j]:
+			result = append(result, "  "+oldLines[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			result = append(result, "- "+oldLines[i])
+			i++
+		default:
+			result = append(result, "+ "+newLines[j])
+			j++
+		}
+	}
+	for ; i < len(oldLines); i++ {
+		result = append(result, "- "+oldLines[i])
+	}
+	for ; j < len(newLines); j++ {
+		result = append(result, "+ "+newLines[j])
+	}
+	return result
+}
+
+// prettyJSON indents a JSON document with sorted keys, returning it unchanged if it is not valid JSON
+func prettyJSON(doc string) string {
+	var jsonObj interface{}
+	if err := json.Unmarshal([]byte(doc), &jsonObj); err != nil {
+		return doc
+	}
+	pretty, err := json.MarshalIndent(jsonObj, "", "  ")
+	if err != nil {
+		return doc
+	}
+	return string(pretty)
+}
+
+// valueOrNone returns the value or a placeholder when it is empty
+func valueOrNone(value string) string {
+	if value == "" {
+		return "(none)"
+	}
+	return value
+}
+
+// sortedKeys returns the union of the keys of both maps in sorted order
+func sortedKeys[V any](a, b map[string]V) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, m := range []map[string]V{a, b} {
+		for name := range m {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// openDiff compares a snapshot with a second snapshot, or with the live account when newPath is empty
+func (m *model) openDiff(oldPath, newPath string) error {
+	oldSnap, err := readSnapshot(oldPath)
+	if err != nil {
+		return err
+	}
+	m.diffBase = oldSnap.Account.data()
+	m.currentScreen = "diff"
+
+	if newPath == "" {
+		m.diffPending = true
+		m.diffList.Title = fmt.Sprintf("Changes since %s", filepath.Base(oldPath))
+		m.statusMsg = "Loading the live account to compare..."
+		return nil
+	}
+
+	newSnap, err := readSnapshot(newPath)
+	if err != nil {
+		return err
+	}
+	m.openSnapshot(newPath, newSnap)
+	m.sourceLabel = fmt.Sprintf("Diff: %s → %s", filepath.Base(oldPath), filepath.Base(newPath))
+	m.diffList.Title = fmt.Sprintf("Changes from %s to %s", oldSnap.CreatedAt.Local().Format("2006-01-02 15:04"), newSnap.CreatedAt.Local().Format("2006-01-02 15:04"))
+	m.showDiff(computeDiff(m.diffBase, m.account))
+	return nil
+}
+
+// showDiff fills the changes list
+func (m *model) showDiff(changes []diffChange) {
+	items := []list.Item{}
+	for i := range changes {
+		items = append(items, &changes[i])
+	}
+	m.diffList.SetItems(items)
+
+	if len(changes) == 0 {
+		m.statusMsg = "No changes found"
+	} else {
+		m.statusMsg = fmt.Sprintf("%d changes found", len(changes))
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Test the line diff of two texts
+func TestLineDiff(t *testing.T) {
+	result := lineDiff("a\nb\nc", "a\nc\nd")
+	expected := []string{"  a", "- b", "  c", "+ d"}
+
+	if strings.Join(result, "|") != strings.Join(expected, "|") {
+		t.Errorf("Expected %v, got %v", expected, result)
+	}
+}
+
+// Test computing changes between two account states
+func TestComputeDiff(t *testing.T) {
+	policyArn := "arn:aws:iam::123456789012:policy/AppPolicy"
+	oldData := &accountData{
+		roles: []RoleItem{
+			{
+				roleName:       "AppRole",
+				trustPolicy:    `{"Principal":"ec2.amazonaws.com"}`,
+				tags:           map[string]string{"env": "dev", "team": "a"},
+				policiesLoaded: true,
+				policies: []PolicyItem{
+					{policyName: "AppPolicy", policyArn: policyArn, policyType: "Customer"},
+					{policyName: "Inline", policyType: "Inline", rawDocument: `{"Action":"s3:GetObject"}`},
+				},
+			},
+			{roleName: "OldRole"},
+		},
+		policies: map[string]PolicyItem{
+			policyArn: {policyName: "AppPolicy", policyArn: policyArn, policyType: "Customer", defaultVersionId: "v1", rawDocument: `{"Action":"s3:GetObject"}`},
+		},
+	}
+	newData := &accountData{
+		roles: []RoleItem{
+			{
+				roleName:       "AppRole",
+				trustPolicy:    `{"Principal":"lambda.amazonaws.com"}`,
+				tags:           map[string]string{"env": "prod", "owner": "b"},
+				policiesLoaded: true,
+				policies: []PolicyItem{
+					{policyName: "ReadOnlyAccess", policyArn: "arn:aws:iam::aws:policy/ReadOnlyAccess", policyType: "AWS"},
+					{policyName: "Inline", policyType: "Inline", rawDocument: `{"Action":"s3:*"}`},
+				},
+			},
+			{roleName: "NewRole"},
+		},
+		policies: map[string]PolicyItem{
+			policyArn: {policyName: "AppPolicy", policyArn: policyArn, policyType: "Customer", defaultVersionId: "v2", rawDocument: `{"Action":"s3:PutObject"}`},
+		},
+	}
+
+	changes := computeDiff(oldData, newData)
+
+	kinds := make(map[string]diffChange)
+	for _, change := range changes {
+		kinds[change.subject+"/"+change.kind] = change
+	}
+	for _, expected := range []string{
+		"AppRole/Trust policy changed",
+		"AppRole/Policy attached",
+		"AppRole/Policy detached",
+		"AppRole/Inline policy changed",
+		"AppRole/Tags changed",
+		"NewRole/Role added",
+		"OldRole/Role removed",
+		"AppPolicy/Policy version changed",
+	} {
+		if _, ok := kinds[expected]; !ok {
+			t.Errorf("Expected change %s, got %v", expected, changes)
+		}
+	}
+	if len(changes) != 8 {
+		t.Errorf("Expected 8 changes, got %d", len(changes))
+	}
+
+	version := kinds["AppPolicy/Policy version changed"]
+	if version.summary != "v1 → v2" {
+		t.Errorf("Expected version summary, got '%s'", version.summary)
+	}
+	detail := stripAnsiCodes(version.detail)
+	if !strings.Contains(detail, `-   "Action": "s3:GetObject"`) || !strings.Contains(detail, `+   "Action": "s3:PutObject"`) {
+		t.Errorf("Expected line diff of the policy document, got:\n%s", detail)
+	}
+
+	tags := stripAnsiCodes(kinds["AppRole/Tags changed"].detail)
+	if !strings.Contains(tags, "~ env: dev → prod") || !strings.Contains(tags, "+ owner = b") || !strings.Contains(tags, "- team = a") {
+		t.Errorf("Expected tag changes, got:\n%s", tags)
+	}
+}
+
+// Test that identical states have no changes
+func TestComputeDiffNoChanges(t *testing.T) {
+	data := &accountData{roles: []RoleItem{{roleName: "AppRole", trustPolicy: `{"a":1}`}}}
+	same := &accountData{roles: []RoleItem{{roleName: "AppRole", trustPolicy: `{ "a": 1 }`}}}
+
+	if changes := computeDiff(data, same); len(changes) != 0 {
+		t.Errorf("Expected no changes, got %v", changes)
+	}
+}
+
+// Test opening a diff of two snapshot files and navigating to a change
+func TestOpenDiff(t *testing.T) {
+	dir := t.TempDir()
+	oldPath := filepath.Join(dir, "old.json")
+	newPath := filepath.Join(dir, "new.json")
+	if err := writeSnapshot(oldPath, snapshot{Version: snapshotVersion, CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("Failed to write snapshot: %v", err)
+	}
+	if err := writeSnapshot(newPath, snapshot{Version: snapshotVersion, CreatedAt: time.Now(), Account: accountRecord{Roles: []roleRecord{{RoleName: "NewRole"}}}}); err != nil {
+		t.Fatalf("Failed to write snapshot: %v", err)
+	}
+
+	m := createTestModel()
+	if err := m.openDiff(oldPath, newPath); err != nil {
+		t.Fatalf("Expected diff to open, got %v", err)
+	}
+	if m.currentScreen != "diff" || !m.offline || len(m.diffList.Items()) != 1 {
+		t.Fatalf("Expected one change on the diff screen, got %d", len(m.diffList.Items()))
+	}
+
+	newModel, _ := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	updatedModel := newModel.(model)
+	if updatedModel.currentScreen != "diff_detail" || updatedModel.diffSelected.kind != "Role added" {
+		t.Errorf("Expected change details to open")
+	}
+
+	newModel, _ = updatedModel.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if newModel.(model).currentScreen != "diff" {
+		t.Errorf("Expected esc to return to the changes list")
+	}
+}
+
+// Test comparing a snapshot with the live account
+func TestLiveDiff(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "old.json")
+	if err := writeSnapshot(path, snapshot{Version: snapshotVersion, CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("Failed to write snapshot: %v", err)
+	}
+
+	m := createTestModel()
+	if err := m.openDiff(path, ""); err != nil {
+		t.Fatalf("Expected diff to open, got %v", err)
+	}
+	if !m.diffPending || m.offline {
+		t.Errorf("Expected to wait for the live account")
+	}
+
+	newModel, _ := m.Update(accountLoadedMsg{data: &accountData{roles: []RoleItem{{roleName: "LiveRole"}}}})
+	updatedModel := newModel.(model)
+	if updatedModel.diffPending || len(updatedModel.diffList.Items()) != 1 {
+		t.Errorf("Expected live diff with one change, got %d", len(updatedModel.diffList.Items()))
+	}
+}
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+	tea "github.com/charmbracelet/bubbletea"
+	appconfig "github.com/vlkyrylenko/atui/config"
+)
+
+// driftEnvironment names the environment of a profile in drift role names, set from the atui config
+var driftEnvironment = appconfig.DefaultConfig.DriftEnvironment
+
+// driftCheck is a role compared across several profiles
+type driftCheck struct {
+	pattern  string // Role name, {env} is replaced by the environment of each profile
+	profiles []string
+	roles    map[string]*RoleItem // Loaded roles by profile, nil when the role does not exist
+	errs     map[string]error     // Profiles that failed to load
+	pending  int
+}
+
+// driftRoleLoadedMsg is sent when the role of one profile in a drift check is loaded
+type driftRoleLoadedMsg struct {
+	check   *driftCheck
+	profile string
+	role    *RoleItem // Nil when the role does not exist in the account
+	err     error
+}
+
+// driftRoleName fills the environment placeholder of a role name pattern with the profile's environment, the whole
+// profile name unless the config maps it to another name, e.g. "acme-prod" to "prod"
+func driftRoleName(pattern, profile string) string {
+	return strings.ReplaceAll(pattern, "{env}", driftEnvironment(profile))
+}
+
+// Load a role with its trust policy, boundary and policy documents from a profile
+func loadDriftRoleCmd(ctx context.Context, check *driftCheck, session awsSession) tea.Cmd {
+	profile := session.profile
+	roleName := driftRoleName(check.pattern, profile)
+	return func() tea.Msg {
+		cfg, err := session.loadConfig(ctx)
+		if err != nil {
+			return driftRoleLoadedMsg{check: check, profile: profile, err: fmt.Errorf("error loading AWS configuration: %w", err)}
+		}
+		iamClient := session.iamClient(cfg)
+
+		output, err := iamClient.GetRole(ctx, &iam.GetRoleInput{RoleName: aws.String(roleName)})
+		if err != nil {
+			var noSuchEntity *types.NoSuchEntityException
+			if errors.As(err, &noSuchEntity) {
+				return driftRoleLoadedMsg{check: check, profile: profile}
+			}
+			return driftRoleLoadedMsg{check: check, profile: profile, err: fmt.Errorf("error getting role %s: %w", roleName, err)}
+		}
+
+		trustPolicy, err := decodeURLEncodedDocument(aws.ToString(output.Role.AssumeRolePolicyDocument))
+		if err != nil {
+			return driftRoleLoadedMsg{check: check, profile: profile, err: fmt.Errorf("error decoding trust policy: %w", err)}
+		}
+		role := &RoleItem{
+			roleName:           roleName,
+			roleArn:            aws.ToString(output.Role.Arn),
+			path:               aws.ToString(output.Role.Path),
+			trustPolicy:        trustPolicy,
+			tags:               tagMap(output.Role.Tags),
+			maxSessionDuration: aws.ToInt32(output.Role.MaxSessionDuration),
+			policiesLoaded:     true,
+		}
+		if output.Role.PermissionsBoundary != nil {
+			role.permissionsBoundary = aws.ToString(output.Role.PermissionsBoundary.PermissionsBoundaryArn)
+		}
+
+		attached := iam.NewListAttachedRolePoliciesPaginator(iamClient, &iam.ListAttachedRolePoliciesInput{RoleName: aws.String(roleName)})
+		for attached.HasMorePages() {
+			page, err := attached.NextPage(ctx)
+			if err != nil {
+				return driftRoleLoadedMsg{check: check, profile: profile, err: fmt.Errorf("error listing policies for role %s: %w", roleName, err)}
+			}
+			for _, policy := range page.AttachedPolicies {
+				policyArn := aws.ToString(policy.PolicyArn)
+				role.policies = append(role.policies, PolicyItem{
+					policyName: aws.ToString(policy.PolicyName),
+					policyArn:  policyArn,
+					policyType: managedPolicyType(policyArn),
+				})
+			}
+		}
+
+		inline := iam.NewListRolePoliciesPaginator(iamClient, &iam.ListRolePoliciesInput{RoleName: aws.String(roleName)})
+		for inline.HasMorePages() {
+			page, err := inline.NextPage(ctx)
+			if err != nil {
+				return driftRoleLoadedMsg{check: check, profile: profile, err: fmt.Errorf("error listing inline policies for role %s: %w", roleName, err)}
+			}
+			for _, policyName := range page.PolicyNames {
+				policy, err := iamClient.GetRolePolicy(ctx, &iam.GetRolePolicyInput{RoleName: aws.String(roleName), PolicyName: aws.String(policyName)})
+				if err != nil {
+					return driftRoleLoadedMsg{check: check, profile: profile, err: fmt.Errorf("error getting inline policy %s: %w", policyName, err)}
+				}
+				document, err := decodeURLEncodedDocument(aws.ToString(policy.PolicyDocument))
+				if err != nil {
+					return driftRoleLoadedMsg{check: check, profile: profile, err: fmt.Errorf("error decoding inline policy %s: %w", policyName, err)}
+				}
+				role.policies = append(role.policies, PolicyItem{policyName: policyName, policyType: "Inline", rawDocument: document})
+			}
+		}
+		role.policyCount = len(role.policies)
+
+		return driftRoleLoadedMsg{check: check, profile: profile, role: role}
+	}
+}
+
+// openDriftPrompt asks for the role name to compare across the marked profiles
+func (m *model) openDriftPrompt() tea.Cmd {
+	if len(m.markedProfiles()) < 2 {
+		m.statusMsg = "Mark at least two profiles with space to check drift"
+		return nil
+	}
+	name := ""
+	if selected, ok := m.rolesList.SelectedItem().(*RoleItem); ok {
+		name = selected.roleName
+	}
+	m.driftInput.SetValue(name)
+	m.driftInput.CursorEnd()
+	m.currentScreen = "drift_prompt"
+	m.statusMsg = "Role name to compare, {env} is replaced by each profile name"
+	return m.driftInput.Focus()
+}
+
+// startDrift loads the role from every marked profile
+func (m *model) startDrift(pattern string) tea.Cmd {
+	profiles := m.markedProfiles()
+	check := &driftCheck{
+		pattern:  pattern,
+		profiles: profiles,
+		roles:    make(map[string]*RoleItem),
+		errs:     make(map[string]error),
+		pending:  len(profiles),
+	}
+	m.drift = check
+	m.driftView.SetContent("")
+	m.currentScreen = "drift"
+	updateKeyBindingsForScreen(m.currentScreen)
+	m.statusMsg = fmt.Sprintf("Loading %s from %d profiles...", pattern, len(profiles))
+
+	var cmds []tea.Cmd
+	for _, profile := range profiles {
+		cmds = append(cmds, loadDriftRoleCmd(m.screenContext("drift"), check, m.profileSession(profile)))
+	}
+	return tea.Batch(cmds...)
+}
+
+// addDriftRole records the role of one profile and renders the matrix once all are loaded
+func (m *model) addDriftRole(msg driftRoleLoadedMsg) {
+	check := msg.check
+	if check != m.drift {
+		return // A newer check replaced this one
+	}
+	check.pending--
+	if msg.err != nil {
+		check.errs[msg.profile] = msg.err
+	} else {
+		check.roles[msg.profile] = msg.role
+	}
+
+	if check.pending > 0 {
+		m.statusMsg = fmt.Sprintf("Loading %s: %d of %d profiles done", check.pattern, len(check.profiles)-check.pending, len(check.profiles))
+		return
+	}
+	m.driftView.SetContent(driftMatrix(check))
+	m.driftView.GotoTop()
+	m.statusMsg = ""
+}
+
+// driftMatrix renders which profiles diverge for every part of the role
+func driftMatrix(check *driftCheck) string {
+	width := 10
+	for _, profile := range check.profiles {
+		width = max(width, len(profile)+2)
+	}
+
+	// Rows of the matrix, each with one cell per profile
+	type driftRow struct {
+		name  string
+		cells []string
+	}
+	var rows []driftRow
+	var details []string
+
+	presence := driftRow{name: "Role"}
+	for _, profile := range check.profiles {
+		role, err := check.roles[profile], check.errs[profile]
+		switch {
+		case err != nil:
+			presence.cells = append(presence.cells, "error")
+		case role == nil:
+			presence.cells = append(presence.cells, "missing")
+		default:
+			presence.cells = append(presence.cells, "✓")
+		}
+	}
+	rows = append(rows, presence)
+
+	// Documents are labelled A, B, ... so profiles with the same content share a label
+	documentRow := func(name string, document func(role *RoleItem) (string, bool)) {
+		row := driftRow{name: name}
+		labels := make(map[string]string)
+		var variants []string
+		profilesOf := make(map[string][]string)
+		for _, profile := range check.profiles {
+			role := check.roles[profile]
+			if role == nil {
+				row.cells = append(row.cells, "")
+				continue
+			}
+			doc, ok := document(role)
+			if !ok {
+				row.cells = append(row.cells, "—")
+				continue
+			}
+			pretty := prettyJSON(doc)
+			label, seen := labels[pretty]
+			if !seen {
+				label = string(rune('A' + len(variants)))
+				labels[pretty] = label
+				variants = append(variants, pretty)
+			}
+			profilesOf[label] = append(profilesOf[label], profile)
+			row.cells = append(row.cells, label)
+		}
+		rows = append(rows, row)
+
+		// Show how each variant differs from the first one
+		for i := 1; i < len(variants); i++ {
+			label := string(rune('A' + i))
+			details = append(details, fmt.Sprintf("%s: variant %s (%s) against A (%s)", name, label, strings.Join(profilesOf[label], ", "), strings.Join(profilesOf["A"], ", ")))
+			details = append(details, documentDiff(variants[0], variants[i]), "")
+		}
+	}
+
+	documentRow("Trust policy", func(role *RoleItem) (string, bool) { return role.trustPolicy, true })
+
+	boundary := driftRow{name: "Permissions boundary"}
+	for _, profile := range check.profiles {
+		if role := check.roles[profile]; role == nil {
+			boundary.cells = append(boundary.cells, "")
+		} else if role.permissionsBoundary == "" {
+			boundary.cells = append(boundary.cells, "—")
+		} else {
+			// The policy name is comparable across accounts, the ARN is not
+			boundary.cells = append(boundary.cells, role.permissionsBoundary[strings.LastIndex(role.permissionsBoundary, "/")+1:])
+		}
+	}
+	rows = append(rows, boundary)
+
+	// One row per policy attached in any profile, by name since ARNs differ across accounts
+	managedNames := make(map[string]bool)
+	inlineNames := make(map[string]bool)
+	for _, role := range check.roles {
+		if role == nil {
+			continue
+		}
+		for _, policy := range role.policies {
+			if policy.policyType == "Inline" {
+				inlineNames[policy.policyName] = true
+			} else {
+				managedNames[policy.policyName] = true
+			}
+		}
+	}
+	for _, name := range sortedKeys(managedNames, nil) {
+		row := driftRow{name: name}
+		for _, profile := range check.profiles {
+			role := check.roles[profile]
+			switch {
+			case role == nil:
+				row.cells = append(row.cells, "")
+			case rolePolicy(role, name, false) != nil:
+				row.cells = append(row.cells, "✓")
+			default:
+				row.cells = append(row.cells, "—")
+			}
+		}
+		rows = append(rows, row)
+	}
+	for _, name := range sortedKeys(inlineNames, nil) {
+		documentRow("Inline "+name, func(role *RoleItem) (string, bool) {
+			if policy := rolePolicy(role, name, true); policy != nil {
+				return policy.rawDocument, true
+			}
+			return "", false
+		})
+	}
+
+	// Render the matrix, highlighting rows where the profiles diverge
+	lines := []string{compareSectionStyle.Render(fmt.Sprintf("Drift of %s", check.pattern)), ""}
+	header := fmt.Sprintf("  %-32s", "")
+	for _, profile := range check.profiles {
+		header += fmt.Sprintf("%-*s", width, profile)
+	}
+	lines = append(lines, header)
+	for _, row := range rows {
+		line := fmt.Sprintf("  %-32s", truncateText(row.name, 31))
+		for _, cell := range row.cells {
+			line += fmt.Sprintf("%-*s", width, cell)
+		}
+		if driftDiverges(row.cells) {
+			line = diffChangedStyle.Render(line)
+		}
+		lines = append(lines, line)
+	}
+
+	for _, profile := range check.profiles {
+		if err := check.errs[profile]; err != nil {
+			lines = append(lines, "", diffRemovedStyle.Render(fmt.Sprintf("%s: %v", profile, err)))
+		}
+	}
+	if len(details) > 0 {
+		lines = append(lines, "", compareSectionStyle.Render("Document differences"), "")
+		lines = append(lines, details...)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// driftDiverges reports whether the cells of the profiles that have the role differ
+func driftDiverges(cells []string) bool {
+	values := make(map[string]bool)
+	for _, cell := range cells {
+		if cell != "" {
+			values[cell] = true
+		}
+	}
+	return len(values) > 1
+}
+
+// rolePolicy returns the attached or inline policy of a role with the given name
+func rolePolicy(role *RoleItem, name string, inline bool) *PolicyItem {
+	for i, policy := range role.policies {
+		if policy.policyName == name && (policy.policyType == "Inline") == inline {
+			return &role.policies[i]
+		}
+	}
+	return nil
+}
+
+// truncateText shortens text to a maximum number of characters
+func truncateText(text string, maxLen int) string {
+	runes := []rune(text)
+	if len(runes) <= maxLen {
+		return text
+	}
+	return string(runes[:maxLen-1]) + "…"
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	appconfig "github.com/vlkyrylenko/atui/config"
+)
+
+// Test the drift matrix of a role across environments
+func TestDriftMatrix(t *testing.T) {
+	trust := `{"Statement": [{"Principal": {"Service": "ecs-tasks.amazonaws.com"}}]}`
+	role := func(inline string, extra ...PolicyItem) *RoleItem {
+		policies := append([]PolicyItem{
+			{policyName: "ReadOnly", policyArn: "arn:aws:iam::aws:policy/ReadOnly", policyType: "AWS"},
+			{policyName: "App", policyType: "Inline", rawDocument: inline},
+		}, extra...)
+		return &RoleItem{trustPolicy: trust, policies: policies, policiesLoaded: true}
+	}
+	check := &driftCheck{
+		pattern:  "svc-{env}-api",
+		profiles: []string{"dev", "stage", "prod", "qa"},
+		roles: map[string]*RoleItem{
+			"dev":   role(`{"Action": "s3:GetObject"}`),
+			"stage": role(`{ "Action": "s3:GetObject" }`),
+			"prod":  role(`{"Action": "s3:*"}`, PolicyItem{policyName: "AdministratorAccess", policyArn: "arn:aws:iam::aws:policy/AdministratorAccess", policyType: "AWS"}),
+		},
+		errs: map[string]error{"qa": fmt.Errorf("expired token")},
+	}
+
+	matrix := stripAnsiCodes(driftMatrix(check))
+
+	for _, expected := range []string{
+		"Drift of svc-{env}-api",
+		"Role                            ✓         ✓         ✓         error",
+		"Trust policy                    A         A         A",
+		"AdministratorAccess             —         —         ✓",
+		"Inline App                      A         A         B",
+		"Inline App: variant B (prod) against A (dev, stage)",
+		`+   "Action": "s3:*"`,
+		"qa: expired token",
+	} {
+		if !strings.Contains(matrix, expected) {
+			t.Errorf("Expected matrix to contain %q, got:\n%s", expected, matrix)
+		}
+	}
+	if strings.Contains(matrix, "Trust policy: variant") {
+		t.Errorf("Expected no details for identical trust policies")
+	}
+}
+
+// Test that only diverging rows are highlighted
+func TestDriftDiverges(t *testing.T) {
+	if driftDiverges([]string{"A", "", "A"}) {
+		t.Errorf("Expected profiles without the role to be ignored")
+	}
+	if !driftDiverges([]string{"✓", "—"}) {
+		t.Errorf("Expected different cells to diverge")
+	}
+}
+
+// Test prompting for the role name and collecting results
+func TestDriftPrompt(t *testing.T) {
+	m := createTestModel()
+	m.currentScreen = "profiles"
+	m.profilesList.SetItems([]list.Item{&ProfileItem{name: "dev", marked: true}, &ProfileItem{name: "prod", marked: true}})
+
+	newModel, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'D'}})
+	m = newModel.(model)
+	if m.currentScreen != "drift_prompt" {
+		t.Fatalf("Expected the drift prompt, got '%s'", m.currentScreen)
+	}
+
+	// Keys that are shortcuts elsewhere are typed into the prompt
+	for _, r := range "svc-{env}-q" {
+		newModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+		m = newModel.(model)
+	}
+	newModel, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = newModel.(model)
+	if cmd == nil || m.currentScreen != "drift" || m.drift.pattern != "svc-{env}-q" {
+		t.Fatalf("Expected drift check to start, got pattern '%s'", m.driftInput.Value())
+	}
+
+	m.addDriftRole(driftRoleLoadedMsg{check: m.drift, profile: "dev", role: &RoleItem{roleName: "svc-dev-q"}})
+	if !strings.Contains(m.statusMsg, "1 of 2") {
+		t.Errorf("Expected progress, got '%s'", m.statusMsg)
+	}
+	m.addDriftRole(driftRoleLoadedMsg{check: m.drift, profile: "prod"})
+	if !strings.Contains(stripAnsiCodes(m.driftView.View()), "missing") {
+		t.Errorf("Expected the matrix to show the missing prod role")
+	}
+
+	if driftRoleName(m.drift.pattern, "prod") != "svc-prod-q" {
+		t.Errorf("Expected the environment placeholder to be filled")
+	}
+}
+
+// Test {env} is the whole profile name unless the config maps the profile to an environment
+func TestDriftRoleName(t *testing.T) {
+	if got := driftRoleName("svc-{env}-api", "acme-prod"); got != "svc-acme-prod-api" {
+		t.Errorf("Expected the profile name by default, got '%s'", got)
+	}
+
+	previous := driftEnvironment
+	config := appconfig.Config{Drift: appconfig.DriftSettings{Environments: map[string]string{"acme-prod": "prod"}}}
+	driftEnvironment = config.DriftEnvironment
+	t.Cleanup(func() { driftEnvironment = previous })
+	if got := driftRoleName("svc-{env}-api", "acme-prod"); got != "svc-prod-api" {
+		t.Errorf("Expected the configured environment, got '%s'", got)
+	}
+	if got := driftRoleName("svc-{env}-api", "dev"); got != "svc-dev-api" {
+		t.Errorf("Expected unmapped profiles to keep their name, got '%s'", got)
+	}
+}
+
+// Test long names are shortened by characters, not bytes
+func TestTruncateText(t *testing.T) {
+	if got := truncateText("short", 10); got != "short" {
+		t.Errorf("Expected short text unchanged, got '%s'", got)
+	}
+	if got := truncateText("élément-éphémère", 8); got != "élément…" || !utf8.ValidString(got) {
+		t.Errorf("Expected 8 characters, got '%s'", got)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/organizations"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	appconfig "github.com/vlkyrylenko/atui/config"
+)
+
+var (
+	// endpointOverride is the endpoint URL of every AWS client, set with --endpoint-url
+	endpointOverride string
+	// endpointConfig is the atui config holding endpoint URLs by profile
+	endpointConfig = &appconfig.Config{}
+)
+
+// configEndpoint returns the base endpoint of a profile's configuration, empty for the SDK's resolution.
+// AWS_ENDPOINT_URL takes precedence over the atui config, and AWS_ENDPOINT_URL_<SERVICE> over both in each client.
+func configEndpoint(profile string) string {
+	if endpointOverride != "" {
+		return endpointOverride
+	}
+	if _, set := os.LookupEnv("AWS_ENDPOINT_URL"); set {
+		return ""
+	}
+	if profile == "" {
+		profile = currentProfileName()
+	}
+	return endpointConfig.EndpointURL(profile)
+}
+
+// overrideEndpoint replaces a client's base endpoint with the --endpoint-url one, which beats all other settings
+func overrideEndpoint(baseEndpoint **string) {
+	if endpointOverride != "" {
+		*baseEndpoint = aws.String(endpointOverride)
+	}
+}
+
+// newIAMClient creates an IAM client at the configured endpoint, calling through the call layer
+func newIAMClient(cfg aws.Config) *iam.Client {
+	return iam.NewFromConfig(cfg, func(o *iam.Options) {
+		overrideEndpoint(&o.BaseEndpoint)
+		o.APIOptions = append(o.APIOptions, apiCalls.addMiddleware)
+	})
+}
+
+// newSTSClient creates an STS client at the configured endpoint, calling through the call layer
+func newSTSClient(cfg aws.Config) *sts.Client {
+	return sts.NewFromConfig(cfg, func(o *sts.Options) {
+		overrideEndpoint(&o.BaseEndpoint)
+		o.APIOptions = append(o.APIOptions, apiCalls.addMiddleware)
+	})
+}
+
+// newOrganizationsClient creates an Organizations client at the configured endpoint, calling through the call layer
+func newOrganizationsClient(cfg aws.Config) *organizations.Client {
+	return organizations.NewFromConfig(cfg, func(o *organizations.Options) {
+		overrideEndpoint(&o.BaseEndpoint)
+		o.APIOptions = append(o.APIOptions, apiCalls.addMiddleware)
+	})
+}
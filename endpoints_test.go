@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	appconfig "github.com/vlkyrylenko/atui/config"
+)
+
+// setEndpoints replaces the endpoint settings for a test
+func setEndpoints(t *testing.T, override string, byProfile map[string]string) {
+	previousOverride, previousConfig := endpointOverride, endpointConfig
+	endpointOverride, endpointConfig = override, &appconfig.Config{Endpoints: byProfile}
+	t.Cleanup(func() { endpointOverride, endpointConfig = previousOverride, previousConfig })
+}
+
+// Test the precedence of endpoint settings
+func TestConfigEndpoint(t *testing.T) {
+	os.Unsetenv("AWS_ENDPOINT_URL")
+	setEndpoints(t, "", map[string]string{"localstack": "http://localhost:4566"})
+	if configEndpoint("localstack") != "http://localhost:4566" || configEndpoint("prod") != "" {
+		t.Errorf("Expected the configured endpoint of the profile only")
+	}
+
+	t.Setenv("AWS_ENDPOINT_URL", "http://localhost:5000")
+	if configEndpoint("localstack") != "" {
+		t.Errorf("Expected AWS_ENDPOINT_URL to be left to the SDK")
+	}
+
+	setEndpoints(t, "http://vpce.example:443", nil)
+	if configEndpoint("localstack") != "http://vpce.example:443" {
+		t.Errorf("Expected --endpoint-url to win")
+	}
+}
+
+// Test that clients are created at the configured endpoints
+func TestClientEndpoints(t *testing.T) {
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "config")
+	if err := os.WriteFile(configFile, []byte("[profile localstack]\nregion = us-east-1\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AWS_CONFIG_FILE", configFile)
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "credentials"))
+	os.Unsetenv("AWS_ENDPOINT_URL")
+	setEndpoints(t, "", map[string]string{"localstack": "http://localhost:4566"})
+
+	cfg, err := loadAWSConfig(context.Background(), "localstack", "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if aws.ToString(newIAMClient(cfg).Options().BaseEndpoint) != "http://localhost:4566" {
+		t.Errorf("Expected IAM at the profile's endpoint")
+	}
+
+	// Service specific variables beat the profile's endpoint
+	t.Setenv("AWS_ENDPOINT_URL_STS", "http://localhost:4567")
+	cfg, _ = loadAWSConfig(context.Background(), "localstack", "")
+	if aws.ToString(newSTSClient(cfg).Options().BaseEndpoint) != "http://localhost:4567" {
+		t.Errorf("Expected STS at AWS_ENDPOINT_URL_STS, got %s", aws.ToString(newSTSClient(cfg).Options().BaseEndpoint))
+	}
+
+	// --endpoint-url beats everything
+	setEndpoints(t, "http://localhost:9999", nil)
+	cfg, _ = loadAWSConfig(context.Background(), "localstack", "")
+	for name, endpoint := range map[string]*string{
+		"IAM":           newIAMClient(cfg).Options().BaseEndpoint,
+		"STS":           newSTSClient(cfg).Options().BaseEndpoint,
+		"Organizations": newOrganizationsClient(cfg).Options().BaseEndpoint,
+	} {
+		if aws.ToString(endpoint) != "http://localhost:9999" {
+			t.Errorf("Expected %s at the --endpoint-url, got %s", name, aws.ToString(endpoint))
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/credentials/ssocreds"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Kinds of failed requests
+const (
+	failureAccessDenied = "access denied"
+	failureExpired      = "expired credentials"
+	failureThrottled    = "throttled"
+	failureNetwork      = "network"
+	failureOther        = "other"
+)
+
+var (
+	// accessDeniedCodes are error codes of requests the caller has no permission for
+	accessDeniedCodes = []string{"AccessDenied", "AccessDeniedException", "UnauthorizedOperation"}
+	// expiredCodes are error codes of expired or invalid credentials
+	expiredCodes = []string{"ExpiredToken", "ExpiredTokenException", "RequestExpired", "InvalidClientTokenId", "UnrecognizedClientException", "InvalidGrantException"}
+	// deniedAction and deniedResource match the details of AccessDenied messages, e.g.
+	// "User: arn:... is not authorized to perform: iam:GetPolicy on resource: arn:... because ..."
+	deniedAction   = regexp.MustCompile(`not authorized to perform: ([\w-]+:[\w*-]+)`)
+	deniedResource = regexp.MustCompile(`on resource: (.+?)(?: because | with an explicit deny|$)`)
+)
+
+// failure is a failed request shown in a panel while the current screen stays usable
+type failure struct {
+	kind     string
+	action   string // IAM action that was denied, e.g. "iam:GetPolicy"
+	resource string // Resource the action was denied on, when AWS names it
+	err      error
+	screen   string                 // Screen of the failed request, empty for background loads
+	retry    func(m *model) tea.Cmd // Runs the request again, nil when it cannot be retried
+}
+
+// classifyError tells why a request failed
+func classifyError(err error) *failure {
+	f := &failure{kind: failureOther, err: err}
+
+	var apiErr smithy.APIError
+	var invalidToken *ssocreds.InvalidTokenError
+	var netErr net.Error
+	var sendErr *smithyhttp.RequestSendError
+	switch {
+	case errors.As(err, &apiErr) && slices.Contains(accessDeniedCodes, apiErr.ErrorCode()):
+		f.kind = failureAccessDenied
+		f.action, f.resource = deniedDetails(err)
+	case errors.As(err, &apiErr) && slices.Contains(expiredCodes, apiErr.ErrorCode()), errors.As(err, &invalidToken):
+		f.kind = failureExpired
+	case retry.IsErrorThrottles(retry.DefaultThrottles).IsErrorThrottle(err) == aws.TrueTernary:
+		f.kind = failureThrottled
+	case errors.As(err, &netErr), errors.As(err, &sendErr), errors.Is(err, context.DeadlineExceeded):
+		f.kind = failureNetwork
+	}
+	return f
+}
+
+// deniedDetails returns the denied action and resource of an AccessDenied error. Without an action in the
+// message, the action is the called operation, which is named like its IAM action.
+func deniedDetails(err error) (string, string) {
+	message := err.Error()
+	action := ""
+	if match := deniedAction.FindStringSubmatch(message); match != nil {
+		action = match[1]
+	} else if opErr := (*smithy.OperationError)(nil); errors.As(err, &opErr) {
+		action = strings.ToLower(opErr.ServiceID) + ":" + opErr.OperationName
+	}
+	resource := ""
+	if match := deniedResource.FindStringSubmatch(message); match != nil {
+		resource = match[1]
+	}
+	return action, resource
+}
+
+// title explains the failure in one line
+func (f *failure) title() string {
+	switch f.kind {
+	case failureAccessDenied:
+		if f.action == "" {
+			return "Access denied"
+		}
+		if f.resource != "" {
+			return fmt.Sprintf("Access denied: %s is not allowed on %s", f.action, f.resource)
+		}
+		return fmt.Sprintf("Access denied: %s is not allowed", f.action)
+	case failureExpired:
+		return "Credentials expired or invalid: log in again, e.g. with aws sso login, and retry"
+	case failureThrottled:
+		return "AWS kept throttling the request"
+	case failureNetwork:
+		return "AWS could not be reached"
+	}
+	return "Request failed"
+}
+
+// showFailure opens the failure panel of an error, replacing the previous one
+func (m *model) showFailure(err error, screen string, retry func(m *model) tea.Cmd) {
+	m.failure = classifyError(err)
+	m.failure.screen = screen
+	m.failure.retry = retry
+}
+
+// clearFailure closes the failure panel of a screen's request, e.g. when the screen shows another selection
+func (m *model) clearFailure(screen string) {
+	if m.failure != nil && m.failure.screen == screen {
+		m.failure = nil
+	}
+}
+
+// retryFailure closes the failure panel and runs the failed request again
+func (m *model) retryFailure() tea.Cmd {
+	f := m.failure
+	if f == nil || f.retry == nil {
+		return nil
+	}
+	// A screen's request is only repeated on its screen, whose selection it loads for
+	if f.screen != "" && f.screen != m.currentScreen {
+		m.statusMsg = "Go back to the screen of the failed request to retry it"
+		return nil
+	}
+	m.failure = nil
+	return f.retry(m)
+}
+
+// renderFailure draws the failure panel shown above the footer
+func (m model) renderFailure() string {
+	f := m.failure
+	text := appTheme.errorMessageStyle(f.title()) + "\n" + wordWrap(f.err.Error(), max(m.width-6, 20))
+	if f.kind == failureAccessDenied {
+		text += "\nScreens that do not need this permission keep working."
+	}
+	help := "esc dismiss"
+	if f.retry != nil {
+		help = "t retry • " + help
+	}
+	text += "\n" + lipgloss.NewStyle().Foreground(lipgloss.Color("245")).Render(help)
+
+	return lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("196")). // Red
+		Padding(0, 1).
+		Render(text) + "\n"
+}
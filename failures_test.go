@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/credentials/ssocreds"
+	"github.com/aws/smithy-go"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Test AWS errors are classified, with the denied action and resource of AccessDenied
+func TestClassifyError(t *testing.T) {
+	denied := &smithy.OperationError{ServiceID: "IAM", OperationName: "GetPolicy", Err: &smithy.GenericAPIError{
+		Code:    "AccessDenied",
+		Message: "User: arn:aws:sts::123456789012:assumed-role/ReadOnly/jane is not authorized to perform: iam:GetPolicy on resource: policy arn:aws:iam::aws:policy/ReadOnlyAccess because no identity-based policy allows the iam:GetPolicy action",
+	}}
+	tests := []struct {
+		err      error
+		kind     string
+		action   string
+		resource string
+	}{
+		{fmt.Errorf("error getting policy: %w", denied), failureAccessDenied, "iam:GetPolicy", "policy arn:aws:iam::aws:policy/ReadOnlyAccess"},
+		{&smithy.OperationError{ServiceID: "IAM", OperationName: "GetRolePolicy", Err: &smithy.GenericAPIError{Code: "AccessDenied"}}, failureAccessDenied, "iam:GetRolePolicy", ""},
+		{&smithy.GenericAPIError{Code: "ExpiredToken"}, failureExpired, "", ""},
+		{&ssocreds.InvalidTokenError{Err: errors.New("token expired")}, failureExpired, "", ""},
+		{&smithy.GenericAPIError{Code: "Throttling"}, failureThrottled, "", ""},
+		{&net.OpError{Op: "dial", Err: errors.New("connection refused")}, failureNetwork, "", ""},
+		{fmt.Errorf("no response within 30s: %w", context.DeadlineExceeded), failureNetwork, "", ""},
+		{errors.New("something else"), failureOther, "", ""},
+	}
+	for _, test := range tests {
+		f := classifyError(test.err)
+		if f.kind != test.kind || f.action != test.action || f.resource != test.resource {
+			t.Errorf("Expected %s %q on %q for %v, got %s %q on %q", test.kind, test.action, test.resource, test.err, f.kind, f.action, f.resource)
+		}
+	}
+	if title := classifyError(denied).title(); title != "Access denied: iam:GetPolicy is not allowed on policy arn:aws:iam::aws:policy/ReadOnlyAccess" {
+		t.Errorf("Expected the denied action in the title, got '%s'", title)
+	}
+}
+
+// Test a failed request keeps the screen usable and is retried with the retry key
+func TestFailureRetry(t *testing.T) {
+	f := newTestBackend()
+	f.failOn("ListAttachedRolePolicies", &smithy.GenericAPIError{Code: "AccessDenied", Message: "not authorized to perform: iam:ListAttachedRolePolicies on resource: role TestRole"})
+	m := createTestModel()
+	m.backend = f
+	role := &RoleItem{roleName: "TestRole"}
+	m.rolesList.SetItems([]list.Item{role})
+
+	newModel, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = newModel.(model)
+	newModel, _ = m.Update(requestResponse(t, cmd))
+	m = newModel.(model)
+	if m.failure == nil || m.failure.action != "iam:ListAttachedRolePolicies" || m.failure.resource != "role TestRole" || m.currentScreen != "policies" {
+		t.Fatalf("Expected the denied action in a panel on the policies screen, got %+v", m.failure)
+	}
+
+	f.failOn("ListAttachedRolePolicies", nil)
+	newModel, cmd = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("t")})
+	m = newModel.(model)
+	if m.failure != nil || cmd == nil {
+		t.Fatalf("Expected the retry to close the panel and load again")
+	}
+	newModel, _ = m.Update(requestResponse(t, cmd))
+	m = newModel.(model)
+	if !role.policiesLoaded || len(m.policiesList.Items()) != 2 {
+		t.Errorf("Expected the retried request to load the policies")
+	}
+}
+
+// Test Esc closes the failure panel before navigating, and errors without a request cannot be retried
+func TestFailureDismiss(t *testing.T) {
+	m := createTestModel()
+	m.currentScreen = "policies"
+	newModel, _ := m.Update(errorMsg(errors.New("boom")))
+	m = newModel.(model)
+	if m.failure == nil || m.failure.retry != nil {
+		t.Fatalf("Expected a failure without retry")
+	}
+
+	newModel, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("t")})
+	m = newModel.(model)
+	if cmd != nil || m.failure == nil {
+		t.Errorf("Expected nothing to retry")
+	}
+	newModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	m = newModel.(model)
+	if m.failure != nil || m.currentScreen != "policies" {
+		t.Errorf("Expected Esc to only close the panel, got screen '%s'", m.currentScreen)
+	}
+}
+
+// Test a failed identity load is retried together with the roles
+func TestIdentityFailureRetry(t *testing.T) {
+	f := newTestBackend()
+	f.failOn("GetCallerIdentity", &smithy.GenericAPIError{Code: "ExpiredToken"})
+	m := createTestModel()
+	m.backend = f
+
+	newModel, _ := m.Update(loadUserArnCmd(context.Background(), m.session(), m.viewKey())())
+	m = newModel.(model)
+	if m.failure == nil || m.failure.kind != failureExpired || m.failure.retry == nil {
+		t.Fatalf("Expected a retryable expired credentials failure, got %+v", m.failure)
+	}
+	if cmd := m.retryFailure(); cmd == nil || m.refreshPending != 2 {
+		t.Errorf("Expected the identity and the roles to load again")
+	}
+}
+
+// Test a failed roles page ends the refresh and is retried by loading the roles again
+func TestRolesStreamFailure(t *testing.T) {
+	m := createTestModel()
+	m.backend = newTestBackend()
+	m.refreshPending = 1
+	m.startRolesStream(m.session())
+
+	newModel, _ := m.Update(rolesPageMsg{stream: m.rolesStream, err: errors.New("network down")})
+	m = newModel.(model)
+	if m.failure == nil || m.rolesStream != nil || m.refreshPending != 0 {
+		t.Fatalf("Expected the failure shown and the refresh ended")
+	}
+	if cmd := m.retryFailure(); cmd == nil || m.rolesStream == nil || m.refreshPending != 1 {
+		t.Errorf("Expected the roles to load again")
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test the bulk account load pages through users, groups, roles and policies of the fake
+func TestFakeAccountAuthorizationDetails(t *testing.T) {
+	f := newSampleFakeBackend()
+	f.pageSize = 3
+
+	data, err := loadAccountDetails(context.Background(), f)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(data.roles) != 8 || len(data.users) != 2 || len(data.groups) != 1 || len(data.policies) != 5 {
+		t.Fatalf("Expected 8 roles, 2 users, 1 group and 5 policies, got %d, %d, %d and %d", len(data.roles), len(data.users), len(data.groups), len(data.policies))
+	}
+	if f.callCount("GetAccountAuthorizationDetails") != 6 {
+		t.Errorf("Expected 16 items in 6 pages, got %d pages", f.callCount("GetAccountAuthorizationDetails"))
+	}
+
+	developer, _ := data.findRole("Developer")
+	if developer.permissionsBoundary != f.customerPolicyArn("DeveloperBoundary") || developer.tags["team"] != "app" || len(developer.policies) != 2 {
+		t.Errorf("Expected the developer's boundary, tags and policies, got %+v", developer)
+	}
+	// The default version is the last one
+	if !strings.Contains(data.policies[f.customerPolicyArn("ArtifactsReadWrite")].rawDocument, "s3:*Object") {
+		t.Errorf("Expected the default version of the policy")
+	}
+	deploy, _ := data.findRole("ci-deploy")
+	if deploy.roleArn != "arn:aws:iam::123456789012:role/ci/ci-deploy" || len(deploy.policies) != 2 || deploy.policies[1].policyType != "Inline" {
+		t.Errorf("Expected the path in the ARN and an inline policy, got %+v", deploy)
+	}
+}
+
+// Test drift checks see the fake's missing roles and inline policies
+func TestFakeDriftRole(t *testing.T) {
+	f := newSampleFakeBackend()
+	check := &driftCheck{pattern: "{env}-lambda"}
+
+	msg := loadDriftRoleCmd(context.Background(), check, awsSession{profile: "orders", backend: f})().(driftRoleLoadedMsg)
+	if msg.err != nil || msg.role == nil || len(msg.role.policies) != 2 {
+		t.Fatalf("Expected the role with an attached and an inline policy, got %+v", msg)
+	}
+
+	msg = loadDriftRoleCmd(context.Background(), check, awsSession{profile: "missing", backend: f})().(driftRoleLoadedMsg)
+	if msg.err != nil || msg.role != nil {
+		t.Errorf("Expected a missing role without error, got %+v", msg)
+	}
+
+	f.failOn("GetRole", fmt.Errorf("access denied"))
+	msg = loadDriftRoleCmd(context.Background(), check, awsSession{profile: "orders", backend: f})().(driftRoleLoadedMsg)
+	if msg.err == nil {
+		t.Errorf("Expected the injected error")
+	}
+}
+
+// Test assuming roles of the fake account
+func TestFakeAssumeRole(t *testing.T) {
+	f := newSampleFakeBackend()
+	session := awsSession{backend: f}
+	req := assumeRequest{roleArn: "arn:aws:iam::123456789012:role/Admin", roleName: "Admin", sessionName: "atui", duration: time.Hour}
+
+	msg := assumeRoleCmd(context.Background(), session, req)().(roleAssumedMsg)
+	if msg.err != nil {
+		t.Fatalf("Unexpected error: %v", msg.err)
+	}
+	if msg.identity.arn != "arn:aws:sts::123456789012:assumed-role/Admin/atui" || msg.identity.session.backend != f {
+		t.Errorf("Expected the assumed role session on the fake, got %+v", msg.identity)
+	}
+	if time.Until(msg.identity.expiration) < 59*time.Minute {
+		t.Errorf("Expected credentials for the requested duration, got %s", msg.identity.expiration)
+	}
+
+	req.roleArn = "arn:aws:iam::123456789012:role/Missing"
+	if msg := assumeRoleCmd(context.Background(), session, req)().(roleAssumedMsg); msg.err == nil {
+		t.Errorf("Expected an error assuming a missing role")
+	}
+}
+
+// Test data of the fake backend is never cached
+func TestFakeBackendNotCached(t *testing.T) {
+	m := createTestModel()
+	m.backend = newSampleFakeBackend()
+	m.accountID = "123456789012"
+	m.lastRefresh = time.Now()
+
+	if msg := m.cachedAccountCmd("default")().(cachedAccountLoadedMsg); msg.data != nil {
+		t.Errorf("Expected no cached data for the fake")
+	}
+	if m.saveCurrentCacheCmd() != nil {
+		t.Errorf("Expected fake data not to be cached")
+	}
+	if m.profileSession("dev").backend != m.backend {
+		t.Errorf("Expected sessions to use the model's backend")
+	}
+}
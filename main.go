@@ -1,26 +1,29 @@
 package main
 
 import (
-	"bufio"
 	"context"
+	"errors"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"log"
 	"net/url"
 	"os"
-	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
+	"time"
 
 	appconfig "github.com/vlkyrylenko/atui/config"
 )
@@ -45,24 +48,97 @@ var (
 	appTheme Theme
 )
 
+// cacheTTL is how long cached IAM data is used before refreshing on startup
+var cacheTTL = appconfig.DefaultConfig.CacheTTL()
+
 // Model holds the application state
 type model struct {
 	rolesList         list.Model
 	policiesList      list.Model
-	loading           bool
 	spinner           spinner.Model
 	selectedRole      *RoleItem
 	policyView        viewport.Model
 	selectedPolicy    *PolicyItem
 	policyDocument    string
 	currentScreen     string
-	err               error
+	failure           *failure
 	width, height     int
 	statusMsg         string
 	currentProfile    string
+	sessionProfile    string // Profile picked on the profiles screen, empty for the environment default
 	availableProfiles []string
 	profilesList      list.Model
-	userArn           string // Store current user ARN
+	userArn           string       // Store current user ARN
+	account           *accountData // Bulk-loaded account details, nil until loaded
+	accountID         string       // Account of the current identity
+	// Cache state
+	cachedAt        time.Time // When the shown data was cached, zero once refreshed
+	cachedAccountID string    // Account the cached data belongs to
+	refreshPending  int       // Loaders still running for the current refresh
+	lastRefresh     time.Time // When data was last loaded from AWS
+	// Backend of every session, nil for AWS
+	backend awsBackend
+	// Last call AWS throttled, shown in the header while it is recent
+	throttledAt        time.Time
+	throttledOperation string
+	// Roles list loading page by page, nil when not loading
+	rolesStream *rolesStream
+	// Bulk account load in flight, nil when not loading
+	accountLoad *accountLoad
+	// Bumped whenever the account view is reset, loads started before are dropped
+	viewGeneration int
+	// Requests in flight by ID, responses of other requests are dropped
+	requests      map[int]*loadRequest
+	nextRequestID int
+	screenLoads   map[string]*screenLoad // Contexts of background loads by screen, shared by copies of the model
+	// Offline browsing of exported data without AWS access
+	offline     bool
+	sourceLabel string // Shown instead of the profile when browsing offline data or several accounts
+	// Roles of several profiles shown together
+	multiProfiles []string // Nil when a single account is shown
+	multiPending  int      // Profiles still loading
+	multiErrors   []string // Profiles that failed to load
+	// Scan of every account of an organization, nil when not scanning
+	orgScan *orgScan
+	orgList list.Model
+	// Background count of the policies of listed roles, nil when not counting
+	policyCounts *policyCountScan
+	// Snapshot comparison
+	diffList     list.Model
+	diffView     viewport.Model
+	diffBase     *accountData // Older state compared against the live account
+	diffPending  bool         // Waiting for the live account to compare against
+	diffSelected *diffChange
+	// Comparison of two marked roles
+	compared         [2]*RoleItem
+	compareDocuments map[string]string // Documents loaded for the comparison by ARN
+	compareView      viewport.Model
+	// Drift of a role across profiles
+	drift      *driftCheck
+	driftInput textinput.Model
+	driftView  viewport.Model
+	// Decoding of encoded authorization failure messages, the decoded document is shown in policyView
+	decodeInput   textinput.Model
+	decodeSummary []string // Denied action, resource, matched statements and context of the decoded message
+	// Roles assumed from the roles screen, the last one is browsed
+	identities   []assumedIdentity
+	assumeTarget *RoleItem
+	assumeInputs []textinput.Model
+	assumeFocus  int
+	// Ways to write out the credentials of the assumed role
+	credentialsList list.Model
+	// MFA code modal of a credential provider, nil when closed
+	mfaPrompt       *mfaRequest
+	mfaInput        textinput.Model
+	mfaReturnScreen string
+	// Region applied to all sessions, empty for the profile's region
+	region             string
+	activeRegion       string // Region the identity was last loaded in
+	regionsList        list.Model
+	regionReturnScreen string
+	// IAM Identity Center login waiting for approval, nil when none
+	ssoLogin        *ssoLogin
+	ssoReturnScreen string
 	// Viewport search functionality
 	searchMode    bool
 	searchQuery   string
@@ -77,7 +153,20 @@ type RoleItem struct {
 	description    string
 	policies       []PolicyItem
 	policiesLoaded bool
-	policyCount    int // Add count of policies
+	policyCount    int  // Add count of policies
+	countLoaded    bool // policyCount was counted in the background, the policies are not loaded
+	marked         bool
+	// Seconds a session of the role may last, only known from the roles list
+	maxSessionDuration int32
+	// Details filled by the bulk account loader
+	path                string
+	trustPolicy         string
+	permissionsBoundary string
+	tags                map[string]string
+	// Set when roles of several accounts are shown together
+	profile      string
+	accountID    string
+	accountAlias string
 }
 
 // PolicyItem represents an IAM policy
@@ -87,17 +176,43 @@ type PolicyItem struct {
 	policyType     string // Added policy type (AWS managed vs Customer managed)
 	policyDocument string
 	documentLoaded bool
+	// Decoded JSON document as returned by IAM, set when known without a separate request
+	rawDocument      string
+	defaultVersionId string
 }
 
-func (i RoleItem) Title() string { return i.roleName }
+func (i RoleItem) Title() string {
+	if i.marked {
+		return "✓ " + i.roleName
+	}
+	return i.roleName
+}
 func (i RoleItem) Description() string {
 	desc := i.description
+	if i.accountID != "" {
+		columns := []string{i.accountLabel()}
+		if i.profile != "" {
+			columns = append(columns, i.profile)
+		}
+		if desc != "" {
+			columns = append(columns, desc)
+		}
+		desc = strings.Join(columns, " | ")
+	}
 	if i.policiesLoaded {
 		desc += fmt.Sprintf(" | %d policies attached", len(i.policies))
+	} else if i.countLoaded {
+		desc += fmt.Sprintf(" | %d policies attached", i.policyCount)
 	}
 	return desc
 }
-func (i RoleItem) FilterValue() string { return i.roleName }
+func (i RoleItem) FilterValue() string {
+	if i.accountID == "" {
+		return i.roleName
+	}
+	// Filtering by account or profile narrows the aggregated list
+	return strings.Join([]string{i.roleName, i.accountID, i.accountAlias, i.profile}, " ")
+}
 
 func (i PolicyItem) Title() string {
 	// Make the title more prominent by adding a symbol
@@ -122,12 +237,19 @@ func (i PolicyItem) FilterValue() string { return i.policyName }
 
 // ProfileItem represents an AWS profile for the list
 type ProfileItem struct {
-	name string
+	name        string
+	description string // Type, region and account of the profile
+	marked      bool   // Picked for the aggregated multi-account view
 }
 
-func (i ProfileItem) Title() string       { return i.name }
-func (i ProfileItem) Description() string { return "" }
-func (i ProfileItem) FilterValue() string { return i.name }
+func (i ProfileItem) Title() string {
+	if i.marked {
+		return "✓ " + i.name
+	}
+	return i.name
+}
+func (i ProfileItem) Description() string { return i.description }
+func (i ProfileItem) FilterValue() string { return i.name + " " + i.description }
 
 // Key mappings
 type keyMap struct {
@@ -136,6 +258,19 @@ type keyMap struct {
 	Enter         key.Binding
 	Back          key.Binding
 	SwitchProfile key.Binding
+	SwitchRegion  key.Binding
+	Mark          key.Binding
+	OrgScan       key.Binding
+	Compare       key.Binding
+	Drift         key.Binding
+	Assume        key.Binding
+	PopIdentity   key.Binding
+	ExportCreds   key.Binding
+	Decode        key.Binding
+	NextField     key.Binding
+	Refresh       key.Binding
+	Retry         key.Binding
+	Export        key.Binding
 	Quit          key.Binding
 	Filter        key.Binding // Filter list items
 	// Viewport-specific key bindings
@@ -199,6 +334,58 @@ var keys = keyMap{
 		key.WithKeys("p"),
 		key.WithHelp("p", "switch profiles"),
 	),
+	SwitchRegion: key.NewBinding(
+		key.WithKeys("R"),
+		key.WithHelp("R", "switch region"),
+	),
+	Mark: key.NewBinding(
+		key.WithKeys(" "),
+		key.WithHelp("space", "mark"),
+	),
+	OrgScan: key.NewBinding(
+		key.WithKeys("o"),
+		key.WithHelp("o", "scan progress"),
+	),
+	Compare: key.NewBinding(
+		key.WithKeys("c"),
+		key.WithHelp("c", "compare marked"),
+	),
+	Drift: key.NewBinding(
+		key.WithKeys("D"),
+		key.WithHelp("D", "check drift"),
+	),
+	Assume: key.NewBinding(
+		key.WithKeys("a"),
+		key.WithHelp("a", "assume role"),
+	),
+	PopIdentity: key.NewBinding(
+		key.WithKeys("z"),
+		key.WithHelp("z", "previous identity"),
+	),
+	ExportCreds: key.NewBinding(
+		key.WithKeys("E"),
+		key.WithHelp("E", "export credentials"),
+	),
+	Decode: key.NewBinding(
+		key.WithKeys("e"),
+		key.WithHelp("e", "decode message"),
+	),
+	NextField: key.NewBinding(
+		key.WithKeys("tab", "shift+tab"),
+		key.WithHelp("tab", "next field"),
+	),
+	Refresh: key.NewBinding(
+		key.WithKeys("r"),
+		key.WithHelp("r", "refresh"),
+	),
+	Retry: key.NewBinding(
+		key.WithKeys("t"),
+		key.WithHelp("t", "retry"),
+	),
+	Export: key.NewBinding(
+		key.WithKeys("x"),
+		key.WithHelp("x", "export snapshot"),
+	),
 	Quit: key.NewBinding(
 		key.WithKeys("q", "ctrl+c"),
 		key.WithHelp("q", "quit"),
@@ -270,6 +457,46 @@ func updateKeyBindingsForScreen(currentScreen string) {
 			key.WithKeys("enter"),
 			key.WithHelp("enter", "switch profile"),
 		)
+	case "diff":
+		keys.Enter = key.NewBinding(
+			key.WithKeys("enter"),
+			key.WithHelp("enter", "view change"),
+		)
+	case "drift_prompt":
+		keys.Enter = key.NewBinding(
+			key.WithKeys("enter"),
+			key.WithHelp("enter", "check drift"),
+		)
+	case "org":
+		keys.Enter = key.NewBinding(
+			key.WithKeys("enter"),
+			key.WithHelp("enter", "view account roles"),
+		)
+	case "assume":
+		keys.Enter = key.NewBinding(
+			key.WithKeys("enter"),
+			key.WithHelp("enter", "assume role"),
+		)
+	case "credentials":
+		keys.Enter = key.NewBinding(
+			key.WithKeys("enter"),
+			key.WithHelp("enter", "write credentials"),
+		)
+	case "decode_prompt":
+		keys.Enter = key.NewBinding(
+			key.WithKeys("enter"),
+			key.WithHelp("enter", "decode"),
+		)
+	case "regions":
+		keys.Enter = key.NewBinding(
+			key.WithKeys("enter"),
+			key.WithHelp("enter", "switch region"),
+		)
+	case "mfa":
+		keys.Enter = key.NewBinding(
+			key.WithKeys("enter"),
+			key.WithHelp("enter", "submit code"),
+		)
 	default:
 		keys.Enter = key.NewBinding(
 			key.WithKeys("enter"),
@@ -340,6 +567,63 @@ func initialModel() model {
 	policyView := viewport.New(0, 0)
 	policyView.Style = lipgloss.NewStyle().Padding(1, 2)
 
+	diffList := list.New([]list.Item{}, list.NewDefaultDelegate(), 0, 0)
+	diffList.Title = "Changes"
+	diffList.SetShowStatusBar(false)
+	diffList.SetFilteringEnabled(true)
+	diffList.SetShowHelp(false) // Disable original help bar
+	diffList.Styles.Title = boxedTitleStyle
+	diffList.Styles.PaginationStyle = appTheme.paginationStyle
+	diffList.Styles.HelpStyle = appTheme.helpStyle
+	diffList.KeyMap.Quit.SetKeys("ctrl+c")
+	diffList.KeyMap.CloseFullHelp.SetKeys("q")
+
+	diffView := viewport.New(0, 0)
+	diffView.Style = lipgloss.NewStyle().Padding(1, 2)
+
+	compareView := viewport.New(0, 0)
+	compareView.Style = lipgloss.NewStyle().Padding(1, 2)
+
+	driftInput := textinput.New()
+	driftInput.Prompt = "Role name: "
+	driftInput.Placeholder = "svc-{env}-api"
+
+	driftView := viewport.New(0, 0)
+	driftView.Style = lipgloss.NewStyle().Padding(1, 2)
+
+	decodeInput := textinput.New()
+	decodeInput.Prompt = "Encoded message: "
+	decodeInput.Placeholder = "paste the message of the error"
+
+	orgList := list.New([]list.Item{}, list.NewDefaultDelegate(), 0, 0)
+	orgList.Title = "Organization Accounts"
+	orgList.SetShowStatusBar(false)
+	orgList.SetFilteringEnabled(true)
+	orgList.SetShowHelp(false) // Disable original help bar
+	orgList.Styles.Title = boxedTitleStyle
+	orgList.Styles.PaginationStyle = appTheme.paginationStyle
+	orgList.Styles.HelpStyle = appTheme.helpStyle
+	orgList.KeyMap.Quit.SetKeys("ctrl+c")
+	orgList.KeyMap.CloseFullHelp.SetKeys("q")
+
+	credentialsList := list.New([]list.Item{}, list.NewDefaultDelegate(), 0, 0)
+	credentialsList.Title = "Export Credentials"
+	credentialsList.SetShowStatusBar(false)
+	credentialsList.SetFilteringEnabled(false)
+	credentialsList.SetShowHelp(false) // Disable original help bar
+	credentialsList.Styles.Title = boxedTitleStyle
+	credentialsList.Styles.PaginationStyle = appTheme.paginationStyle
+	credentialsList.KeyMap.Quit.SetKeys("ctrl+c")
+
+	regionsList := list.New([]list.Item{}, list.NewDefaultDelegate(), 0, 0)
+	regionsList.Title = "AWS Regions"
+	regionsList.SetShowStatusBar(false)
+	regionsList.SetFilteringEnabled(true)
+	regionsList.SetShowHelp(false) // Disable original help bar
+	regionsList.Styles.Title = boxedTitleStyle
+	regionsList.Styles.PaginationStyle = appTheme.paginationStyle
+	regionsList.KeyMap.Quit.SetKeys("ctrl+c")
+
 	profilesList := list.New([]list.Item{}, list.NewDefaultDelegate(), 0, 0)
 	profilesList.Title = "AWS Profiles"
 	profilesList.SetShowStatusBar(false)
@@ -359,25 +643,55 @@ func initialModel() model {
 	profilesList.KeyMap.CloseFullHelp.SetKeys("q")
 
 	return model{
-		rolesList:     rolesList,
-		policiesList:  policiesList,
-		spinner:       s,
-		loading:       false,
-		policyView:    policyView,
-		currentScreen: "roles",
-		statusMsg:     "Select a role to view its policies",
-		profilesList:  profilesList,
+		rolesList:       rolesList,
+		policiesList:    policiesList,
+		spinner:         s,
+		policyView:      policyView,
+		currentScreen:   "roles",
+		statusMsg:       "Select a role to view its policies",
+		profilesList:    profilesList,
+		diffList:        diffList,
+		diffView:        diffView,
+		orgList:         orgList,
+		credentialsList: credentialsList,
+		regionsList:     regionsList,
+		compareView:     compareView,
+		driftInput:      driftInput,
+		driftView:       driftView,
+		decodeInput:     decodeInput,
+		screenLoads:     make(map[string]*screenLoad),
 	}
 }
 
 func (m model) Init() tea.Cmd {
 	// Set initial key bindings for the starting screen
-	updateKeyBindingsForScreen("roles")
+	updateKeyBindingsForScreen(m.currentScreen)
+	if m.offline {
+		// Offline data is already loaded, nothing to fetch
+		return m.spinner.Tick
+	}
+	if m.orgScan != nil {
+		// Accounts of the organization are scanned instead of loading the current one
+		return tea.Batch(
+			m.spinner.Tick,
+			loadCurrentProfileCmd(),
+			loadUserArnCmd(m.screenContext("roles"), m.session(), m.viewKey()),
+			orgScanCmd(m.screenContext("roles"), m.orgScan, m.session()),
+			waitForMFARequestCmd(),
+			waitForSSOEventCmd(),
+			waitForThrottleCmd(),
+		)
+	}
+
+	// Roles come from the cache first, the refresh starts once the cache was checked
 	return tea.Batch(
 		m.spinner.Tick,
 		loadCurrentProfileCmd(),
-		loadIAMRolesCmd(),
-		loadUserArnCmd(),
+		loadUserArnCmd(m.screenContext("roles"), m.session(), m.viewKey()),
+		m.cachedAccountCmd(currentProfileName()),
+		waitForMFARequestCmd(),
+		waitForSSOEventCmd(),
+		waitForThrottleCmd(),
 	)
 }
 
@@ -390,8 +704,111 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		// The MFA modal takes all typed keys, AWS requests wait for it
+		if m.currentScreen == "mfa" && msg.Type != tea.KeyCtrlC {
+			switch msg.Type {
+			case tea.KeyEsc:
+				return m, m.answerMFAPrompt(mfaReply{err: fmt.Errorf("MFA code entry cancelled")})
+			case tea.KeyEnter:
+				return m, m.submitMFACode()
+			}
+			m.mfaInput, cmd = m.mfaInput.Update(msg)
+			return m, cmd
+		}
+
+		// The SSO login modal only closes, the login goes on in the browser
+		if m.currentScreen == "sso" && msg.Type != tea.KeyCtrlC {
+			if msg.Type == tea.KeyEsc {
+				m.ssoLogin.cancel()
+				m.closeSSOLogin()
+				m.statusMsg = "Cancelling SSO login..."
+			}
+			return m, nil
+		}
+
+		// Let the active list handle typing while its filter input is open
+		if m.isFiltering() && msg.Type != tea.KeyCtrlC {
+			break
+		}
+
+		// The drift prompt takes all typed keys
+		if m.currentScreen == "drift_prompt" && msg.Type != tea.KeyCtrlC {
+			switch msg.Type {
+			case tea.KeyEsc:
+				m.driftInput.Blur()
+				m.currentScreen = "profiles"
+				updateKeyBindingsForScreen(m.currentScreen)
+				m.statusMsg = ""
+				return m, nil
+			case tea.KeyEnter:
+				pattern := strings.TrimSpace(m.driftInput.Value())
+				if pattern == "" {
+					return m, nil
+				}
+				m.driftInput.Blur()
+				return m, m.startDrift(pattern)
+			}
+			m.driftInput, cmd = m.driftInput.Update(msg)
+			return m, cmd
+		}
+
+		// The decode prompt takes all typed keys, pasted messages included
+		if m.currentScreen == "decode_prompt" && msg.Type != tea.KeyCtrlC {
+			switch msg.Type {
+			case tea.KeyEsc:
+				m.decodeInput.Blur()
+				m.currentScreen = "roles"
+				updateKeyBindingsForScreen(m.currentScreen)
+				m.statusMsg = ""
+				return m, nil
+			case tea.KeyEnter:
+				return m, m.startDecode(m.decodeInput.Value())
+			}
+			m.decodeInput, cmd = m.decodeInput.Update(msg)
+			return m, cmd
+		}
+
+		// The assume form takes all typed keys
+		if m.currentScreen == "assume" && msg.Type != tea.KeyCtrlC {
+			switch msg.Type {
+			case tea.KeyEsc:
+				m.cancelScreen("assume")
+				m.currentScreen = "roles"
+				updateKeyBindingsForScreen(m.currentScreen)
+				m.statusMsg = ""
+				return m, nil
+			case tea.KeyEnter:
+				return m, m.submitAssumeForm()
+			case tea.KeyTab, tea.KeyDown:
+				return m, m.focusAssumeInput(1)
+			case tea.KeyShiftTab, tea.KeyUp:
+				return m, m.focusAssumeInput(-1)
+			}
+			m.assumeInputs[m.assumeFocus], cmd = m.assumeInputs[m.assumeFocus].Update(msg)
+			return m, cmd
+		}
+
+		// The failure panel closes before Esc navigates, the search input keeps its keys
+		if m.failure != nil && !m.searchMode {
+			if msg.Type == tea.KeyEsc {
+				m.failure = nil
+				return m, nil
+			}
+			if key.Matches(msg, keys.Retry) {
+				return m, m.retryFailure()
+			}
+		}
+
 		// Direct check for Escape key by its type
 		if msg.Type == tea.KeyEsc {
+			// Leaving a screen cancels its requests and loads, responses of requests are dropped. The roles
+			// screen is not left: Esc cancels its requests unless it clears an applied filter, the roles list,
+			// bulk load and scans keep loading until another profile or identity is opened.
+			if m.currentScreen != "roles" {
+				m.cancelScreen(m.currentScreen)
+			} else if m.rolesList.FilterState() == list.Unfiltered {
+				m.cancelRequests(m.currentScreen)
+			}
 			if m.currentScreen == "profiles" {
 				m.currentScreen = "roles"
 				updateKeyBindingsForScreen(m.currentScreen)
@@ -408,6 +825,23 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				updateKeyBindingsForScreen(m.currentScreen)
 				m.statusMsg = ""
 				return m, nil
+			} else if m.currentScreen == "diff_detail" {
+				m.currentScreen = "diff"
+				updateKeyBindingsForScreen(m.currentScreen)
+				return m, nil
+			} else if m.currentScreen == "drift" {
+				m.currentScreen = "profiles"
+				updateKeyBindingsForScreen(m.currentScreen)
+				m.statusMsg = ""
+				return m, nil
+			} else if m.currentScreen == "regions" {
+				m.currentScreen = m.regionReturnScreen
+				updateKeyBindingsForScreen(m.currentScreen)
+				return m, nil
+			} else if m.currentScreen == "org" || m.currentScreen == "compare" || m.currentScreen == "credentials" || m.currentScreen == "decoded" {
+				m.currentScreen = "roles"
+				updateKeyBindingsForScreen(m.currentScreen)
+				return m, nil
 			}
 		}
 
@@ -416,16 +850,110 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, tea.Quit
 
 		case key.Matches(msg, keys.SwitchProfile):
+			if m.offline {
+				m.statusMsg = "Profiles are not available while browsing offline data"
+				return m, nil
+			}
 			if m.currentScreen != "profiles" {
 				m.currentScreen = "profiles"
 				updateKeyBindingsForScreen(m.currentScreen)
-				m.loading = true
 				// Ensure profiles list is properly sized
 				headerHeight := 6
 				footerHeight := 3
 				verticalMarginHeight := headerHeight + footerHeight
 				m.profilesList.SetSize(m.width, m.height-verticalMarginHeight)
-				return m, loadAWSProfilesCmd()
+				return m, m.startRequest("profiles", "Loading profiles...", func(context.Context) tea.Cmd { return loadAWSProfilesCmd() })
+			}
+
+		case key.Matches(msg, keys.SwitchRegion):
+			if m.currentScreen == "roles" || m.currentScreen == "profiles" {
+				m.openRegions()
+				return m, nil
+			}
+
+		case key.Matches(msg, keys.Mark):
+			if m.currentScreen == "roles" {
+				if selected, ok := m.rolesList.SelectedItem().(*RoleItem); ok {
+					selected.marked = !selected.marked
+					m.statusMsg = fmt.Sprintf("%d roles marked, c compares two of them", len(m.markedRoles()))
+				}
+				return m, nil
+			}
+			if m.currentScreen == "profiles" {
+				if selected, ok := m.profilesList.SelectedItem().(*ProfileItem); ok {
+					selected.marked = !selected.marked
+					m.statusMsg = fmt.Sprintf("%d profiles marked, enter loads them together", len(m.markedProfiles()))
+				}
+				return m, nil
+			}
+
+		case key.Matches(msg, keys.Compare):
+			if m.currentScreen == "roles" {
+				return m, m.openCompare()
+			}
+
+		case key.Matches(msg, keys.Drift):
+			if m.currentScreen == "profiles" {
+				return m, m.openDriftPrompt()
+			}
+
+		case key.Matches(msg, keys.Assume):
+			if m.currentScreen == "roles" {
+				return m, m.openAssumeForm()
+			}
+
+		case key.Matches(msg, keys.PopIdentity):
+			if m.currentScreen == "roles" {
+				return m, m.popIdentity()
+			}
+
+		case key.Matches(msg, keys.ExportCreds):
+			if m.currentScreen == "roles" {
+				m.openCredentialsExport()
+				return m, nil
+			}
+
+		case key.Matches(msg, keys.Decode):
+			if m.currentScreen == "roles" && m.offline {
+				m.statusMsg = "Decoding messages needs AWS, it is not available while browsing offline data"
+				return m, nil
+			}
+			if m.currentScreen == "roles" {
+				return m, m.openDecodePrompt()
+			}
+
+		case key.Matches(msg, keys.OrgScan):
+			if m.currentScreen == "roles" && m.orgScan != nil {
+				m.currentScreen = "org"
+				updateKeyBindingsForScreen(m.currentScreen)
+				return m, nil
+			}
+
+		case key.Matches(msg, keys.Refresh):
+			if (m.currentScreen == "roles" || m.currentScreen == "org") && m.orgScan != nil {
+				return m, m.startOrgScan()
+			}
+			if m.currentScreen == "roles" && len(m.multiProfiles) > 0 {
+				return m, m.startMultiAccount(m.multiProfiles)
+			}
+			if m.currentScreen == "roles" && !m.offline {
+				cmd := m.startRefresh()
+				if cmd != nil {
+					m.statusMsg = "Refreshing roles and policies..."
+				}
+				return m, cmd
+			}
+
+		case key.Matches(msg, keys.Export):
+			if m.currentScreen == "roles" && m.aggregated() {
+				m.statusMsg = "Snapshots hold a single account, switch to one profile to export"
+				return m, nil
+			}
+			if m.currentScreen == "roles" {
+				snap := m.currentSnapshot()
+				path := snapshotFileName(snap.AccountID, snap.CreatedAt)
+				m.statusMsg = fmt.Sprintf("Exporting snapshot to %s...", path)
+				return m, exportSnapshotCmd(path, snap)
 			}
 
 		case key.Matches(msg, keys.Back):
@@ -455,15 +983,22 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 				if selected, ok := m.rolesList.SelectedItem().(*RoleItem); ok {
 					m.selectedRole = selected
+					m.clearFailure("policies")
 					m.currentScreen = "policies"
 					updateKeyBindingsForScreen(m.currentScreen)
 					m.policiesList.Title = fmt.Sprintf("Policies for %s", m.selectedRole.roleName)
 					m.statusMsg = ""
 
-					if !m.selectedRole.policiesLoaded {
-						m.loading = true
-						m.statusMsg = fmt.Sprintf("Loading policies for %s...", m.selectedRole.roleName)
-						return m, loadRolePoliciesCmd(m.selectedRole.roleName)
+					if !m.selectedRole.policiesLoaded && !m.canLoad(m.selectedRole) {
+						m.policiesList.SetItems([]list.Item{})
+						m.statusMsg = fmt.Sprintf("Policies for %s are not included in this data", m.selectedRole.roleName)
+					} else if !m.selectedRole.policiesLoaded {
+						// The previous role's policies must not show while these load
+						m.policiesList.SetItems([]list.Item{})
+						session, roleName := m.roleSession(m.selectedRole), m.selectedRole.roleName
+						return m, m.startRequest("policies", fmt.Sprintf("Loading policies for %s...", roleName), func(ctx context.Context) tea.Cmd {
+							return loadRolePoliciesCmd(ctx, session, roleName)
+						})
 					} else {
 						// Update policy list with existing policies
 						items := []list.Item{}
@@ -490,6 +1025,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 				if selected, ok := m.policiesList.SelectedItem().(*PolicyItem); ok {
 					m.selectedPolicy = selected
+					m.clearFailure("policy_document")
 					m.currentScreen = "policy_document"
 					updateKeyBindingsForScreen(m.currentScreen)
 					m.statusMsg = ""
@@ -500,10 +1036,32 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.searchResults = []int{}
 					m.currentMatch = 0
 
-					if !m.selectedPolicy.documentLoaded {
-						m.loading = true
-						m.statusMsg = fmt.Sprintf("Loading policy document for %s...", m.selectedPolicy.policyName)
-						return m, loadPolicyDocumentCmd(m.selectedPolicy.policyArn)
+					if !m.selectedPolicy.documentLoaded && m.selectedPolicy.rawDocument == "" && m.account != nil {
+						// Use the bulk-loaded document when this item was loaded before it
+						if policy, ok := m.account.policies[m.selectedPolicy.policyArn]; ok {
+							m.selectedPolicy.rawDocument = policy.rawDocument
+						}
+					}
+
+					if !m.selectedPolicy.documentLoaded && m.selectedPolicy.rawDocument != "" {
+						m.setPolicyDocument(m.selectedPolicy.rawDocument)
+					} else if !m.selectedPolicy.documentLoaded && !m.canLoad(m.selectedRole) {
+						m.policyDocument = ""
+						m.policyView.SetContent("")
+						m.statusMsg = fmt.Sprintf("The document of %s is not included in this data", m.selectedPolicy.policyName)
+					} else if !m.selectedPolicy.documentLoaded {
+						m.policyDocument = ""
+						m.policyView.SetContent("")
+						session, policyArn := m.roleSession(m.selectedRole), m.selectedPolicy.policyArn
+						if m.selectedPolicy.policyType == "Inline" && m.selectedRole != nil {
+							roleName, policyName := m.selectedRole.roleName, m.selectedPolicy.policyName
+							return m, m.startRequest("policy_document", fmt.Sprintf("Loading policy document for %s...", policyName), func(ctx context.Context) tea.Cmd {
+								return loadInlinePolicyDocumentCmd(ctx, session, roleName, policyName)
+							})
+						}
+						return m, m.startRequest("policy_document", fmt.Sprintf("Loading policy document for %s...", m.selectedPolicy.policyName), func(ctx context.Context) tea.Cmd {
+							return loadPolicyDocumentCmd(ctx, session, policyArn)
+						})
 					} else {
 						m.policyDocument = m.selectedPolicy.policyDocument
 						m.policyView.SetContent(m.policyDocument)
@@ -515,16 +1073,44 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					return m, nil
 				}
 
+				if marked := m.markedProfiles(); len(marked) > 0 {
+					return m, m.startMultiAccount(marked)
+				}
 				if selected, ok := m.profilesList.SelectedItem().(*ProfileItem); ok {
-					m.currentProfile = selected.name
-					m.statusMsg = fmt.Sprintf("Switched to profile: %s", m.currentProfile)
+					return m, m.switchProfile(selected.name)
+				}
+				return m, nil
+			} else if m.currentScreen == "org" {
+				if selected, ok := m.orgList.SelectedItem().(*orgAccountItem); ok {
+					m.currentScreen = "roles"
+					updateKeyBindingsForScreen(m.currentScreen)
+					m.rolesList.SetFilterText(selected.accountID)
+				}
+				return m, nil
+			} else if m.currentScreen == "regions" {
+				if selected, ok := m.regionsList.SelectedItem().(*regionItem); ok {
+					return m, m.selectRegion(selected.code)
+				}
+				return m, nil
+			} else if m.currentScreen == "credentials" {
+				if selected, ok := m.credentialsList.SelectedItem().(*credentialFormatItem); ok {
+					return m, exportCredentialsCmd(selected.format, m.exportedIdentity())
+				}
+				return m, nil
+			} else if m.currentScreen == "diff" {
+				if selected, ok := m.diffList.SelectedItem().(*diffChange); ok {
+					m.diffSelected = selected
+					m.diffView.SetContent(selected.detail)
+					m.diffView.GotoTop()
+					m.currentScreen = "diff_detail"
+					updateKeyBindingsForScreen(m.currentScreen)
 				}
 				return m, nil
 			}
 
 		// Handle viewport-specific key bindings
 		case key.Matches(msg, keys.Search):
-			if m.currentScreen == "policy_document" && !m.searchMode {
+			if m.documentScreen() && !m.searchMode {
 				m.searchMode = true
 				m.searchQuery = ""
 				m.searchResults = []int{}
@@ -533,7 +1119,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 
 		case key.Matches(msg, keys.PageUp):
-			if m.currentScreen == "policy_document" && !m.searchMode {
+			if m.documentScreen() && !m.searchMode {
 				m.policyView.YOffset -= m.policyView.Height
 				if m.policyView.YOffset < 0 {
 					m.policyView.YOffset = 0
@@ -542,7 +1128,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 
 		case key.Matches(msg, keys.PageDown):
-			if m.currentScreen == "policy_document" && !m.searchMode {
+			if m.documentScreen() && !m.searchMode {
 				m.policyView.YOffset += m.policyView.Height
 				maxOffset := len(strings.Split(m.policyDocument, "\n")) - m.policyView.Height
 				if m.policyView.YOffset > maxOffset {
@@ -552,7 +1138,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 
 		case key.Matches(msg, keys.HalfPageUp):
-			if m.currentScreen == "policy_document" && !m.searchMode {
+			if m.documentScreen() && !m.searchMode {
 				m.policyView.YOffset -= m.policyView.Height / 2
 				if m.policyView.YOffset < 0 {
 					m.policyView.YOffset = 0
@@ -561,7 +1147,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 
 		case key.Matches(msg, keys.HalfPageDown):
-			if m.currentScreen == "policy_document" && !m.searchMode {
+			if m.documentScreen() && !m.searchMode {
 				m.policyView.YOffset += m.policyView.Height / 2
 				maxOffset := len(strings.Split(m.policyDocument, "\n")) - m.policyView.Height
 				if m.policyView.YOffset > maxOffset {
@@ -571,13 +1157,13 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 
 		case key.Matches(msg, keys.GotoTop):
-			if m.currentScreen == "policy_document" && !m.searchMode {
+			if m.documentScreen() && !m.searchMode {
 				m.policyView.YOffset = 0
 				return m, nil
 			}
 
 		case key.Matches(msg, keys.GotoBottom):
-			if m.currentScreen == "policy_document" && !m.searchMode {
+			if m.documentScreen() && !m.searchMode {
 				maxOffset := len(strings.Split(m.policyDocument, "\n")) - m.policyView.Height
 				if maxOffset < 0 {
 					maxOffset = 0
@@ -588,14 +1174,14 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		// Handle search result navigation
 		case key.Matches(msg, keys.NextMatch):
-			if m.currentScreen == "policy_document" && len(m.searchResults) > 0 {
+			if m.documentScreen() && len(m.searchResults) > 0 {
 				m.currentMatch = (m.currentMatch + 1) % len(m.searchResults)
 				m.policyView.YOffset = m.searchResults[m.currentMatch]
 				return m, nil
 			}
 
 		case key.Matches(msg, keys.PrevMatch):
-			if m.currentScreen == "policy_document" && len(m.searchResults) > 0 {
+			if m.documentScreen() && len(m.searchResults) > 0 {
 				m.currentMatch = (m.currentMatch - 1 + len(m.searchResults)) % len(m.searchResults)
 				m.policyView.YOffset = m.searchResults[m.currentMatch]
 				return m, nil
@@ -603,7 +1189,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		// Handle up/down keys for viewport
 		case key.Matches(msg, keys.Up):
-			if m.currentScreen == "policy_document" && !m.searchMode {
+			if m.documentScreen() && !m.searchMode {
 				if m.policyView.YOffset > 0 {
 					m.policyView.YOffset--
 				}
@@ -611,7 +1197,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 
 		case key.Matches(msg, keys.Down):
-			if m.currentScreen == "policy_document" && !m.searchMode {
+			if m.documentScreen() && !m.searchMode {
 				maxOffset := len(strings.Split(m.policyDocument, "\n")) - m.policyView.Height
 				if m.policyView.YOffset < maxOffset {
 					m.policyView.YOffset++
@@ -621,7 +1207,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 		// Handle search mode input
-		if m.searchMode && m.currentScreen == "policy_document" {
+		if m.searchMode && m.documentScreen() {
 			switch msg.Type {
 			case tea.KeyEsc:
 				m.searchMode = false
@@ -663,22 +1249,143 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.rolesList.SetSize(msg.Width, msg.Height-verticalMarginHeight)
 		m.policiesList.SetSize(msg.Width, msg.Height-verticalMarginHeight)
 		m.profilesList.SetSize(msg.Width, msg.Height-verticalMarginHeight)
+		m.diffList.SetSize(msg.Width, msg.Height-verticalMarginHeight)
+		m.orgList.SetSize(msg.Width, msg.Height-verticalMarginHeight)
+		m.credentialsList.SetSize(msg.Width, msg.Height-verticalMarginHeight)
+		m.regionsList.SetSize(msg.Width, msg.Height-verticalMarginHeight)
 		m.policyView.Width = msg.Width
 		m.policyView.Height = msg.Height - verticalMarginHeight
+		m.diffView.Width = msg.Width
+		m.diffView.Height = msg.Height - verticalMarginHeight - 2
+		m.compareView.Width = msg.Width
+		m.compareView.Height = msg.Height - verticalMarginHeight - 2
+		m.driftView.Width = msg.Width
+		m.driftView.Height = msg.Height - verticalMarginHeight
 
 		return m, nil
 	case rolesLoadedMsg:
-		m.loading = false
+		if m.aggregated() {
+			// A single-account refresh finished after switching to several accounts
+			return m, m.finishRefreshStep()
+		}
 		items := []list.Item{}
 		for _, role := range msg {
 			roleCopy := role // Create a copy to avoid issues with loop variables in closures
 			items = append(items, &roleCopy)
 		}
 		m.rolesList.SetItems(items)
+		// Roles listed after the bulk load finished still get its details
+		m.mergeAccountRoles()
+		return m, tea.Batch(m.finishRefreshStep(), m.startPolicyCounts())
+
+	case rolesPageMsg:
+		return m, m.addRolesPage(msg)
+
+	case accountLoadedMsg:
+		// Loads of a previous account or identity are dropped, their roles are not this account's
+		if msg.load != m.accountLoad {
+			return m, nil
+		}
+		m.stopAccountLoad()
+		if msg.err != nil {
+			// Not fatal: roles and policies are still loaded on demand
+			m.statusMsg = fmt.Sprintf("Background account load failed: %v", msg.err)
+			if m.diffPending {
+				m.diffPending = false
+				m.statusMsg = fmt.Sprintf("Cannot compare with the live account: %v", msg.err)
+			}
+			return m, m.finishRefreshStep()
+		}
+		if m.aggregated() {
+			return m, m.finishRefreshStep()
+		}
+		m.account = msg.data
+		m.mergeAccountRoles()
+		// The bulk load counted every role
+		m.stopPolicyCounts()
+		if m.diffPending {
+			m.diffPending = false
+			m.showDiff(computeDiff(m.diffBase, m.account))
+		}
+		return m, m.finishRefreshStep()
+
+	case profileRolesLoadedMsg:
+		m.addProfileRoles(msg)
+		return m, nil
+
+	case driftRoleLoadedMsg:
+		m.addDriftRole(msg)
+		return m, nil
+
+	case orgAccountsListedMsg:
+		return m, m.handleOrgProgress(msg.scan, msg)
+	case orgAccountStartedMsg:
+		return m, m.handleOrgProgress(msg.scan, msg)
+	case orgAccountScannedMsg:
+		return m, m.handleOrgProgress(msg.scan, msg)
+	case orgScanDoneMsg:
+		return m, m.handleOrgProgress(msg.scan, msg)
+
+	case policyCountMsg:
+		return m, m.handlePolicyCount(msg)
+	case policyCountsDoneMsg:
+		m.finishPolicyCounts(msg)
+		return m, nil
+
+	case throttledMsg:
+		return m, m.handleThrottled(msg)
+	case throttleClearedMsg:
+		// Rendering again removes an expired notice
+		return m, nil
+
+	case cachedAccountLoadedMsg:
+		if msg.view != m.viewKey() || m.aggregated() {
+			return m, nil
+		}
+		if msg.data == nil {
+			return m, m.startRefresh()
+		}
+		m.showAccountData(msg.data)
+		m.cachedAt = msg.savedAt
+		m.cachedAccountID = msg.accountID
+
+		// Stale data stays visible while it is refreshed, a live comparison always needs fresh data
+		if time.Since(msg.savedAt) < cacheTTL && !m.diffPending {
+			return m, nil
+		}
+		return m, m.startRefresh()
+
+	case snapshotExportedMsg:
+		if msg.err != nil {
+			m.statusMsg = fmt.Sprintf("Snapshot export failed: %v", msg.err)
+		} else {
+			m.statusMsg = fmt.Sprintf("Snapshot exported to %s", msg.path)
+		}
+		return m, nil
+
+	case credentialsExportedMsg:
+		if msg.err != nil {
+			m.statusMsg = fmt.Sprintf("Credentials export failed: %v", msg.err)
+		} else {
+			m.statusMsg = msg.text
+		}
 		return m, nil
 
+	case cacheSavedMsg:
+		if msg.err != nil {
+			m.statusMsg = fmt.Sprintf("Failed to update cache: %v", msg.err)
+		}
+		return m, nil
+
+	case requestMsg:
+		request := m.finishRequest(msg.id)
+		if request == nil {
+			return m, nil
+		}
+		return m, m.applyResponse(request, msg.msg)
+
 	case policiesLoadedMsg:
-		m.loading = false
+		m.attachBulkDocuments(msg.roleName, msg.policies)
 		items := []list.Item{}
 		for _, policy := range msg.policies {
 			policyCopy := policy // Create a copy to avoid issues with loop variables in closures
@@ -690,6 +1397,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if m.selectedRole != nil {
 			m.selectedRole.policies = msg.policies
 			m.selectedRole.policiesLoaded = true
+			m.selectedRole.policyCount = len(msg.policies)
 		}
 
 		// Clear status message so we just see the policies directly
@@ -697,58 +1405,99 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		return m, nil
 
-	case policyDocumentLoadedMsg:
-		m.loading = false
-		m.policyDocument = msg.document
+	case comparedLoadedMsg:
+		return m, m.applyCompared(msg)
 
-		// Pretty format the JSON
-		var jsonObj interface{}
-		if err := json.Unmarshal([]byte(msg.document), &jsonObj); err != nil {
-			m.policyDocument = "Error parsing JSON: " + err.Error()
-		} else {
-			prettyJSON, err := json.MarshalIndent(jsonObj, "", "  ")
-			if err != nil {
-				m.policyDocument = "Error formatting JSON: " + err.Error()
-			} else {
-				// Apply color formatting to the pretty-printed JSON
-				m.policyDocument = colorizeJSON(string(prettyJSON))
-			}
-		}
-
-		m.policyView.SetContent(m.policyDocument)
+	case policyDocumentLoadedMsg:
+		m.setPolicyDocument(msg.document)
+		return m, nil
 
-		// Update the selected policy
-		m.selectedPolicy.policyDocument = m.policyDocument
-		m.selectedPolicy.documentLoaded = true
+	case authorizationDecodedMsg:
+		m.showDecoded(msg.decoded)
 		return m, nil
 
 	case profilesLoadedMsg:
-		m.loading = false
-		m.availableProfiles = msg.profiles
-		m.currentProfile = msg.currentProfile
+		if m.sessionProfile == "" {
+			// Keep a profile picked on the profiles screen
+			m.currentProfile = msg.currentProfile
+		}
 
-		// Convert profiles to list items using ProfileItem
+		// Convert profiles to list items using ProfileItem, keeping profiles of the aggregated view marked
+		byName := make(map[string]awsProfile)
+		for _, profile := range msg.profiles {
+			byName[profile.name] = profile
+		}
+		m.availableProfiles = nil
 		items := []list.Item{}
 		for _, profile := range msg.profiles {
-			items = append(items, &ProfileItem{name: profile})
+			m.availableProfiles = append(m.availableProfiles, profile.name)
+			items = append(items, &ProfileItem{
+				name:        profile.name,
+				description: profileDescription(profile, byName),
+				marked:      slices.Contains(m.multiProfiles, profile.name),
+			})
 		}
 		m.profilesList.SetItems(items)
 		return m, nil
 
+	case ssoLoginStartedMsg:
+		m.openSSOLogin(msg.login)
+		return m, waitForSSOEventCmd()
+
+	case ssoLoginFinishedMsg:
+		return m, m.finishSSOLogin(msg)
+
+	case mfaRequestedMsg:
+		return m, m.openMFAPrompt(msg.request)
+
+	case roleAssumedMsg:
+		if errors.Is(msg.err, context.Canceled) {
+			return m, nil
+		}
+		if msg.err != nil {
+			m.statusMsg = msg.err.Error()
+			return m, nil
+		}
+		return m, m.pushIdentity(msg.identity)
+
 	case userArnLoadedMsg:
+		// The identity of a previous profile or role must not be cached with this account's roles
+		if msg.view != m.viewKey() || errors.Is(msg.err, context.Canceled) {
+			return m, nil
+		}
+		if msg.err != nil {
+			// Usually expired credentials, after logging in again everything is loaded again
+			m.showFailure(msg.err, "", func(m *model) tea.Cmd {
+				return tea.Batch(loadUserArnCmd(m.screenContext("roles"), m.session(), m.viewKey()), m.startRefresh())
+			})
+			return m, nil
+		}
 		m.userArn = msg.arn
-		return m, nil
+		m.activeRegion = msg.region
+		m.accountID = accountIDFromArn(msg.arn)
+		if m.aggregated() {
+			return m, nil
+		}
+
+		// Cached data of another account must not be shown for this identity
+		if !m.cachedAt.IsZero() && m.cachedAccountID != m.accountID {
+			m.cachedAt = time.Time{}
+			m.account = nil
+			m.stopPolicyCounts()
+			m.rolesList.SetItems([]list.Item{})
+			return m, m.startRefresh()
+		}
+		return m, m.saveCurrentCacheCmd()
 
 	case spinner.TickMsg:
 		var spinnerCmd tea.Cmd
 		m.spinner, spinnerCmd = m.spinner.Update(msg)
-		if m.loading {
+		if m.loading() {
 			return m, spinnerCmd
 		}
 
 	case errorMsg:
-		m.loading = false
-		m.err = msg
+		m.showFailure(msg, "", nil)
 		return m, nil
 	}
 
@@ -760,12 +1509,33 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case "policies":
 		m.policiesList, cmd = m.policiesList.Update(msg)
 		cmds = append(cmds, cmd)
-	case "policy_document":
+	case "policy_document", "decoded":
 		m.policyView, cmd = m.policyView.Update(msg)
 		cmds = append(cmds, cmd)
 	case "profiles":
 		m.profilesList, cmd = m.profilesList.Update(msg)
 		cmds = append(cmds, cmd)
+	case "diff":
+		m.diffList, cmd = m.diffList.Update(msg)
+		cmds = append(cmds, cmd)
+	case "org":
+		m.orgList, cmd = m.orgList.Update(msg)
+		cmds = append(cmds, cmd)
+	case "credentials":
+		m.credentialsList, cmd = m.credentialsList.Update(msg)
+		cmds = append(cmds, cmd)
+	case "regions":
+		m.regionsList, cmd = m.regionsList.Update(msg)
+		cmds = append(cmds, cmd)
+	case "compare":
+		m.compareView, cmd = m.compareView.Update(msg)
+		cmds = append(cmds, cmd)
+	case "drift":
+		m.driftView, cmd = m.driftView.Update(msg)
+		cmds = append(cmds, cmd)
+	case "diff_detail":
+		m.diffView, cmd = m.diffView.Update(msg)
+		cmds = append(cmds, cmd)
 	}
 
 	return m, tea.Batch(cmds...)
@@ -773,75 +1543,64 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 // View renders the UI based on the current state
 func (m model) View() string {
-	if m.loading {
-		return fmt.Sprintf("\n\n   %s Loading...\n\n", m.spinner.View())
-	}
-
-	if m.err != nil {
-		// Wrap error message to fit screen width, leaving some margin
-		wrappedErrorMsg := wordWrap(m.err.Error(), m.width-10)
-		return fmt.Sprintf("\n\n   Error: %s\n\n", appTheme.errorMessageStyle(wrappedErrorMsg))
-	}
-
 	// Create profile indicator for top right corner
 	profileIndicator := ""
-	if m.currentProfile != "" {
+	if m.currentProfile != "" || m.sourceLabel != "" {
 		profileStyle := lipgloss.NewStyle().
 			Background(lipgloss.Color("220")). // Yellow background
 			Foreground(lipgloss.Color("0")). // Black text
 			Bold(true).
 			Padding(0, 1)
 
-		profileText := fmt.Sprintf("Profile: %s", m.currentProfile)
+		profileText := fmt.Sprintf("Profile: %s%s%s", m.currentProfile, m.identityTrail(), m.regionText())
+		if m.sourceLabel != "" {
+			profileText = m.sourceLabel + m.regionText()
+		}
 		profileIndicator = profileStyle.Render(profileText)
+
+		// Show the cache age next to the profile while cached data is displayed
+		if !m.cachedAt.IsZero() {
+			cacheText := cacheAgeText(m.cachedAt, time.Now())
+			if m.refreshPending > 0 {
+				cacheText += ", refreshing..."
+			}
+			cacheStyle := lipgloss.NewStyle().
+				Foreground(lipgloss.Color("245")).
+				Padding(0, 1)
+			profileIndicator = cacheStyle.Render(cacheText) + profileIndicator
+		}
+		if text := m.throttleText(); text != "" {
+			throttleStyle := lipgloss.NewStyle().
+				Foreground(lipgloss.Color("214")). // Orange
+				Padding(0, 1)
+			profileIndicator = throttleStyle.Render(text) + profileIndicator
+		}
+		if m.rolesStream != nil && m.rolesStream.live {
+			streamStyle := lipgloss.NewStyle().
+				Foreground(lipgloss.Color("245")).
+				Padding(0, 1)
+			profileIndicator = streamStyle.Render(m.rolesStream.progressText()) + profileIndicator
+		}
+		if m.policyCounts != nil {
+			countStyle := lipgloss.NewStyle().
+				Foreground(lipgloss.Color("245")).
+				Padding(0, 1)
+			profileIndicator = countStyle.Render(m.policyCounts.progressText()) + profileIndicator
+		}
 	}
 
 	var view string
 
 	switch m.currentScreen {
 	case "roles":
-		// Create header with logo and profile indicator on the same line
-		logo := displayLogo()
-		header := ""
-		if profileIndicator != "" {
-			// Calculate spacing to put logo on left, profile on right
-			logoWidth := len(stripAnsiCodes(logo))
-			profileWidth := len(stripAnsiCodes(profileIndicator))
-			spacerWidth := m.width - logoWidth - profileWidth - 2
-			if spacerWidth > 0 {
-				spacer := strings.Repeat(" ", spacerWidth)
-				header = fmt.Sprintf("%s%s%s\n", logo, spacer, profileIndicator)
-			} else {
-				// Not enough space, put on separate lines
-				header = fmt.Sprintf("%s\n%s\n", logo, profileIndicator)
-			}
-		} else {
-			header = fmt.Sprintf("%s\n", logo)
-		}
+		header := m.renderHeader(profileIndicator)
 
 		view = header + "\n" + m.rolesList.View()
 		// Status message will be handled in the footer area
 
 	case "policies":
 		if m.selectedRole != nil {
-			// Create header with logo and profile indicator on the same line
-			logo := displayLogo()
-			header := ""
-			if profileIndicator != "" {
-				// Calculate spacing to put logo on left, profile on right
-				logoWidth := len(stripAnsiCodes(logo))
-				profileWidth := len(stripAnsiCodes(profileIndicator))
-				spacerWidth := m.width - logoWidth - profileWidth - 2
-				if spacerWidth > 0 {
-					spacer := strings.Repeat(" ", spacerWidth)
-					header = fmt.Sprintf("%s%s%s\n", logo, spacer, profileIndicator)
-				} else {
-					// Not enough space, put on separate lines
-					header = fmt.Sprintf("%s\n%s\n", logo, profileIndicator)
-				}
-			} else {
-				header = fmt.Sprintf("%s\n", logo)
-			}
+			header := m.renderHeader(profileIndicator)
 
 			view = header + "\n" + m.policiesList.View()
 			// Status message will be handled in the footer area
@@ -871,72 +1630,116 @@ func (m model) View() string {
 			}
 			headerStr += "\n"
 
-			// Show search input and match status if in search mode or has results
-			searchBar := ""
-			if m.searchMode {
-				searchStyle := lipgloss.NewStyle().
-					Foreground(lipgloss.Color("220")).
-					Bold(true).
-					PaddingLeft(1)
-				searchBar = "\n" + searchStyle.Render(fmt.Sprintf("Search: %s_", m.searchQuery))
-			} else if len(m.searchResults) > 0 {
-				// Show search results status
-				matchStyle := lipgloss.NewStyle().
-					Foreground(lipgloss.Color("245")).
-					PaddingLeft(1)
-				searchBar = "\n" + matchStyle.Render(fmt.Sprintf("Match %d of %d for '%s'", m.currentMatch+1, len(m.searchResults), m.searchQuery))
-			}
+			view = header + headerStr + m.renderDocument()
+		}
 
-			// Apply search highlighting if we have search results
-			content := m.policyDocument
-			if len(m.searchResults) > 0 && m.searchQuery != "" {
-				content = m.highlightSearchResults(m.policyDocument, m.searchQuery, m.currentMatch)
-			}
-			m.policyView.SetContent(content)
+	case "decode_prompt":
+		header := m.renderHeader(profileIndicator)
+		title := fmt.Sprintf("\n  %s\n\n", appTheme.policyNameHighlightStyle("Decode an authorization failure message"))
+		view = header + title + "  " + m.decodeInput.View()
 
-			view = header + headerStr + m.policyView.View() + searchBar
+	case "decoded":
+		header := m.renderHeader(profileIndicator)
+		title := fmt.Sprintf("\n  %s\n", appTheme.policyNameHighlightStyle("Decoded authorization message"))
+		for _, line := range m.decodeSummary {
+			title += appTheme.policyMetadataStyle(wordWrap(line, max(m.width-4, 20))) + "\n"
 		}
+		view = header + title + m.renderDocument()
 
 	case "profiles":
-		// Create header with logo and profile indicator on the same line
-		logo := displayLogo()
-		header := ""
-		if profileIndicator != "" {
-			// Calculate spacing to put logo on left, profile on right
-			logoWidth := len(stripAnsiCodes(logo))
-			profileWidth := len(stripAnsiCodes(profileIndicator))
-			spacerWidth := m.width - logoWidth - profileWidth - 2
-			if spacerWidth > 0 {
-				spacer := strings.Repeat(" ", spacerWidth)
-				header = fmt.Sprintf("%s%s%s\n", logo, spacer, profileIndicator)
-			} else {
-				// Not enough space, put on separate lines
-				header = fmt.Sprintf("%s\n%s\n", logo, profileIndicator)
-			}
-		} else {
-			header = fmt.Sprintf("%s\n", logo)
-		}
+		header := m.renderHeader(profileIndicator)
 
 		view = header + "\n" + m.profilesList.View()
 		// Status message will be handled in the footer area
+
+	case "diff":
+		header := m.renderHeader(profileIndicator)
+		view = header + "\n" + m.diffList.View()
+
+	case "org":
+		header := m.renderHeader(profileIndicator)
+		view = header + "\n" + m.orgList.View()
+
+	case "credentials":
+		header := m.renderHeader(profileIndicator)
+		view = header + "\n" + m.credentialsList.View()
+
+	case "regions":
+		header := m.renderHeader(profileIndicator)
+		view = header + "\n" + m.regionsList.View()
+
+	case "sso":
+		if m.ssoLogin != nil {
+			header := m.renderHeader(profileIndicator)
+			view = header + fmt.Sprintf("\n  %s\n", appTheme.policyNameHighlightStyle(m.ssoLoginText()))
+		}
+
+	case "mfa":
+		if m.mfaPrompt != nil {
+			header := m.renderHeader(profileIndicator)
+			title := fmt.Sprintf("\n  %s\n\n", appTheme.policyNameHighlightStyle(m.mfaPromptTitle()))
+			view = header + title + "  " + m.mfaInput.View()
+		}
+
+	case "drift_prompt":
+		header := m.renderHeader(profileIndicator)
+		title := fmt.Sprintf("\n  %s\n\n", appTheme.policyNameHighlightStyle(fmt.Sprintf("Check drift across %s", strings.Join(m.markedProfiles(), ", "))))
+		view = header + title + "  " + m.driftInput.View()
+
+	case "drift":
+		header := m.renderHeader(profileIndicator)
+		view = header + m.driftView.View()
+
+	case "assume":
+		if m.assumeTarget != nil {
+			header := m.renderHeader(profileIndicator)
+			title := fmt.Sprintf("\n  %s\n\n", appTheme.policyNameHighlightStyle(fmt.Sprintf("Assume %s", roleLabel(m.assumeTarget))))
+			var fields []string
+			for _, input := range m.assumeInputs {
+				fields = append(fields, "  "+input.View())
+			}
+			view = header + title + strings.Join(fields, "\n")
+		}
+
+	case "compare":
+		if m.compared[0] != nil {
+			header := m.renderHeader(profileIndicator)
+			title := fmt.Sprintf("\n  %s ↔ %s\n", appTheme.policyNameHighlightStyle(roleLabel(m.compared[0])), appTheme.policyNameHighlightStyle(roleLabel(m.compared[1])))
+			view = header + title + m.compareView.View()
+		}
+
+	case "diff_detail":
+		if m.diffSelected != nil {
+			header := m.renderHeader(profileIndicator)
+			title := fmt.Sprintf("\n  %s\n  %s\n", appTheme.policyNameHighlightStyle(m.diffSelected.subject), appTheme.policyMetadataStyle(m.diffSelected.Description()))
+			view = header + title + m.diffView.View()
+		}
 	}
 
 	// Create consistent footer with help bar and user ARN for all views
-	if m.userArn != "" {
+	if m.userArn != "" || m.offline || m.failure != nil {
 		// Add help bar above Current ARN message based on current screen
 		helpBar := ""
 		statusBar := ""
 
 		switch m.currentScreen {
-		case "policy_document":
+		case "policy_document", "decoded":
 			if m.searchMode {
 				helpBar += renderSearchHelpBar() + "\n"
 			} else {
 				helpBar += renderViewportHelpBar() + "\n"
 			}
-		case "roles", "policies", "profiles":
+		case "roles", "policies", "profiles", "diff", "org", "credentials", "regions":
 			// Show general help for list navigation
 			helpBar += renderListHelpBar(m.currentScreen) + "\n"
+		case "drift_prompt", "mfa", "decode_prompt":
+			helpBar += renderHelpBar([]key.Binding{keys.Enter, keys.Back, keys.Quit}) + "\n"
+		case "assume":
+			helpBar += renderHelpBar([]key.Binding{keys.NextField, keys.Enter, keys.Back, keys.Quit}) + "\n"
+		case "sso":
+			helpBar += renderHelpBar([]key.Binding{keys.Back, keys.Quit}) + "\n"
+		case "diff_detail", "compare", "drift":
+			helpBar += renderHelpBar([]key.Binding{keys.Up, keys.Down, keys.PageUp, keys.PageDown, keys.Back, keys.Quit}) + "\n"
 		}
 
 		// Add gap between help bar and current ARN message
@@ -944,8 +1747,16 @@ func (m model) View() string {
 			helpBar += "\n"
 		}
 
-		// Add status message if present
-		if m.statusMsg != "" {
+		// The failure panel goes above the status message
+		failurePanel := ""
+		if m.failure != nil {
+			failurePanel = m.renderFailure()
+		}
+
+		// Add the progress of the screen's request, or the status message if present
+		if text := m.requestText(); text != "" {
+			statusBar = appTheme.statusMessageStyle(text) + "\n"
+		} else if m.statusMsg != "" {
 			statusStyle := appTheme.statusMessageStyle(m.statusMsg)
 			statusBar = statusStyle + "\n"
 		}
@@ -956,6 +1767,14 @@ func (m model) View() string {
 			Padding(0, 1)
 
 		userArnText := fmt.Sprintf("Current user ARN: %s", m.userArn)
+		if m.userArn == "" && m.offline {
+			userArnText = "Current user ARN: unknown (offline data)"
+		} else if m.userArn == "" {
+			userArnText = "Current user ARN: unknown"
+		}
+		if len(m.identities) > 0 {
+			userArnText += fmt.Sprintf(" (expires %s)", m.identities[len(m.identities)-1].expiration.Local().Format("15:04"))
+		}
 		userArnDisplay := userArnStyle.Render(userArnText)
 
 		// Calculate the height of the main view content
@@ -970,21 +1789,86 @@ func (m model) View() string {
 		if statusBar != "" {
 			footerHeight += 1
 		}
+		footerHeight += strings.Count(failurePanel, "\n")
 
 		// Create padding to push footer to the bottom
 		if m.height > contentHeight+footerHeight {
 			paddingLines := m.height - contentHeight - footerHeight
 			padding := strings.Repeat("\n", paddingLines)
-			view = view + padding + statusBar + helpBar + userArnDisplay
+			view = view + padding + failurePanel + statusBar + helpBar + userArnDisplay
 		} else {
 			// If content is too tall, place at bottom anyway
-			view = view + "\n" + statusBar + helpBar + userArnDisplay
+			view = view + "\n" + failurePanel + statusBar + helpBar + userArnDisplay
 		}
 	}
 
 	return view
 }
 
+// isFiltering reports whether the list on the current screen is taking filter input
+func (m model) isFiltering() bool {
+	switch m.currentScreen {
+	case "roles":
+		return m.rolesList.FilterState() == list.Filtering
+	case "policies":
+		return m.policiesList.FilterState() == list.Filtering
+	case "profiles":
+		return m.profilesList.FilterState() == list.Filtering
+	case "diff":
+		return m.diffList.FilterState() == list.Filtering
+	case "org":
+		return m.orgList.FilterState() == list.Filtering
+	case "regions":
+		return m.regionsList.FilterState() == list.Filtering
+	}
+	return false
+}
+
+// startRefresh reloads data from AWS unless a refresh is already running
+func (m *model) startRefresh() tea.Cmd {
+	if m.refreshPending > 0 {
+		return nil
+	}
+	m.refreshPending = 2 // Roles list and bulk account details
+	return tea.Batch(m.startRolesStream(m.session()), m.startAccountLoad(m.session()))
+}
+
+// finishRefreshStep records a finished loader and caches the data once the refresh completes
+func (m *model) finishRefreshStep() tea.Cmd {
+	if m.refreshPending == 0 {
+		return nil
+	}
+	m.refreshPending--
+	if m.refreshPending > 0 {
+		return nil
+	}
+	m.cachedAt = time.Time{}
+	m.lastRefresh = time.Now()
+	if strings.HasPrefix(m.statusMsg, "Refreshing") {
+		m.statusMsg = ""
+	}
+	return m.saveCurrentCacheCmd()
+}
+
+// renderHeader puts the logo on the left and the profile indicator on the right
+func (m model) renderHeader(profileIndicator string) string {
+	logo := displayLogo()
+	if profileIndicator == "" {
+		return fmt.Sprintf("%s\n", logo)
+	}
+
+	// Calculate spacing to put logo on left, profile on right
+	logoWidth := len(stripAnsiCodes(logo))
+	profileWidth := len(stripAnsiCodes(profileIndicator))
+	spacerWidth := m.width - logoWidth - profileWidth - 2
+	if spacerWidth > 0 {
+		spacer := strings.Repeat(" ", spacerWidth)
+		return fmt.Sprintf("%s%s%s\n", logo, spacer, profileIndicator)
+	}
+	// Not enough space, put on separate lines
+	return fmt.Sprintf("%s\n%s\n", logo, profileIndicator)
+}
+
 // renderViewportHelpBar renders a help bar for viewport navigation
 func renderViewportHelpBar() string {
 	helpKeys := keys.ViewportShortHelp()
@@ -1027,11 +1911,22 @@ func renderListHelpBar(currentScreen string) string {
 	var helpKeys []key.Binding
 
 	switch currentScreen {
-	case "roles", "policies":
-		// Use the same keys that were defined in AdditionalShortHelpKeys for roles/policies, plus filter
+	case "roles":
+		// Use the same keys that were defined in AdditionalShortHelpKeys for roles, plus filter and refresh
+		helpKeys = []key.Binding{keys.Enter, keys.Filter, keys.Mark, keys.Compare, keys.Assume, keys.PopIdentity, keys.ExportCreds, keys.Decode, keys.Refresh, keys.Export, keys.SwitchProfile, keys.SwitchRegion, keys.Back}
+	case "policies":
+		// Use the same keys that were defined in AdditionalShortHelpKeys for policies, plus filter
 		helpKeys = []key.Binding{keys.Enter, keys.Filter, keys.SwitchProfile, keys.Back}
 	case "profiles":
 		// Use the same keys that were defined in AdditionalShortHelpKeys for profiles, plus filter
+		helpKeys = []key.Binding{keys.Enter, keys.Mark, keys.Drift, keys.SwitchRegion, keys.Filter, keys.Back}
+	case "diff":
+		helpKeys = []key.Binding{keys.Enter, keys.Filter}
+	case "org":
+		helpKeys = []key.Binding{keys.Enter, keys.Filter, keys.Refresh, keys.Back}
+	case "credentials":
+		helpKeys = []key.Binding{keys.Enter, keys.Back}
+	case "regions":
 		helpKeys = []key.Binding{keys.Enter, keys.Filter, keys.Back}
 	default:
 		helpKeys = []key.Binding{}
@@ -1042,9 +1937,13 @@ func renderListHelpBar(currentScreen string) string {
 	allKeys = append(allKeys, helpKeys...)
 	allKeys = append(allKeys, keys.Quit)
 
-	// Use the exact same formatting logic as the list component's help system
+	return renderHelpBar(allKeys)
+}
+
+// renderHelpBar renders key bindings in the same format as the list component's help system
+func renderHelpBar(bindings []key.Binding) string {
 	var helpStrings []string
-	for _, binding := range allKeys {
+	for _, binding := range bindings {
 		helpStrings = append(helpStrings, fmt.Sprintf("%s %s", binding.Help().Key, binding.Help().Desc))
 	}
 
@@ -1056,6 +1955,71 @@ func renderListHelpBar(currentScreen string) string {
 	return helpStyle.Render(helpText)
 }
 
+// setPolicyDocument shows a policy document and keeps it with the selected policy
+func (m *model) setPolicyDocument(document string) {
+	m.showDocument(document)
+
+	// Update the selected policy
+	if m.selectedPolicy != nil {
+		m.selectedPolicy.policyDocument = m.policyDocument
+		m.selectedPolicy.documentLoaded = true
+	}
+}
+
+// showDocument pretty-prints and colorizes a JSON document and shows it in the viewport
+func (m *model) showDocument(document string) {
+	m.policyDocument = document
+
+	// Pretty format the JSON
+	var jsonObj interface{}
+	if err := json.Unmarshal([]byte(document), &jsonObj); err != nil {
+		m.policyDocument = "Error parsing JSON: " + err.Error()
+	} else {
+		prettyJSON, err := json.MarshalIndent(jsonObj, "", "  ")
+		if err != nil {
+			m.policyDocument = "Error formatting JSON: " + err.Error()
+		} else {
+			// Apply color formatting to the pretty-printed JSON
+			m.policyDocument = colorizeJSON(string(prettyJSON))
+		}
+	}
+
+	m.policyView.SetContent(m.policyDocument)
+}
+
+// documentScreen reports whether the current screen shows policyView, which scrolls and searches the same way
+func (m model) documentScreen() bool {
+	return m.currentScreen == "policy_document" || m.currentScreen == "decoded"
+}
+
+// renderDocument renders policyView with search highlighting and the search bar
+func (m model) renderDocument() string {
+	// Show search input and match status if in search mode or has results
+	searchBar := ""
+	if m.searchMode {
+		searchStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("220")).
+			Bold(true).
+			PaddingLeft(1)
+		searchBar = "\n" + searchStyle.Render(fmt.Sprintf("Search: %s_", m.searchQuery))
+	} else if len(m.searchResults) > 0 {
+		// Show search results status
+		matchStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("245")).
+			PaddingLeft(1)
+		searchBar = "\n" + matchStyle.Render(fmt.Sprintf("Match %d of %d for '%s'", m.currentMatch+1, len(m.searchResults), m.searchQuery))
+	}
+
+	// Apply search highlighting if we have search results
+	content := m.policyDocument
+	if len(m.searchResults) > 0 && m.searchQuery != "" {
+		content = m.highlightSearchResults(m.policyDocument, m.searchQuery, m.currentMatch)
+	}
+	m.policyView.SetContent(content)
+
+	return m.policyView.View() + searchBar
+}
+
 // performSearch searches for the query in the policy document and stores line numbers with matches
 func (m *model) performSearch() {
 	if m.searchQuery == "" {
@@ -1139,96 +2103,87 @@ type policyDocumentLoadedMsg struct {
 }
 
 type profilesLoadedMsg struct {
-	profiles       []string
+	profiles       []awsProfile
 	currentProfile string
 }
 
 type userArnLoadedMsg struct {
-	arn string
+	view   string // Key of the account view the identity was loaded for
+	arn    string
+	region string // Region the identity was loaded in
+	err    error
 }
 
 type errorMsg error
 
-// Load IAM roles from AWS
-func loadIAMRolesCmd() tea.Cmd {
-	return func() tea.Msg {
-		ctx := context.Background()
-
-		// Load AWS configuration with shared config
-		cfg, err := config.LoadDefaultConfig(ctx)
+// listRoles lists all IAM roles of an account
+func listRoles(ctx context.Context, iamClient iamAPI) ([]RoleItem, error) {
+	var roles []RoleItem
+	paginator := iam.NewListRolesPaginator(iamClient, &iam.ListRolesInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
 		if err != nil {
-			return errorMsg(fmt.Errorf("error loading AWS configuration: %w", err))
+			return nil, fmt.Errorf("error listing IAM roles: %w", err)
 		}
+		roles = append(roles, roleItems(page.Roles)...)
+	}
+	return roles, nil
+}
 
-		// Create clients
-		iamClient := iam.NewFromConfig(cfg)
-
-		// List IAM roles
-		var roles []RoleItem
-		paginator := iam.NewListRolesPaginator(iamClient, &iam.ListRolesInput{})
-		for paginator.HasMorePages() {
-			page, err := paginator.NextPage(ctx)
-			if err != nil {
-				return errorMsg(fmt.Errorf("error listing IAM roles: %w", err))
-			}
-
-			for _, role := range page.Roles {
-				description := fmt.Sprintf("ARN: %s", *role.Arn)
-				if role.Description != nil {
-					description = aws.ToString(role.Description)
-				}
-
-				roles = append(roles, RoleItem{
-					roleName:    aws.ToString(role.RoleName),
-					roleArn:     aws.ToString(role.Arn),
-					description: description,
-				})
-			}
+// roleItems converts a page of listed roles to list items
+func roleItems(page []types.Role) []RoleItem {
+	var roles []RoleItem
+	for _, role := range page {
+		description := fmt.Sprintf("ARN: %s", *role.Arn)
+		if role.Description != nil {
+			description = aws.ToString(role.Description)
 		}
 
-		return rolesLoadedMsg(roles)
+		roles = append(roles, RoleItem{
+			roleName:           aws.ToString(role.RoleName),
+			roleArn:            aws.ToString(role.Arn),
+			description:        description,
+			maxSessionDuration: aws.ToInt32(role.MaxSessionDuration),
+		})
 	}
+	return roles
 }
 
-// Load current user ARN
-func loadUserArnCmd() tea.Cmd {
+// Load current user ARN, tagged with the key of the account view it is loaded for
+func loadUserArnCmd(ctx context.Context, session awsSession, view string) tea.Cmd {
 	return func() tea.Msg {
-		ctx := context.Background()
-
 		// Load AWS configuration with shared config
-		cfg, err := config.LoadDefaultConfig(ctx)
+		cfg, err := session.loadConfig(ctx)
 		if err != nil {
-			return errorMsg(fmt.Errorf("error loading AWS configuration: %w", err))
+			return userArnLoadedMsg{view: view, err: fmt.Errorf("error loading AWS configuration: %w", err)}
 		}
 
 		// Create STS client
-		stsClient := sts.NewFromConfig(cfg)
+		stsClient := session.stsClient(cfg)
 
 		// Get caller identity to determine current user/role
 		identity, err := stsClient.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
 		if err != nil {
-			return errorMsg(fmt.Errorf("error getting caller identity: %w", err))
+			return userArnLoadedMsg{view: view, err: fmt.Errorf("error getting caller identity: %w", err)}
 		}
 
 		userArn := aws.ToString(identity.Arn)
-		return userArnLoadedMsg{arn: userArn}
+		return userArnLoadedMsg{view: view, arn: userArn, region: cfg.Region}
 	}
 }
 
-// Load policies attached to a role
-func loadRolePoliciesCmd(roleName string) tea.Cmd {
+// Load the managed and inline policies of a role, stopping when ctx is cancelled
+func loadRolePoliciesCmd(ctx context.Context, session awsSession, roleName string) tea.Cmd {
 	return func() tea.Msg {
-		ctx := context.Background()
-
 		// Load AWS configuration
-		cfg, err := config.LoadDefaultConfig(ctx)
+		cfg, err := session.loadConfig(ctx)
 		if err != nil {
 			fmt.Printf("Error loading AWS configuration: %v\n", err)
 			return errorMsg(fmt.Errorf("error loading AWS configuration: %w", err))
 		}
 
 		// Create IAM client
-		iamClient := iam.NewFromConfig(cfg)
+		iamClient := session.iamClient(cfg)
 
 		// Debug info
 		fmt.Printf("Fetching policies for role: %s\n", roleName)
@@ -1250,12 +2205,7 @@ func loadRolePoliciesCmd(roleName string) tea.Cmd {
 
 			for _, policy := range page.AttachedPolicies {
 				policyArn := aws.ToString(policy.PolicyArn)
-				policyType := "Customer"
-
-				// Check if it's an AWS managed policy
-				if strings.Contains(policyArn, "arn:aws:iam::aws:") {
-					policyType = "AWS"
-				}
+				policyType := managedPolicyType(policyArn)
 
 				policies = append(policies, PolicyItem{
 					policyName: aws.ToString(policy.PolicyName),
@@ -1267,6 +2217,18 @@ func loadRolePoliciesCmd(roleName string) tea.Cmd {
 			}
 		}
 
+		// Inline policies are listed by name, their documents are loaded when opened unless the bulk load has them
+		inlinePages := iam.NewListRolePoliciesPaginator(iamClient, &iam.ListRolePoliciesInput{RoleName: aws.String(roleName)})
+		for inlinePages.HasMorePages() {
+			page, err := inlinePages.NextPage(ctx)
+			if err != nil {
+				return errorMsg(fmt.Errorf("error listing inline policies for role %s: %w", roleName, err))
+			}
+			for _, policyName := range page.PolicyNames {
+				policies = append(policies, PolicyItem{policyName: policyName, policyType: "Inline"})
+			}
+		}
+
 		fmt.Printf("Total policies found for role %s: %d\n", roleName, len(policies))
 
 		return policiesLoadedMsg{
@@ -1276,19 +2238,17 @@ func loadRolePoliciesCmd(roleName string) tea.Cmd {
 	}
 }
 
-// Load policy document
-func loadPolicyDocumentCmd(policyArn string) tea.Cmd {
+// Load policy document, stopping when ctx is cancelled
+func loadPolicyDocumentCmd(ctx context.Context, session awsSession, policyArn string) tea.Cmd {
 	return func() tea.Msg {
-		ctx := context.Background()
-
 		// Load AWS configuration
-		cfg, err := config.LoadDefaultConfig(ctx)
+		cfg, err := session.loadConfig(ctx)
 		if err != nil {
 			return errorMsg(fmt.Errorf("error loading AWS configuration: %w", err))
 		}
 
 		// Create IAM client
-		iamClient := iam.NewFromConfig(cfg)
+		iamClient := session.iamClient(cfg)
 
 		// Get policy version
 		policyResp, err := iamClient.GetPolicy(ctx, &iam.GetPolicyInput{
@@ -1320,6 +2280,34 @@ func loadPolicyDocumentCmd(policyArn string) tea.Cmd {
 	}
 }
 
+// managedPolicyType tells AWS managed policies apart from customer managed ones by ARN
+func managedPolicyType(policyArn string) string {
+	if strings.Contains(policyArn, "arn:aws:iam::aws:") {
+		return "AWS"
+	}
+	return "Customer"
+}
+
+// Load the document of a role's inline policy, stopping when ctx is cancelled
+func loadInlinePolicyDocumentCmd(ctx context.Context, session awsSession, roleName, policyName string) tea.Cmd {
+	return func() tea.Msg {
+		cfg, err := session.loadConfig(ctx)
+		if err != nil {
+			return errorMsg(fmt.Errorf("error loading AWS configuration: %w", err))
+		}
+
+		policy, err := session.iamClient(cfg).GetRolePolicy(ctx, &iam.GetRolePolicyInput{RoleName: aws.String(roleName), PolicyName: aws.String(policyName)})
+		if err != nil {
+			return errorMsg(fmt.Errorf("error getting inline policy %s: %w", policyName, err))
+		}
+		doc, err := decodeURLEncodedDocument(aws.ToString(policy.PolicyDocument))
+		if err != nil {
+			return errorMsg(fmt.Errorf("error decoding inline policy %s: %w", policyName, err))
+		}
+		return policyDocumentLoadedMsg{document: doc}
+	}
+}
+
 // Decode URL-encoded JSON policy document
 func decodeURLEncodedDocument(encoded string) (string, error) {
 	decoded, err := url.QueryUnescape(encoded)
@@ -1419,19 +2407,136 @@ func displayLogo() string {
 }
 
 func main() {
+	snapshotPath := flag.String("snapshot", "", "browse an exported snapshot `file` without AWS credentials")
+	diffPath := flag.String("diff", "", "compare a snapshot `file` with the snapshot given as argument, or with the live account")
+	authzPath := flag.String("from-authz-details", "", "browse the output of `aws iam get-account-authorization-details` saved in `file`")
+	orgScanFlag := flag.Bool("org-scan", false, "scan every account of the organization by assuming a role in each")
+	orgRoleFlag := flag.String("org-role", "", "`role` name assumed in member accounts by --org-scan")
+	flag.StringVar(&endpointOverride, "endpoint-url", "", "send all AWS requests to `url`, e.g. LocalStack or a VPC endpoint")
+	fakeFlag := flag.Bool("fake", false, "browse an in-memory fake account instead of AWS, for development")
+	demoFlag := flag.Bool("demo", false, "browse a generated demo account with thousands of roles instead of AWS")
+	demoRoles := flag.Int("demo-roles", demoRoleCount, "number of `roles` of the --demo account")
+	recordDir := flag.String("record", "", "record every AWS request and response to `dir`, without credentials")
+	replayDir := flag.String("replay", "", "serve the AWS responses recorded in `dir` instead of calling AWS")
+	flag.Parse()
+
+	if err := checkSourceFlags(setFlags(), flag.Arg(0)); err != nil {
+		fmt.Fprintf(os.Stderr, "atui: %v\n", err)
+		flag.Usage()
+		os.Exit(2)
+	}
+
 	// Load the color theme from the config file
 	var err error
 	appTheme, err = loadThemeFromConfig()
 	if err != nil {
 		log.Fatalf("error loading theme from config: %v", err)
 	}
+	orgRole, orgConcurrency := appconfig.DefaultConfig.OrgScanRoleName(), appconfig.DefaultConfig.OrgScanConcurrency()
+	if cfg, err := appconfig.Load(); err == nil {
+		cacheTTL = cfg.CacheTTL()
+		orgRole, orgConcurrency = cfg.OrgScanRoleName(), cfg.OrgScanConcurrency()
+		endpointConfig = cfg
+		driftEnvironment = cfg.DriftEnvironment
+		apiCalls = newCallLayer(cfg.CallTimeout(), cfg.MaxAttempts(), cfg.RequestRates())
+	}
+	if *orgRoleFlag != "" {
+		orgRole = *orgRoleFlag
+	}
 
-	p := tea.NewProgram(initialModel(), tea.WithAltScreen())
+	m := initialModel()
+	if *fakeFlag {
+		m.backend = newSampleFakeBackend()
+	}
+	if *demoFlag {
+		m.backend = newDemoBackend(*demoRoles, 1)
+	}
+	if *recordDir != "" {
+		backend, err := newRecordBackend(*recordDir)
+		if err != nil {
+			log.Fatalf("error starting recording: %v", err)
+		}
+		m.backend = backend
+	}
+	if *replayDir != "" {
+		backend, err := newReplayBackend(*replayDir)
+		if err != nil {
+			log.Fatalf("error opening recording: %v", err)
+		}
+		m.backend = backend
+	}
+	if *snapshotPath != "" {
+		snap, err := readSnapshot(*snapshotPath)
+		if err != nil {
+			log.Fatalf("error opening snapshot: %v", err)
+		}
+		m.openSnapshot(*snapshotPath, snap)
+	}
+	if *authzPath != "" {
+		data, err := readAuthzDetails(*authzPath)
+		if err != nil {
+			log.Fatalf("error opening authorization details: %v", err)
+		}
+		m.openAuthzDetails(*authzPath, data)
+	}
+	if *orgScanFlag {
+		m.openOrgScan(orgRole, orgConcurrency)
+	}
+	if *diffPath != "" {
+		if err := m.openDiff(*diffPath, flag.Arg(0)); err != nil {
+			log.Fatalf("error opening snapshots to compare: %v", err)
+		}
+	}
+
+	p := tea.NewProgram(m, tea.WithAltScreen())
 	if _, err := p.Run(); err != nil {
 		log.Fatal(err)
 	}
 }
 
+// setFlags returns the names of the flags given on the command line with a non-empty value
+func setFlags() []string {
+	var names []string
+	flag.Visit(func(f *flag.Flag) {
+		if value := f.Value.String(); value != "" && value != "false" {
+			names = append(names, f.Name)
+		}
+	})
+	return names
+}
+
+// checkSourceFlags rejects flags that select different data sources, since only one of them can be browsed.
+// --fake, --demo, --record and --replay choose the backend the live account is loaded from, --snapshot and
+// --from-authz-details replace the live account with a file, and --diff compares a snapshot with the live
+// account, or with the snapshot given as diffWith.
+func checkSourceFlags(flags []string, diffWith string) error {
+	var backend, source string
+	for _, name := range flags {
+		switch name {
+		case "fake", "demo", "record", "replay":
+			if backend != "" {
+				return fmt.Errorf("--%s cannot be combined with --%s", name, backend)
+			}
+			backend = name
+		case "snapshot", "from-authz-details", "diff":
+			if source != "" {
+				return fmt.Errorf("--%s cannot be combined with --%s", name, source)
+			}
+			source = name
+		}
+	}
+	if backend == "" || source == "" {
+		return nil
+	}
+	if source == "diff" && diffWith == "" {
+		return nil
+	}
+	if source == "diff" {
+		return fmt.Errorf("--diff with two snapshots cannot be combined with --%s", backend)
+	}
+	return fmt.Errorf("--%s cannot be combined with --%s", source, backend)
+}
+
 // loadThemeFromConfig loads the color theme from the config package
 func loadThemeFromConfig() (Theme, error) {
 	var theme Theme
@@ -1506,81 +2611,23 @@ func loadThemeFromConfig() (Theme, error) {
 	return theme, nil
 }
 
-// Load AWS profiles from config files
-func loadAWSProfilesCmd() tea.Cmd {
-	return func() tea.Msg {
-		// Get home directory
-		homeDir, err := os.UserHomeDir()
-		if err != nil {
-			return errorMsg(fmt.Errorf("error getting home directory: %w", err))
-		}
-
-		// Read AWS config file
-		configPath := filepath.Join(homeDir, ".aws", "config")
-		credentialsPath := filepath.Join(homeDir, ".aws", "credentials")
-
-		profiles := make(map[string]bool)
-
-		// Parse config file
-		if file, err := os.Open(configPath); err == nil {
-			defer func() { _ = file.Close() }()
-			scanner := bufio.NewScanner(file)
-			for scanner.Scan() {
-				line := strings.TrimSpace(scanner.Text())
-				if strings.HasPrefix(line, "[profile ") && strings.HasSuffix(line, "]") {
-					profileName := strings.TrimPrefix(line, "[profile ")
-					profileName = strings.TrimSuffix(profileName, "]")
-					profiles[profileName] = true
-				} else if line == "[default]" {
-					profiles["default"] = true
-				}
-			}
-		}
-
-		// Parse credentials file
-		if file, err := os.Open(credentialsPath); err == nil {
-			defer func() { _ = file.Close() }()
-			scanner := bufio.NewScanner(file)
-			for scanner.Scan() {
-				line := strings.TrimSpace(scanner.Text())
-				if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
-					profileName := strings.TrimPrefix(line, "[")
-					profileName = strings.TrimSuffix(profileName, "]")
-					profiles[profileName] = true
-				}
-			}
-		}
-
-		// Convert map to slice
-		var profileList []string
-		for profile := range profiles {
-			profileList = append(profileList, profile)
-		}
-
-		// Get current profile from environment or default
-		currentProfile := os.Getenv("AWS_PROFILE")
-		if currentProfile == "" {
-			currentProfile = "default"
-		}
-
-		return profilesLoadedMsg{
-			profiles:       profileList,
-			currentProfile: currentProfile,
-		}
+// currentProfileName returns the profile selected by the environment
+func currentProfileName() string {
+	currentProfile := os.Getenv("AWS_PROFILE")
+	if currentProfile == "" {
+		currentProfile = "default"
 	}
+	return currentProfile
 }
 
 // Load current AWS profile
 func loadCurrentProfileCmd() tea.Cmd {
 	return func() tea.Msg {
 		// Get current profile from environment
-		currentProfile := os.Getenv("AWS_PROFILE")
-		if currentProfile == "" {
-			currentProfile = "default"
-		}
+		currentProfile := currentProfileName()
 
 		return profilesLoadedMsg{
-			profiles:       []string{}, // Empty list, we just set the current profile
+			profiles:       []awsProfile{}, // Empty list, we just set the current profile
 			currentProfile: currentProfile,
 		}
 	}
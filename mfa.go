@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// mfaRequest asks the TUI for the code of an MFA device
+type mfaRequest struct {
+	profile string
+	serial  string
+	reply   chan mfaReply
+}
+
+// mfaReply is the code typed for an MFA request, or why there is none
+type mfaReply struct {
+	code string
+	err  error
+}
+
+// mfaRequestedMsg is sent when credentials of a profile need an MFA code
+type mfaRequestedMsg struct {
+	request mfaRequest
+}
+
+var (
+	// mfaRequests carries MFA prompts from credential providers to the TUI
+	mfaRequests = make(chan mfaRequest)
+
+	// configMu serializes loading AWS configurations and guards mfaSessions
+	configMu sync.Mutex
+	// mfaSessions holds the credentials of MFA profiles by profile name until the program exits
+	mfaSessions = make(map[string]aws.CredentialsProvider)
+)
+
+// mfaTokenProvider returns a token provider that asks the TUI for a code and waits for it
+func mfaTokenProvider(profile, serial string) func() (string, error) {
+	return func() (string, error) {
+		reply := make(chan mfaReply, 1)
+		mfaRequests <- mfaRequest{profile: profile, serial: serial, reply: reply}
+		answer := <-reply
+		return answer.code, answer.err
+	}
+}
+
+// Wait for the next MFA prompt of a credential provider
+func waitForMFARequestCmd() tea.Cmd {
+	return func() tea.Msg {
+		return mfaRequestedMsg{request: <-mfaRequests}
+	}
+}
+
+// mfaSessionProvider returns the cached session credentials of a profile that requires MFA, or nil for other profiles.
+// Profiles assuming a role get their code through the SDK's assume role provider, others through GetSessionToken.
+func mfaSessionProvider(cfg aws.Config, profile string) aws.CredentialsProvider {
+	shared := sharedConfigOf(cfg)
+	if shared.MFASerial == "" {
+		return nil
+	}
+	if shared.RoleARN != "" {
+		// Already cached by the SDK, sharing it keeps the assumed session
+		return cfg.Credentials
+	}
+	return aws.NewCredentialsCache(&sessionTokenProvider{
+		client:        newSTSClient(cfg),
+		serial:        shared.MFASerial,
+		tokenProvider: mfaTokenProvider(profile, shared.MFASerial),
+	})
+}
+
+// sessionTokenProvider exchanges long-term credentials and an MFA code for session credentials
+type sessionTokenProvider struct {
+	client        stsAPI
+	serial        string
+	tokenProvider func() (string, error)
+}
+
+// Retrieve asks for an MFA code and gets a session token with it
+func (p *sessionTokenProvider) Retrieve(ctx context.Context) (aws.Credentials, error) {
+	code, err := p.tokenProvider()
+	if err != nil {
+		return aws.Credentials{}, err
+	}
+	result, err := p.client.GetSessionToken(ctx, &sts.GetSessionTokenInput{
+		SerialNumber: aws.String(p.serial),
+		TokenCode:    aws.String(code),
+	})
+	if err != nil {
+		return aws.Credentials{}, fmt.Errorf("error getting MFA session token: %w", err)
+	}
+	return aws.Credentials{
+		AccessKeyID:     aws.ToString(result.Credentials.AccessKeyId),
+		SecretAccessKey: aws.ToString(result.Credentials.SecretAccessKey),
+		SessionToken:    aws.ToString(result.Credentials.SessionToken),
+		Source:          "GetSessionToken",
+		CanExpire:       true,
+		Expires:         aws.ToTime(result.Credentials.Expiration),
+	}, nil
+}
+
+// openMFAPrompt shows the MFA modal over the current screen
+func (m *model) openMFAPrompt(request mfaRequest) tea.Cmd {
+	m.mfaPrompt = &request
+	m.mfaReturnScreen = m.currentScreen
+	m.mfaInput = textinput.New()
+	m.mfaInput.Prompt = "MFA code: "
+	m.mfaInput.Placeholder = "6 digits"
+	m.mfaInput.CharLimit = 6
+	m.currentScreen = "mfa"
+	updateKeyBindingsForScreen(m.currentScreen)
+	return m.mfaInput.Focus()
+}
+
+// answerMFAPrompt replies to the open MFA prompt, returns to the previous screen and waits for the next prompt
+func (m *model) answerMFAPrompt(reply mfaReply) tea.Cmd {
+	m.mfaPrompt.reply <- reply
+	m.mfaPrompt = nil
+	m.currentScreen = m.mfaReturnScreen
+	updateKeyBindingsForScreen(m.currentScreen)
+	if reply.err != nil {
+		m.statusMsg = reply.err.Error()
+	} else {
+		m.statusMsg = "Checking MFA code..."
+	}
+	return waitForMFARequestCmd()
+}
+
+// submitMFACode answers the open MFA prompt with the typed code
+func (m *model) submitMFACode() tea.Cmd {
+	code := strings.TrimSpace(m.mfaInput.Value())
+	if code == "" {
+		return nil
+	}
+	return m.answerMFAPrompt(mfaReply{code: code})
+}
+
+// mfaPromptTitle describes the device the open MFA prompt asks for
+func (m model) mfaPromptTitle() string {
+	profile := m.mfaPrompt.profile
+	if profile == "" {
+		profile = currentProfileName()
+	}
+	return fmt.Sprintf("Profile %s requires an MFA code from %s", profile, m.mfaPrompt.serial)
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Test answering an MFA prompt of a credential provider
+func TestMFAPrompt(t *testing.T) {
+	m := createTestModel()
+	m.currentScreen = "policies"
+
+	type result struct {
+		code string
+		err  error
+	}
+	results := make(chan result)
+	go func() {
+		code, err := mfaTokenProvider("prod", "arn:aws:iam::111111111111:mfa/alice")()
+		results <- result{code, err}
+	}()
+
+	newModel, _ := m.Update(waitForMFARequestCmd()())
+	m = newModel.(model)
+	if m.currentScreen != "mfa" || !strings.Contains(m.mfaPromptTitle(), "prod requires an MFA code from arn:aws:iam::111111111111:mfa/alice") {
+		t.Fatalf("Expected the MFA modal, got screen '%s'", m.currentScreen)
+	}
+
+	// Typed keys go to the code, not to key bindings
+	newModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("123456q")})
+	m = newModel.(model)
+	newModel, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = newModel.(model)
+
+	answer := <-results
+	if answer.err != nil || answer.code != "123456" {
+		t.Errorf("Expected the typed code, got '%s', %v", answer.code, answer.err)
+	}
+	if cmd == nil || m.currentScreen != "policies" || m.mfaPrompt != nil {
+		t.Errorf("Expected to return to the previous screen and wait for the next prompt")
+	}
+}
+
+// Test cancelling an MFA prompt
+func TestMFAPromptCancelled(t *testing.T) {
+	m := createTestModel()
+
+	errs := make(chan error)
+	go func() {
+		_, err := mfaTokenProvider("", "serial")()
+		errs <- err
+	}()
+
+	newModel, _ := m.Update(waitForMFARequestCmd()())
+	newModel, _ = newModel.(model).Update(tea.KeyMsg{Type: tea.KeyEsc})
+	m = newModel.(model)
+
+	if err := <-errs; err == nil || !strings.Contains(err.Error(), "cancelled") {
+		t.Errorf("Expected the provider to fail, got %v", err)
+	}
+	if m.currentScreen != "roles" || !strings.Contains(m.statusMsg, "cancelled") {
+		t.Errorf("Expected to return to the roles screen, got '%s'", m.currentScreen)
+	}
+}
+
+// Test that profiles requiring MFA share one session
+func TestMFASessionCache(t *testing.T) {
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "config")
+	credentialsFile := filepath.Join(dir, "credentials")
+	config := "[profile mfa-user]\nmfa_serial = arn:aws:iam::111111111111:mfa/alice\nregion = eu-west-1\n\n[profile plain]\nregion = eu-west-1\n\n[profile mfa-role]\nrole_arn = arn:aws:iam::222222222222:role/Admin\nsource_profile = plain\nmfa_serial = arn:aws:iam::111111111111:mfa/alice\n"
+	creds := "[mfa-user]\naws_access_key_id = AKIA1\naws_secret_access_key = s1\n\n[plain]\naws_access_key_id = AKIA2\naws_secret_access_key = s2\n"
+	if err := os.WriteFile(configFile, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(credentialsFile, []byte(creds), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AWS_CONFIG_FILE", configFile)
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", credentialsFile)
+
+	first, err := loadAWSConfig(context.Background(), "mfa-user", "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	second, _ := loadAWSConfig(context.Background(), "mfa-user", "")
+	if first.Credentials != second.Credentials {
+		t.Errorf("Expected loaders of an MFA profile to share credentials")
+	}
+	t.Cleanup(func() { delete(mfaSessions, "mfa-user") })
+
+	// Assumed roles get the code through the SDK's token provider
+	if _, err := loadAWSConfig(context.Background(), "mfa-role", ""); err != nil {
+		t.Fatalf("Expected a token provider for the role, got %v", err)
+	}
+	if _, cached := mfaSessions["mfa-role"]; !cached {
+		t.Errorf("Expected the role session to be cached")
+	}
+	t.Cleanup(func() { delete(mfaSessions, "mfa-role") })
+
+	if _, err := loadAWSConfig(context.Background(), "plain", ""); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, cached := mfaSessions["plain"]; cached {
+		t.Errorf("Expected profiles without MFA not to be cached")
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// profileRolesLoadedMsg is sent when the roles of one profile in the aggregated view are loaded
+type profileRolesLoadedMsg struct {
+	profile string
+	roles   []RoleItem
+	err     error
+}
+
+// accountLabel names the account of a role by alias and ID
+func (i RoleItem) accountLabel() string {
+	if i.accountAlias == "" {
+		return i.accountID
+	}
+	return fmt.Sprintf("%s (%s)", i.accountAlias, i.accountID)
+}
+
+// Load the roles of a profile tagged with its account
+func loadProfileRolesCmd(ctx context.Context, session awsSession) tea.Cmd {
+	profile := session.profile
+	return func() tea.Msg {
+		cfg, err := session.loadConfig(ctx)
+		if err != nil {
+			return profileRolesLoadedMsg{profile: profile, err: fmt.Errorf("error loading AWS configuration: %w", err)}
+		}
+
+		identity, err := session.stsClient(cfg).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+		if err != nil {
+			return profileRolesLoadedMsg{profile: profile, err: fmt.Errorf("error getting caller identity: %w", err)}
+		}
+
+		iamClient := session.iamClient(cfg)
+
+		// The alias is optional, listing it is often not allowed
+		alias := ""
+		if aliases, err := iamClient.ListAccountAliases(ctx, &iam.ListAccountAliasesInput{}); err == nil && len(aliases.AccountAliases) > 0 {
+			alias = aliases.AccountAliases[0]
+		}
+
+		roles, err := listRoles(ctx, iamClient)
+		if err != nil {
+			return profileRolesLoadedMsg{profile: profile, err: err}
+		}
+		for i := range roles {
+			roles[i].profile = profile
+			roles[i].accountID = aws.ToString(identity.Account)
+			roles[i].accountAlias = alias
+		}
+		return profileRolesLoadedMsg{profile: profile, roles: roles}
+	}
+}
+
+// markedProfiles returns the profiles marked on the profiles screen
+func (m model) markedProfiles() []string {
+	var profiles []string
+	for _, item := range m.profilesList.Items() {
+		if profile, ok := item.(*ProfileItem); ok && profile.marked {
+			profiles = append(profiles, profile.name)
+		}
+	}
+	return profiles
+}
+
+// aggregated reports whether roles of several accounts are shown instead of the current account
+func (m model) aggregated() bool {
+	return len(m.multiProfiles) > 0 || m.orgScan != nil
+}
+
+// canLoad reports whether details of a role can be requested from AWS
+func (m model) canLoad(role *RoleItem) bool {
+	// Roles found by an organization scan have no profile to load with
+	return !m.offline && (role == nil || role.accountID == "" || role.profile != "")
+}
+
+// roleSession returns the session to load a role's details with
+func (m model) roleSession(role *RoleItem) awsSession {
+	if role != nil && role.profile != "" {
+		return m.profileSession(role.profile)
+	}
+	return m.session()
+}
+
+// startMultiAccount loads the roles of several profiles in parallel into one list
+func (m *model) startMultiAccount(profiles []string) tea.Cmd {
+	m.multiProfiles = profiles
+	m.orgScan = nil
+	m.multiPending = len(profiles)
+	m.multiErrors = nil
+	m.sourceLabel = multiProfileLabel(profiles)
+	m.account = nil
+	m.cachedAt = time.Time{}
+	m.stopPolicyCounts()
+	m.rolesList.SetItems([]list.Item{})
+	m.currentScreen = "roles"
+	updateKeyBindingsForScreen(m.currentScreen)
+	m.statusMsg = fmt.Sprintf("Loading roles from %d profiles...", len(profiles))
+
+	var cmds []tea.Cmd
+	for _, profile := range profiles {
+		cmds = append(cmds, loadProfileRolesCmd(m.screenContext("roles"), m.profileSession(profile)))
+	}
+	return tea.Batch(cmds...)
+}
+
+// addProfileRoles adds the roles of one profile to the aggregated list
+func (m *model) addProfileRoles(msg profileRolesLoadedMsg) {
+	if !slices.Contains(m.multiProfiles, msg.profile) {
+		return // Left the aggregated view or dropped the profile
+	}
+	if m.multiPending > 0 {
+		m.multiPending--
+	}
+
+	if msg.err != nil {
+		m.multiErrors = append(m.multiErrors, fmt.Sprintf("%s: %v", msg.profile, msg.err))
+	} else {
+		// Replace roles of a previous load of this profile
+		items := []list.Item{}
+		for _, item := range m.rolesList.Items() {
+			if role, ok := item.(*RoleItem); ok && role.profile != msg.profile {
+				items = append(items, item)
+			}
+		}
+		for i := range msg.roles {
+			items = append(items, &msg.roles[i])
+		}
+
+		// Keep accounts grouped in the order the profiles were picked
+		slices.SortStableFunc(items, func(a, b list.Item) int {
+			roleA, roleB := a.(*RoleItem), b.(*RoleItem)
+			return cmp.Or(
+				cmp.Compare(slices.Index(m.multiProfiles, roleA.profile), slices.Index(m.multiProfiles, roleB.profile)),
+				strings.Compare(roleA.roleName, roleB.roleName),
+			)
+		})
+		m.rolesList.SetItems(items)
+	}
+
+	if m.multiPending > 0 {
+		m.statusMsg = fmt.Sprintf("Loading roles: %d of %d profiles done", len(m.multiProfiles)-m.multiPending, len(m.multiProfiles))
+		return
+	}
+	m.statusMsg = fmt.Sprintf("Loaded %d roles from %d profiles", len(m.rolesList.Items()), len(m.multiProfiles)-len(m.multiErrors))
+	if len(m.multiErrors) > 0 {
+		m.statusMsg += fmt.Sprintf(", %d failed: %s", len(m.multiErrors), strings.Join(m.multiErrors, "; "))
+	}
+}
+
+// switchProfile leaves the aggregated view and reloads everything for a single profile
+func (m *model) switchProfile(profile string) tea.Cmd {
+	m.currentProfile = profile
+	m.sessionProfile = profile
+	m.identities = nil
+	m.resetAccountView()
+	m.statusMsg = fmt.Sprintf("Switched to profile: %s", profile)
+
+	return tea.Batch(loadUserArnCmd(m.screenContext("roles"), m.session(), m.viewKey()), m.cachedAccountCmd(profile))
+}
+
+// resetAccountView clears the shown account before another identity is loaded
+func (m *model) resetAccountView() {
+	// Identity and cache loads still in flight belong to the previous view
+	m.viewGeneration++
+	// Loads of the roles screen belong to the previous account too
+	m.cancelScreen("roles")
+	m.multiProfiles = nil
+	m.orgScan = nil
+	m.sourceLabel = ""
+	m.userArn = ""
+	m.accountID = ""
+	m.account = nil
+	m.cachedAt = time.Time{}
+	m.refreshPending = 0
+	m.stopRolesStream()
+	m.stopAccountLoad()
+	m.stopPolicyCounts()
+	m.rolesList.SetItems([]list.Item{})
+	m.currentScreen = "roles"
+	updateKeyBindingsForScreen(m.currentScreen)
+}
+
+// multiProfileLabel names the profiles of the aggregated view in the header
+func multiProfileLabel(profiles []string) string {
+	if len(profiles) <= 3 {
+		return "Profiles: " + strings.Join(profiles, ", ")
+	}
+	return fmt.Sprintf("Profiles: %s +%d more", strings.Join(profiles[:3], ", "), len(profiles)-3)
+}
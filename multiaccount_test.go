@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Test marking profiles and loading them together
+func TestMarkProfiles(t *testing.T) {
+	m := createTestModel()
+	m.currentScreen = "profiles"
+	m.profilesList.SetItems([]list.Item{&ProfileItem{name: "dev"}, &ProfileItem{name: "prod"}, &ProfileItem{name: "staging"}})
+
+	space := tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}}
+	newModel, _ := m.Update(space)
+	m = newModel.(model)
+	m.profilesList.Select(2)
+	newModel, _ = m.Update(space)
+	m = newModel.(model)
+
+	if marked := m.markedProfiles(); strings.Join(marked, ",") != "dev,staging" {
+		t.Fatalf("Expected dev and staging to be marked, got %v", marked)
+	}
+
+	newModel, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = newModel.(model)
+	if cmd == nil || m.currentScreen != "roles" || m.multiPending != 2 {
+		t.Errorf("Expected both profiles to load on the roles screen")
+	}
+	if m.sourceLabel != "Profiles: dev, staging" {
+		t.Errorf("Expected profiles in the header, got '%s'", m.sourceLabel)
+	}
+}
+
+// Test aggregating roles of several accounts
+func TestAddProfileRoles(t *testing.T) {
+	m := createTestModel()
+	m.startMultiAccount([]string{"dev", "prod", "broken"})
+
+	m.addProfileRoles(profileRolesLoadedMsg{profile: "prod", roles: []RoleItem{{roleName: "Admin", profile: "prod", accountID: "222222222222", accountAlias: "acme-prod"}}})
+	m.addProfileRoles(profileRolesLoadedMsg{profile: "dev", roles: []RoleItem{{roleName: "Admin", profile: "dev", accountID: "111111111111"}}})
+	m.addProfileRoles(profileRolesLoadedMsg{profile: "other", roles: []RoleItem{{roleName: "Stale", profile: "other"}}})
+	if !strings.Contains(m.statusMsg, "2 of 3") {
+		t.Errorf("Expected progress in the status, got '%s'", m.statusMsg)
+	}
+	m.addProfileRoles(profileRolesLoadedMsg{profile: "broken", err: fmt.Errorf("expired token")})
+
+	items := m.rolesList.Items()
+	if len(items) != 2 {
+		t.Fatalf("Expected 2 roles, got %d", len(items))
+	}
+	first, second := items[0].(*RoleItem), items[1].(*RoleItem)
+	if first.profile != "dev" || second.profile != "prod" {
+		t.Errorf("Expected roles in profile order, got %s, %s", first.profile, second.profile)
+	}
+	if second.Description() != "acme-prod (222222222222) | prod" {
+		t.Errorf("Expected account and profile columns, got '%s'", second.Description())
+	}
+	if !strings.Contains(second.FilterValue(), "acme-prod") {
+		t.Errorf("Expected filtering by account alias, got '%s'", second.FilterValue())
+	}
+	if !strings.Contains(m.statusMsg, "1 failed: broken: expired token") {
+		t.Errorf("Expected the failed profile in the status, got '%s'", m.statusMsg)
+	}
+
+	// Policies are loaded with the profile of the selected role
+	if m.roleSession(second).profile != "prod" {
+		t.Errorf("Expected the role's profile to be used")
+	}
+
+	// Snapshots are single-account
+	newModel, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'x'}})
+	if cmd != nil || !strings.Contains(newModel.(model).statusMsg, "single account") {
+		t.Errorf("Expected export to be refused for several accounts")
+	}
+}
+
+// Test switching back to a single profile
+func TestSwitchProfile(t *testing.T) {
+	m := createTestModel()
+	m.startMultiAccount([]string{"dev", "prod"})
+	m.userArn = "arn:aws:iam::111111111111:user/alice"
+
+	cmd := m.switchProfile("prod")
+
+	if cmd == nil || m.multiProfiles != nil || m.sourceLabel != "" {
+		t.Errorf("Expected the aggregated view to be closed")
+	}
+	if m.currentProfile != "prod" || m.sessionProfile != "prod" || m.userArn != "" {
+		t.Errorf("Expected prod to be loaded, got profile '%s'", m.currentProfile)
+	}
+
+	// Reopening the profiles screen keeps the picked profile
+	newModel, _ := m.Update(profilesLoadedMsg{profiles: []awsProfile{{name: "dev"}, {name: "prod"}}, currentProfile: "default"})
+	if newModel.(model).currentProfile != "prod" {
+		t.Errorf("Expected picked profile to be kept")
+	}
+}
+
+// Test loads of a profile's previous view are dropped after switching away and back
+func TestSwitchBackDropsPreviousLoads(t *testing.T) {
+	m := createTestModel()
+	m.switchProfile("prod")
+	view := m.viewKey()
+	m.switchProfile("dev")
+	m.switchProfile("prod")
+
+	newModel, _ := m.Update(userArnLoadedMsg{view: view, arn: "arn:aws:iam::111111111111:user/alice"})
+	if newModel.(model).userArn != "" {
+		t.Errorf("Expected the identity of the earlier prod view to be dropped")
+	}
+}
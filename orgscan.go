@@ -0,0 +1,297 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/organizations"
+	orgtypes "github.com/aws/aws-sdk-go-v2/service/organizations/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// orgScan is a scan of every member account of an organization
+type orgScan struct {
+	roleName    string // Role assumed in each member account
+	concurrency int    // Accounts scanned at the same time
+	accounts    []*orgAccountItem
+	pending     int // Accounts not scanned yet
+	done        bool
+	progress    chan tea.Msg // Messages of the scan workers
+}
+
+// orgAccountItem is a member account and its scan progress
+type orgAccountItem struct {
+	accountID string
+	name      string
+	state     string // "waiting", "scanning", "done" or "failed"
+	roleCount int
+	err       error
+}
+
+func (i orgAccountItem) Title() string { return fmt.Sprintf("%s (%s)", i.name, i.accountID) }
+func (i orgAccountItem) Description() string {
+	switch i.state {
+	case "scanning":
+		return "Scanning..."
+	case "done":
+		return fmt.Sprintf("%d roles", i.roleCount)
+	case "failed":
+		return fmt.Sprintf("Failed: %v", i.err)
+	}
+	return "Waiting"
+}
+func (i orgAccountItem) FilterValue() string { return i.name + " " + i.accountID + " " + i.state }
+
+// orgAccountsListedMsg is sent when the member accounts are known and the scan workers started
+type orgAccountsListedMsg struct {
+	scan     *orgScan
+	accounts []orgAccountItem
+	progress chan tea.Msg
+	err      error
+}
+
+// orgAccountStartedMsg is sent when a worker starts scanning an account
+type orgAccountStartedMsg struct {
+	scan      *orgScan
+	accountID string
+}
+
+// orgAccountScannedMsg is sent when an account was scanned or failed
+type orgAccountScannedMsg struct {
+	scan      *orgScan
+	accountID string
+	roles     []RoleItem
+	err       error
+}
+
+// orgScanDoneMsg is sent once every account was scanned
+type orgScanDoneMsg struct {
+	scan *orgScan
+}
+
+// openOrgScan switches the model to an organization scan, the scan starts with orgScanCmd
+func (m *model) openOrgScan(roleName string, concurrency int) *orgScan {
+	scan := &orgScan{roleName: roleName, concurrency: concurrency}
+	m.orgScan = scan
+	m.multiProfiles = nil
+	m.sourceLabel = fmt.Sprintf("Organization scan: %s", roleName)
+	m.account = nil
+	m.cachedAt = time.Time{}
+	m.stopPolicyCounts()
+	m.rolesList.SetItems([]list.Item{})
+	m.orgList.SetItems([]list.Item{})
+	m.currentScreen = "org"
+	updateKeyBindingsForScreen(m.currentScreen)
+	m.statusMsg = "Listing organization accounts..."
+	return scan
+}
+
+// startOrgScan starts a new scan with the settings of the current one
+func (m *model) startOrgScan() tea.Cmd {
+	scan := m.openOrgScan(m.orgScan.roleName, m.orgScan.concurrency)
+	return orgScanCmd(m.screenContext("roles"), scan, m.session())
+}
+
+// List the organization's accounts and start scanning them in the background
+func orgScanCmd(ctx context.Context, scan *orgScan, session awsSession) tea.Cmd {
+	return func() tea.Msg {
+		cfg, err := session.loadConfig(ctx)
+		if err != nil {
+			return orgAccountsListedMsg{scan: scan, err: fmt.Errorf("error loading AWS configuration: %w", err)}
+		}
+
+		identity, err := session.stsClient(cfg).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+		if err != nil {
+			return orgAccountsListedMsg{scan: scan, err: fmt.Errorf("error getting caller identity: %w", err)}
+		}
+
+		var accounts []orgAccountItem
+		paginator := organizations.NewListAccountsPaginator(session.organizationsClient(cfg), &organizations.ListAccountsInput{})
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				return orgAccountsListedMsg{scan: scan, err: fmt.Errorf("error listing organization accounts: %w", err)}
+			}
+			for _, account := range page.Accounts {
+				// Suspended accounts cannot be assumed into
+				if account.Status != orgtypes.AccountStatusActive {
+					continue
+				}
+				accounts = append(accounts, orgAccountItem{
+					accountID: aws.ToString(account.Id),
+					name:      aws.ToString(account.Name),
+					state:     "waiting",
+				})
+			}
+		}
+
+		progress := make(chan tea.Msg)
+		go runOrgScan(ctx, session, cfg, identity, scan, accounts, progress)
+		return orgAccountsListedMsg{scan: scan, accounts: accounts, progress: progress}
+	}
+}
+
+// runOrgScan scans accounts with at most scan.concurrency workers and closes progress when done
+func runOrgScan(ctx context.Context, session awsSession, cfg aws.Config, identity *sts.GetCallerIdentityOutput, scan *orgScan, accounts []orgAccountItem, progress chan<- tea.Msg) {
+	partition := "aws"
+	if callerArn, err := arn.Parse(aws.ToString(identity.Arn)); err == nil {
+		partition = callerArn.Partition
+	}
+	managementAccountID := aws.ToString(identity.Account)
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, max(scan.concurrency, 1))
+	for _, account := range accounts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			progress <- orgAccountStartedMsg{scan: scan, accountID: account.accountID}
+
+			accountCfg := cfg.Copy()
+			// The caller's own account is read directly, the access role usually only exists in member accounts
+			if account.accountID != managementAccountID {
+				roleArn := fmt.Sprintf("arn:%s:iam::%s:role/%s", partition, account.accountID, scan.roleName)
+				provider := stscreds.NewAssumeRoleProvider(session.stsClient(cfg), roleArn, func(o *stscreds.AssumeRoleOptions) {
+					o.RoleSessionName = "atui-org-scan"
+				})
+				accountCfg.Credentials = aws.NewCredentialsCache(provider)
+			}
+
+			data, err := loadAccountDetails(ctx, session.iamClient(accountCfg))
+			if err != nil {
+				progress <- orgAccountScannedMsg{scan: scan, accountID: account.accountID, err: err}
+				return
+			}
+			for i := range data.roles {
+				data.roles[i].accountID = account.accountID
+				data.roles[i].accountAlias = account.name
+			}
+			progress <- orgAccountScannedMsg{scan: scan, accountID: account.accountID, roles: data.roles}
+		}()
+	}
+	wg.Wait()
+	close(progress)
+}
+
+// handleOrgProgress applies a scan message and waits for the next one
+func (m *model) handleOrgProgress(scan *orgScan, msg tea.Msg) tea.Cmd {
+	if listed, ok := msg.(orgAccountsListedMsg); ok {
+		scan.progress = listed.progress
+	}
+	// Messages of a replaced scan are only drained so its workers can finish
+	if scan == m.orgScan {
+		m.updateOrgScan(msg)
+	}
+	if _, finished := msg.(orgScanDoneMsg); finished || scan.progress == nil {
+		return nil
+	}
+	return waitForOrgProgress(scan)
+}
+
+// waitForOrgProgress delivers the next message of a running scan
+func waitForOrgProgress(scan *orgScan) tea.Cmd {
+	progress := scan.progress
+	return func() tea.Msg {
+		msg, ok := <-progress
+		if !ok {
+			return orgScanDoneMsg{scan: scan}
+		}
+		return msg
+	}
+}
+
+// findOrgAccount returns the scan item of an account
+func (s *orgScan) findOrgAccount(accountID string) *orgAccountItem {
+	for _, account := range s.accounts {
+		if account.accountID == accountID {
+			return account
+		}
+	}
+	return nil
+}
+
+// updateOrgScan applies scan progress to the model
+func (m *model) updateOrgScan(msg tea.Msg) {
+	scan := m.orgScan
+	switch msg := msg.(type) {
+	case orgAccountsListedMsg:
+		if msg.err != nil {
+			scan.done = true
+			m.statusMsg = fmt.Sprintf("Organization scan failed: %v", msg.err)
+			return
+		}
+		items := []list.Item{}
+		for i := range msg.accounts {
+			scan.accounts = append(scan.accounts, &msg.accounts[i])
+			items = append(items, &msg.accounts[i])
+		}
+		scan.pending = len(msg.accounts)
+		m.orgList.SetItems(items)
+
+	case orgAccountStartedMsg:
+		if account := scan.findOrgAccount(msg.accountID); account != nil {
+			account.state = "scanning"
+		}
+
+	case orgAccountScannedMsg:
+		account := scan.findOrgAccount(msg.accountID)
+		if account == nil {
+			return
+		}
+		scan.pending--
+		if msg.err != nil {
+			account.state = "failed"
+			account.err = msg.err
+			return
+		}
+		account.state = "done"
+		account.roleCount = len(msg.roles)
+
+		items := m.rolesList.Items()
+		for i := range msg.roles {
+			items = append(items, &msg.roles[i])
+		}
+		// Group roles by account name
+		slices.SortStableFunc(items, func(a, b list.Item) int {
+			roleA, roleB := a.(*RoleItem), b.(*RoleItem)
+			return cmp.Or(strings.Compare(roleA.accountAlias, roleB.accountAlias), strings.Compare(roleA.roleName, roleB.roleName))
+		})
+		m.rolesList.SetItems(items)
+
+	case orgScanDoneMsg:
+		scan.done = true
+	}
+
+	m.statusMsg = scan.statusText(len(m.rolesList.Items()))
+}
+
+// statusText summarizes the scan progress
+func (s *orgScan) statusText(roleCount int) string {
+	failed := 0
+	for _, account := range s.accounts {
+		if account.state == "failed" {
+			failed++
+		}
+	}
+	if !s.done {
+		return fmt.Sprintf("Scanning accounts: %d of %d done, %d roles found", len(s.accounts)-s.pending, len(s.accounts), roleCount)
+	}
+	text := fmt.Sprintf("Scanned %d accounts, %d roles found", len(s.accounts)-failed, roleCount)
+	if failed > 0 {
+		text += fmt.Sprintf(", %d accounts failed", failed)
+	}
+	return text
+}
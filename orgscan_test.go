@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	orgtypes "github.com/aws/aws-sdk-go-v2/service/organizations/types"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Test applying scan progress from the workers
+func TestOrgScanProgress(t *testing.T) {
+	m := createTestModel()
+	scan := m.openOrgScan("AuditRole", 2)
+	if m.currentScreen != "org" || m.sourceLabel != "Organization scan: AuditRole" {
+		t.Fatalf("Expected the scan screen, got '%s'", m.currentScreen)
+	}
+
+	progress := make(chan tea.Msg, 1)
+	newModel, cmd := m.Update(orgAccountsListedMsg{
+		scan:     scan,
+		accounts: []orgAccountItem{{accountID: "111111111111", name: "prod", state: "waiting"}, {accountID: "222222222222", name: "dev", state: "waiting"}},
+		progress: progress,
+	})
+	m = newModel.(model)
+	if cmd == nil || len(m.orgList.Items()) != 2 {
+		t.Fatalf("Expected accounts listed and the next message awaited")
+	}
+
+	// The next message comes from the workers' channel
+	progress <- orgAccountStartedMsg{scan: scan, accountID: "111111111111"}
+	newModel, _ = m.Update(cmd())
+	m = newModel.(model)
+	if scan.accounts[0].Description() != "Scanning..." {
+		t.Errorf("Expected account to be scanning, got '%s'", scan.accounts[0].Description())
+	}
+
+	newModel, _ = m.Update(orgAccountScannedMsg{scan: scan, accountID: "111111111111", roles: []RoleItem{{roleName: "Admin", accountID: "111111111111", accountAlias: "prod", policiesLoaded: true}}})
+	m = newModel.(model)
+	newModel, _ = m.Update(orgAccountScannedMsg{scan: scan, accountID: "222222222222", err: fmt.Errorf("AccessDenied")})
+	m = newModel.(model)
+	newModel, cmd = m.Update(orgScanDoneMsg{scan: scan})
+	m = newModel.(model)
+
+	if cmd != nil {
+		t.Errorf("Expected no more waiting once the scan is done")
+	}
+	if m.statusMsg != "Scanned 1 accounts, 1 roles found, 1 accounts failed" {
+		t.Errorf("Expected scan summary, got '%s'", m.statusMsg)
+	}
+	if !strings.Contains(scan.accounts[1].Description(), "AccessDenied") {
+		t.Errorf("Expected failure per account, got '%s'", scan.accounts[1].Description())
+	}
+
+	// Scanned roles have no profile, so details not in the scan are not requested
+	role := m.rolesList.Items()[0].(*RoleItem)
+	if m.canLoad(role) || role.Description() != "prod (111111111111) | 0 policies attached" {
+		t.Errorf("Expected scanned role without a profile, got '%s'", role.Description())
+	}
+
+	// Enter shows the roles of the selected account
+	m.orgList.Select(0)
+	newModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = newModel.(model)
+	if m.currentScreen != "roles" || m.rolesList.FilterValue() != "111111111111" {
+		t.Errorf("Expected roles filtered by account, got filter '%s'", m.rolesList.FilterValue())
+	}
+}
+
+// Test a scan against the fake backend lists its organization without calling AWS
+func TestOrgScanFakeBackend(t *testing.T) {
+	f := newTestBackend()
+	f.pageSize = 1
+	f.addAccount(f.accountID, "management", orgtypes.AccountStatusActive)
+	f.addAccount("222222222222", "dev", orgtypes.AccountStatusActive)
+	f.addAccount("333333333333", "closed", orgtypes.AccountStatusSuspended)
+
+	m := createTestModel()
+	m.backend = f
+	scan := m.openOrgScan("AuditRole", 1)
+	cmd := orgScanCmd(context.Background(), scan, m.session())
+	for cmd != nil {
+		newModel, next := m.Update(cmd())
+		m = newModel.(model)
+		cmd = next
+	}
+
+	if len(scan.accounts) != 2 || f.callCount("ListAccounts") != 3 {
+		t.Fatalf("Expected the active accounts of every page, got %d accounts", len(scan.accounts))
+	}
+	if !strings.HasPrefix(m.statusMsg, "Scanned 2 accounts") {
+		t.Errorf("Expected both accounts scanned, got '%s'", m.statusMsg)
+	}
+}
+
+// Test that a replaced scan is drained without changing the model
+func TestOrgScanReplaced(t *testing.T) {
+	m := createTestModel()
+	old := m.openOrgScan("AuditRole", 2)
+	old.progress = make(chan tea.Msg)
+	m.openOrgScan("AuditRole", 2)
+
+	cmd := m.handleOrgProgress(old, orgAccountScannedMsg{scan: old, accountID: "111111111111", roles: []RoleItem{{roleName: "Stale"}}})
+	if cmd == nil || len(m.rolesList.Items()) != 0 {
+		t.Errorf("Expected stale roles to be ignored while the old scan is drained")
+	}
+}
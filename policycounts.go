@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// policyCountWorkers is how many roles have their policies counted at the same time
+const policyCountWorkers = 5
+
+// policyCountScan counts the policies of every listed role in the background
+type policyCountScan struct {
+	total    int
+	done     int
+	failed   int
+	cancel   context.CancelFunc
+	progress chan tea.Msg // Messages of the count workers
+}
+
+// policyCountJob is a role to count, its name is copied so workers never read the list item
+type policyCountJob struct {
+	role     *RoleItem
+	roleName string
+}
+
+// policyCountMsg is sent when the policies of a role were counted or counting failed
+type policyCountMsg struct {
+	scan     *policyCountScan
+	role     *RoleItem
+	attached int
+	inline   int
+	err      error
+}
+
+// policyCountsDoneMsg is sent once every role was counted
+type policyCountsDoneMsg struct {
+	scan *policyCountScan
+}
+
+// startPolicyCounts counts the policies of listed roles whose policies are not loaded yet, replacing a running count
+func (m *model) startPolicyCounts() tea.Cmd {
+	m.stopPolicyCounts()
+	if m.aggregated() || !m.canLoad(nil) {
+		return nil
+	}
+
+	var jobs []policyCountJob
+	for _, item := range m.rolesList.Items() {
+		if role, ok := item.(*RoleItem); ok && !role.policiesLoaded && !role.countLoaded {
+			jobs = append(jobs, policyCountJob{role: role, roleName: role.roleName})
+		}
+	}
+	if len(jobs) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	scan := &policyCountScan{total: len(jobs), cancel: cancel, progress: make(chan tea.Msg)}
+	m.policyCounts = scan
+	session := m.session()
+	wait := waitForPolicyCount(scan)
+	return func() tea.Msg {
+		go runPolicyCounts(ctx, session, scan, jobs)
+		return wait()
+	}
+}
+
+// stopPolicyCounts cancels the running count, roles already counted keep their count
+func (m *model) stopPolicyCounts() {
+	if m.policyCounts != nil {
+		m.policyCounts.cancel()
+		m.policyCounts = nil
+	}
+}
+
+// runPolicyCounts counts policies with at most policyCountWorkers workers and closes progress when done
+func runPolicyCounts(ctx context.Context, session awsSession, scan *policyCountScan, jobs []policyCountJob) {
+	defer close(scan.progress)
+
+	cfg, err := session.loadConfig(ctx)
+	if err != nil {
+		// Without a configuration no role can be counted, roles are still counted when opened
+		for _, job := range jobs {
+			if !sendPolicyCount(ctx, scan, policyCountMsg{scan: scan, role: job.role, err: err}) {
+				return
+			}
+		}
+		return
+	}
+	iamClient := session.iamClient(cfg)
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, policyCountWorkers)
+	for _, job := range jobs {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			attached, inline, err := countRolePolicies(ctx, iamClient, job.roleName)
+			sendPolicyCount(ctx, scan, policyCountMsg{scan: scan, role: job.role, attached: attached, inline: inline, err: err})
+		}()
+	}
+	wg.Wait()
+}
+
+// sendPolicyCount delivers a message unless the count was cancelled, reporting whether it was delivered
+func sendPolicyCount(ctx context.Context, scan *policyCountScan, msg tea.Msg) bool {
+	select {
+	case scan.progress <- msg:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// countRolePolicies returns the number of managed and inline policies of a role
+func countRolePolicies(ctx context.Context, iamClient iamAPI, roleName string) (int, int, error) {
+	attached := 0
+	attachedPages := iam.NewListAttachedRolePoliciesPaginator(iamClient, &iam.ListAttachedRolePoliciesInput{RoleName: aws.String(roleName)})
+	for attachedPages.HasMorePages() {
+		page, err := attachedPages.NextPage(ctx)
+		if err != nil {
+			return 0, 0, fmt.Errorf("error listing policies for role %s: %w", roleName, err)
+		}
+		attached += len(page.AttachedPolicies)
+	}
+
+	inline := 0
+	inlinePages := iam.NewListRolePoliciesPaginator(iamClient, &iam.ListRolePoliciesInput{RoleName: aws.String(roleName)})
+	for inlinePages.HasMorePages() {
+		page, err := inlinePages.NextPage(ctx)
+		if err != nil {
+			return 0, 0, fmt.Errorf("error listing inline policies for role %s: %w", roleName, err)
+		}
+		inline += len(page.PolicyNames)
+	}
+	return attached, inline, nil
+}
+
+// waitForPolicyCount delivers the next message of a running count
+func waitForPolicyCount(scan *policyCountScan) tea.Cmd {
+	return func() tea.Msg {
+		msg, ok := <-scan.progress
+		if !ok {
+			return policyCountsDoneMsg{scan: scan}
+		}
+		return msg
+	}
+}
+
+// handlePolicyCount applies a count to its role and waits for the next one
+func (m *model) handlePolicyCount(msg policyCountMsg) tea.Cmd {
+	// Counts of a replaced scan are dropped, its workers stop on their own once cancelled
+	if msg.scan != m.policyCounts {
+		return nil
+	}
+	msg.scan.done++
+	if msg.err != nil {
+		msg.scan.failed++
+	} else if !msg.role.policiesLoaded {
+		// Roles are updated in place, so the list shows the count on its next render
+		msg.role.policyCount = msg.attached + msg.inline
+		msg.role.countLoaded = true
+	}
+	return waitForPolicyCount(msg.scan)
+}
+
+// finishPolicyCounts reports failed counts once the scan is done
+func (m *model) finishPolicyCounts(msg policyCountsDoneMsg) {
+	if msg.scan != m.policyCounts {
+		return
+	}
+	m.policyCounts = nil
+	if msg.scan.failed > 0 {
+		m.statusMsg = fmt.Sprintf("Could not count the policies of %d of %d roles", msg.scan.failed, msg.scan.total)
+	}
+}
+
+// progressText summarizes a running count for the header
+func (s *policyCountScan) progressText() string {
+	return fmt.Sprintf("Counting policies %d/%d", s.done, s.total)
+}
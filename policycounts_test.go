@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// runPolicyCountsToEnd applies the messages of a running count until it is done
+func runPolicyCountsToEnd(t *testing.T, m model, cmd tea.Cmd) model {
+	t.Helper()
+	for cmd != nil {
+		newModel, next := m.Update(cmd())
+		m = newModel.(model)
+		cmd = next
+	}
+	return m
+}
+
+// Test roles get their attached and inline policy counts in the background
+func TestPolicyCounts(t *testing.T) {
+	f := newTestBackend()
+	f.addRole(fakeRole{name: "InlineRole", inline: map[string]string{"a": `{}`, "b": `{}`}})
+	f.addRole(fakeRole{name: "EmptyRole"})
+	f.pageSize = 1
+
+	m := createTestModel()
+	m.backend = f
+	loaded := &RoleItem{roleName: "LoadedRole", policies: []PolicyItem{{policyName: "P"}}, policiesLoaded: true}
+	m.rolesList.SetItems([]list.Item{&RoleItem{roleName: "TestRole"}, &RoleItem{roleName: "InlineRole"}, &RoleItem{roleName: "EmptyRole"}, loaded})
+
+	cmd := m.startPolicyCounts()
+	if cmd == nil || m.policyCounts == nil || m.policyCounts.total != 3 {
+		t.Fatalf("Expected 3 roles to count, skipping the loaded one")
+	}
+	if text := m.policyCounts.progressText(); text != "Counting policies 0/3" {
+		t.Errorf("Expected progress text, got '%s'", text)
+	}
+	m = runPolicyCountsToEnd(t, m, cmd)
+
+	if m.policyCounts != nil || m.statusMsg != "" {
+		t.Errorf("Expected the count to finish without failures, got '%s'", m.statusMsg)
+	}
+	expected := []string{" | 2 policies attached", " | 2 policies attached", " | 0 policies attached", " | 1 policies attached"}
+	for i, item := range m.rolesList.Items() {
+		if desc := item.(*RoleItem).Description(); desc != expected[i] {
+			t.Errorf("Expected '%s' for %s, got '%s'", expected[i], item.(*RoleItem).roleName, desc)
+		}
+	}
+	if f.callCount("ListAttachedRolePolicies") != 4 {
+		t.Errorf("Expected every page of attached policies, got %d calls", f.callCount("ListAttachedRolePolicies"))
+	}
+}
+
+// Test opening a counted role shows the same count, inline policies included
+func TestPolicyCountMatchesLoadedPolicies(t *testing.T) {
+	f := newTestBackend()
+	f.addRole(fakeRole{name: "MixedRole", attached: []string{"arn:aws:iam::aws:policy/ReadOnlyAccess"}, inline: map[string]string{"a": `{"Statement": []}`}})
+
+	m := createTestModel()
+	m.backend = f
+	role := &RoleItem{roleName: "MixedRole"}
+	m.rolesList.SetItems([]list.Item{role})
+	m = runPolicyCountsToEnd(t, m, m.startPolicyCounts())
+	counted := role.Description()
+
+	m.selectedRole = role
+	newModel, _ := m.Update(loadRolePoliciesCmd(context.Background(), m.session(), "MixedRole")())
+	m = newModel.(model)
+	if counted != " | 2 policies attached" || role.Description() != counted {
+		t.Errorf("Expected the same count before and after opening the role, got '%s' and '%s'", counted, role.Description())
+	}
+	if inline := role.policies[1]; inline.policyType != "Inline" || f.callCount("GetRolePolicy") != 0 {
+		t.Errorf("Expected the inline policy listed without loading its document, got %+v", inline)
+	}
+}
+
+// Test failed counts are reported and counts of a stopped scan are dropped
+func TestPolicyCountsFailures(t *testing.T) {
+	f := newTestBackend()
+	f.failOn("ListRolePolicies", fmt.Errorf("AccessDenied"))
+
+	m := createTestModel()
+	m.backend = f
+	role := &RoleItem{roleName: "TestRole"}
+	m.rolesList.SetItems([]list.Item{role})
+
+	m = runPolicyCountsToEnd(t, m, m.startPolicyCounts())
+	if role.countLoaded || !strings.Contains(m.statusMsg, "1 of 1 roles") {
+		t.Errorf("Expected the failure to be reported, got '%s'", m.statusMsg)
+	}
+
+	scan := &policyCountScan{total: 1, cancel: func() {}}
+	m.policyCounts = scan
+	m.stopPolicyCounts()
+	newModel, cmd := m.Update(policyCountMsg{scan: scan, role: role, attached: 3})
+	m = newModel.(model)
+	if cmd != nil || role.countLoaded {
+		t.Errorf("Expected counts of a stopped scan to be dropped")
+	}
+}
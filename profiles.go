@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// awsProfile is a profile of the shared config and credentials files
+type awsProfile struct {
+	name   string
+	values map[string]string // Keys of the profile, credentials file values take precedence
+}
+
+// iniSection is a section of an INI file and its keys
+type iniSection struct {
+	name   string
+	values map[string]string
+}
+
+// parseINI reads the sections of an AWS style INI file. Comments and indented sub-keys are skipped and
+// repeated sections are merged.
+func parseINI(r io.Reader) ([]iniSection, error) {
+	var sections []iniSection
+	index := make(map[string]int)
+	current := -1
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		raw := scanner.Text()
+		line := strings.TrimSpace(raw)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			end := strings.Index(line, "]")
+			if end < 0 {
+				current = -1 // Malformed header, skip its keys
+				continue
+			}
+			name := strings.Join(strings.Fields(line[1:end]), " ")
+			if i, ok := index[name]; ok {
+				current = i
+				continue
+			}
+			index[name] = len(sections)
+			current = len(sections)
+			sections = append(sections, iniSection{name: name, values: make(map[string]string)})
+			continue
+		}
+
+		// Indented lines belong to a nested key such as s3 settings
+		if current < 0 || raw[0] == ' ' || raw[0] == '\t' {
+			continue
+		}
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			continue
+		}
+		sections[current].values[strings.ToLower(strings.TrimSpace(key))] = strings.TrimSpace(value)
+	}
+	return sections, scanner.Err()
+}
+
+// parseINIFile parses an INI file, a missing file has no sections
+func parseINIFile(path string) ([]iniSection, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+	return parseINI(file)
+}
+
+// sharedConfigFile returns the path of the shared config file, honoring AWS_CONFIG_FILE
+func sharedConfigFile() (string, error) {
+	if path := os.Getenv("AWS_CONFIG_FILE"); path != "" {
+		return path, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("error getting home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".aws", "config"), nil
+}
+
+// sharedCredentialsFile returns the path of the shared credentials file, honoring AWS_SHARED_CREDENTIALS_FILE
+func sharedCredentialsFile() (string, error) {
+	if path := os.Getenv("AWS_SHARED_CREDENTIALS_FILE"); path != "" {
+		return path, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("error getting home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".aws", "credentials"), nil
+}
+
+// loadAWSProfiles reads the profiles of the config and credentials files sorted by name
+func loadAWSProfiles(configPath, credentialsPath string) ([]awsProfile, error) {
+	profiles := make(map[string]*awsProfile)
+	profile := func(name string) *awsProfile {
+		if profiles[name] == nil {
+			profiles[name] = &awsProfile{name: name, values: make(map[string]string)}
+		}
+		return profiles[name]
+	}
+
+	configSections, err := parseINIFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", configPath, err)
+	}
+	for _, section := range configSections {
+		// Only "default" and "profile x" are profiles, sso-session and services sections are not
+		name, isProfile := strings.CutPrefix(section.name, "profile ")
+		if !isProfile && section.name != "default" {
+			continue
+		}
+		for key, value := range section.values {
+			profile(name).values[key] = value
+		}
+	}
+
+	credentialSections, err := parseINIFile(credentialsPath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", credentialsPath, err)
+	}
+	for _, section := range credentialSections {
+		for key, value := range section.values {
+			profile(section.name).values[key] = value
+		}
+	}
+
+	var sorted []awsProfile
+	for _, p := range profiles {
+		sorted = append(sorted, *p)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].name < sorted[j].name })
+	return sorted, nil
+}
+
+// profileType describes how a profile gets its credentials, following source_profile chains through byName
+func profileType(p awsProfile, byName map[string]awsProfile) string {
+	v := p.values
+	switch {
+	case v["role_arn"] != "" && v["web_identity_token_file"] != "":
+		return "web identity"
+	case v["role_arn"] != "" && v["source_profile"] != "":
+		chain := []string{p.name}
+		for source := v["source_profile"]; source != ""; source = byName[source].values["source_profile"] {
+			if slices.Contains(chain, source) {
+				// A profile sourcing itself ends the chain, its keys are used directly
+				break
+			}
+			chain = append(chain, source)
+			if byName[source].values["role_arn"] == "" {
+				break
+			}
+		}
+		return "assume role via " + strings.Join(chain[1:], " → ")
+	case v["role_arn"] != "" && v["credential_source"] != "":
+		return "assume role from " + v["credential_source"]
+	case v["role_arn"] != "":
+		return "assume role"
+	case v["sso_session"] != "" || v["sso_start_url"] != "":
+		return "SSO"
+	case v["credential_process"] != "":
+		return "credential_process"
+	case v["aws_access_key_id"] != "":
+		return "static keys"
+	}
+	return ""
+}
+
+// profileAccountID returns the account of a profile when the files name it
+func profileAccountID(p awsProfile) string {
+	if roleArn, err := arn.Parse(p.values["role_arn"]); err == nil {
+		return roleArn.AccountID
+	}
+	if accountID := p.values["sso_account_id"]; accountID != "" {
+		return accountID
+	}
+	return p.values["aws_account_id"]
+}
+
+// profileDescription summarizes a profile's type, region and account
+func profileDescription(p awsProfile, byName map[string]awsProfile) string {
+	var columns []string
+	for _, column := range []string{profileType(p, byName), p.values["region"], profileAccountID(p)} {
+		if column != "" {
+			columns = append(columns, column)
+		}
+	}
+	return strings.Join(columns, " | ")
+}
+
+// Load AWS profiles from the shared config and credentials files
+func loadAWSProfilesCmd() tea.Cmd {
+	return func() tea.Msg {
+		configPath, err := sharedConfigFile()
+		if err != nil {
+			return errorMsg(err)
+		}
+		credentialsPath, err := sharedCredentialsFile()
+		if err != nil {
+			return errorMsg(err)
+		}
+
+		profiles, err := loadAWSProfiles(configPath, credentialsPath)
+		if err != nil {
+			return errorMsg(err)
+		}
+		return profilesLoadedMsg{
+			profiles:       profiles,
+			currentProfile: currentProfileName(),
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testConfigFile = `# Shared settings
+[default]
+region = eu-west-1
+
+[profile prod]
+role_arn = arn:aws:iam::222222222222:role/Admin
+source_profile = base
+region = us-east-1
+s3 =
+  max_concurrent_requests = 20
+
+[profile audit]
+role_arn = arn:aws:iam::333333333333:role/Audit
+source_profile = prod
+
+[sso-session corp]
+sso_start_url = https://corp.awsapps.com/start
+sso_region = eu-west-1
+
+[profile  sso-dev ]
+sso_session = corp
+sso_account_id = 444444444444
+sso_role_name = Developer
+
+[profile ci]
+role_arn = arn:aws:iam::555555555555:role/CI
+web_identity_token_file = /var/run/token
+
+[profile vault]
+credential_process = vault-aws-creds
+
+[services local]
+dynamodb =
+  endpoint_url = http://localhost:8000
+`
+
+const testCredentialsFile = `[base]
+aws_access_key_id = AKIABASE
+aws_secret_access_key = secret
+
+; Keys of the default profile
+[default]
+aws_access_key_id = AKIADEFAULT
+aws_secret_access_key = secret
+`
+
+// Test reading profiles from both files
+func TestLoadAWSProfiles(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config")
+	credentialsPath := filepath.Join(dir, "credentials")
+	if err := os.WriteFile(configPath, []byte(testConfigFile), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(credentialsPath, []byte(testCredentialsFile), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	profiles, err := loadAWSProfiles(configPath, credentialsPath)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var names []string
+	byName := make(map[string]awsProfile)
+	for _, profile := range profiles {
+		names = append(names, profile.name)
+		byName[profile.name] = profile
+	}
+	if strings.Join(names, ",") != "audit,base,ci,default,prod,sso-dev,vault" {
+		t.Fatalf("Expected sorted profiles without sso-session and services sections, got %v", names)
+	}
+	if byName["prod"].values["max_concurrent_requests"] != "" {
+		t.Errorf("Expected nested keys to be skipped")
+	}
+
+	expected := map[string]string{
+		"audit":   "assume role via prod → base | 333333333333",
+		"base":    "static keys",
+		"ci":      "web identity | 555555555555",
+		"default": "static keys | eu-west-1",
+		"prod":    "assume role via base | us-east-1 | 222222222222",
+		"sso-dev": "SSO | 444444444444",
+		"vault":   "credential_process",
+	}
+	for name, description := range expected {
+		if got := profileDescription(byName[name], byName); got != description {
+			t.Errorf("Expected %s to be described as '%s', got '%s'", name, description, got)
+		}
+	}
+
+	// Missing files have no profiles
+	profiles, err = loadAWSProfiles(filepath.Join(dir, "missing"), filepath.Join(dir, "missing"))
+	if err != nil || len(profiles) != 0 {
+		t.Errorf("Expected no profiles and no error, got %v, %v", profiles, err)
+	}
+}
+
+// Test source_profile chains that loop
+func TestProfileTypeLoop(t *testing.T) {
+	byName := map[string]awsProfile{
+		"a": {name: "a", values: map[string]string{"role_arn": "arn:aws:iam::111111111111:role/A", "source_profile": "b"}},
+		"b": {name: "b", values: map[string]string{"role_arn": "arn:aws:iam::111111111111:role/B", "source_profile": "a"}},
+	}
+	if got := profileType(byName["a"], byName); got != "assume role via b" {
+		t.Errorf("Expected the loop to end the chain, got '%s'", got)
+	}
+}
+
+// Test that the environment picks the files
+func TestLoadAWSProfilesCmdEnvironment(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "custom-config")
+	if err := os.WriteFile(configPath, []byte("[profile custom]\nregion = eu-central-1\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AWS_CONFIG_FILE", configPath)
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "missing"))
+
+	msg := loadAWSProfilesCmd()().(profilesLoadedMsg)
+	if len(msg.profiles) != 1 || msg.profiles[0].name != "custom" {
+		t.Fatalf("Expected the profile of AWS_CONFIG_FILE, got %v", msg.profiles)
+	}
+
+	m := createTestModel()
+	newModel, _ := m.Update(msg)
+	item := newModel.(model).profilesList.Items()[0].(*ProfileItem)
+	if item.Description() != "eu-central-1" || !strings.Contains(item.FilterValue(), "eu-central-1") {
+		t.Errorf("Expected the region in the description, got '%s'", item.Description())
+	}
+}
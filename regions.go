@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// defaultRegion is used when neither the TUI nor the profile picks a region
+const defaultRegion = "us-east-1"
+
+// regionItem is a region offered by the region switcher
+type regionItem struct {
+	code string // Empty for the region of the profile
+	name string
+}
+
+func (i regionItem) Title() string {
+	if i.code == "" {
+		return "Profile default"
+	}
+	return i.code
+}
+func (i regionItem) Description() string { return i.name }
+func (i regionItem) FilterValue() string { return i.code + " " + i.name }
+
+// awsRegions are the commercial regions offered by the region switcher
+var awsRegions = []regionItem{
+	{"us-east-1", "US East (N. Virginia)"},
+	{"us-east-2", "US East (Ohio)"},
+	{"us-west-1", "US West (N. California)"},
+	{"us-west-2", "US West (Oregon)"},
+	{"af-south-1", "Africa (Cape Town)"},
+	{"ap-east-1", "Asia Pacific (Hong Kong)"},
+	{"ap-south-1", "Asia Pacific (Mumbai)"},
+	{"ap-south-2", "Asia Pacific (Hyderabad)"},
+	{"ap-southeast-1", "Asia Pacific (Singapore)"},
+	{"ap-southeast-2", "Asia Pacific (Sydney)"},
+	{"ap-southeast-3", "Asia Pacific (Jakarta)"},
+	{"ap-southeast-4", "Asia Pacific (Melbourne)"},
+	{"ap-northeast-1", "Asia Pacific (Tokyo)"},
+	{"ap-northeast-2", "Asia Pacific (Seoul)"},
+	{"ap-northeast-3", "Asia Pacific (Osaka)"},
+	{"ca-central-1", "Canada (Central)"},
+	{"ca-west-1", "Canada West (Calgary)"},
+	{"eu-central-1", "Europe (Frankfurt)"},
+	{"eu-central-2", "Europe (Zurich)"},
+	{"eu-west-1", "Europe (Ireland)"},
+	{"eu-west-2", "Europe (London)"},
+	{"eu-west-3", "Europe (Paris)"},
+	{"eu-south-1", "Europe (Milan)"},
+	{"eu-south-2", "Europe (Spain)"},
+	{"eu-north-1", "Europe (Stockholm)"},
+	{"il-central-1", "Israel (Tel Aviv)"},
+	{"me-south-1", "Middle East (Bahrain)"},
+	{"me-central-1", "Middle East (UAE)"},
+	{"sa-east-1", "South America (São Paulo)"},
+}
+
+// openRegions shows the region switcher with the active region selected
+func (m *model) openRegions() {
+	if m.offline {
+		m.statusMsg = "Regions are not available while browsing offline data"
+		return
+	}
+	items := []list.Item{&regionItem{name: "Region of the profile or the environment"}}
+	selected := 0
+	for i := range awsRegions {
+		items = append(items, &awsRegions[i])
+		if awsRegions[i].code == m.region {
+			selected = i + 1
+		}
+	}
+	m.regionsList.SetItems(items)
+	m.regionsList.Select(selected)
+	m.regionReturnScreen = m.currentScreen
+	m.currentScreen = "regions"
+	updateKeyBindingsForScreen(m.currentScreen)
+	m.statusMsg = ""
+}
+
+// selectRegion applies a region to the sessions of all loaders. IAM is global, so the loaded data stays and only
+// the identity is loaded again through the regional STS endpoint.
+func (m *model) selectRegion(region string) tea.Cmd {
+	m.region = region
+	m.activeRegion = region
+	m.currentScreen = m.regionReturnScreen
+	updateKeyBindingsForScreen(m.currentScreen)
+	if region == "" {
+		m.statusMsg = "Using the region of the profile"
+	} else {
+		m.statusMsg = fmt.Sprintf("Switched to region: %s", region)
+	}
+	return loadUserArnCmd(m.screenContext("roles"), m.session(), m.viewKey())
+}
+
+// regionText names the active region for the header, empty until it is known
+func (m model) regionText() string {
+	if m.activeRegion == "" {
+		return ""
+	}
+	return " | Region: " + m.activeRegion
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Test switching the region of all sessions
+func TestSwitchRegion(t *testing.T) {
+	m := createTestModel()
+	m.sessionProfile = "dev"
+	m.currentProfile = "dev"
+
+	newModel, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'R'}})
+	m = newModel.(model)
+	if m.currentScreen != "regions" || len(m.regionsList.Items()) != len(awsRegions)+1 {
+		t.Fatalf("Expected the region switcher, got screen '%s'", m.currentScreen)
+	}
+	if m.regionsList.Index() != 0 {
+		t.Errorf("Expected the profile default to be selected")
+	}
+
+	m.regionsList.Select(slicesIndexRegion("eu-west-1") + 1)
+	newModel, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = newModel.(model)
+	if cmd == nil || m.currentScreen != "roles" || m.region != "eu-west-1" {
+		t.Fatalf("Expected eu-west-1 to be picked, got '%s'", m.region)
+	}
+
+	// Every loader uses the region
+	if m.session().region != "eu-west-1" || m.roleSession(&RoleItem{profile: "prod", accountID: "222222222222"}).region != "eu-west-1" {
+		t.Errorf("Expected the region in all sessions")
+	}
+	m.identities = []assumedIdentity{{session: awsSession{profile: "dev", credentials: credentials.NewStaticCredentialsProvider("a", "b", "c")}}}
+	if session := m.session(); session.region != "eu-west-1" || session.credentials == nil {
+		t.Errorf("Expected the region for the assumed role too")
+	}
+
+	m.currentProfile = "dev"
+	if !strings.Contains(m.View(), "Region: eu-west-1") {
+		t.Errorf("Expected the region in the header")
+	}
+
+	// The identity reports the region it was loaded in
+	newModel, _ = m.Update(userArnLoadedMsg{view: m.viewKey(), arn: "arn:aws:iam::111111111111:user/alice", region: "eu-west-1"})
+	if newModel.(model).activeRegion != "eu-west-1" {
+		t.Errorf("Expected the loaded region to be shown")
+	}
+
+	// Reopening selects the active region, esc keeps it
+	m.openRegions()
+	if m.regionsList.SelectedItem().(*regionItem).code != "eu-west-1" {
+		t.Errorf("Expected the active region to be selected")
+	}
+	newModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if newModel.(model).currentScreen != "roles" || newModel.(model).region != "eu-west-1" {
+		t.Errorf("Expected esc to keep the region")
+	}
+}
+
+// slicesIndexRegion returns the position of a region in the switcher list
+func slicesIndexRegion(code string) int {
+	for i, region := range awsRegions {
+		if region.code == code {
+			return i
+		}
+	}
+	return -1
+}
+
+// Test the region of loaded configurations
+func TestLoadAWSConfigRegion(t *testing.T) {
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "config")
+	if err := os.WriteFile(configFile, []byte("[profile regional]\nregion = eu-central-1\n\n[profile global]\noutput = json\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AWS_CONFIG_FILE", configFile)
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "credentials"))
+	t.Setenv("AWS_REGION", "")
+	t.Setenv("AWS_DEFAULT_REGION", "")
+
+	for _, tc := range []struct {
+		session awsSession
+		region  string
+	}{
+		{awsSession{profile: "regional"}, "eu-central-1"},
+		{awsSession{profile: "regional", region: "ap-south-1"}, "ap-south-1"},
+		{awsSession{profile: "global"}, defaultRegion},
+	} {
+		cfg, err := tc.session.loadConfig(context.Background())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if cfg.Region != tc.region {
+			t.Errorf("Expected region %s for %+v, got %s", tc.region, tc.session, cfg.Region)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// loadRequest is a request in flight, its progress is shown on the screen that started it
+type loadRequest struct {
+	screen string
+	text   string // e.g. "Loading policies for AppRole..."
+	load   func(ctx context.Context) tea.Cmd
+	cancel context.CancelFunc
+}
+
+// screenLoad is the context of a screen's background loads, which are not tracked as requests
+type screenLoad struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// requestMsg carries the response of a request, which is dropped once the request was cancelled or replaced
+type requestMsg struct {
+	id  int
+	msg tea.Msg
+}
+
+// startRequest runs a loader for a screen, cancelling the screen's previous request
+func (m *model) startRequest(screen, text string, load func(ctx context.Context) tea.Cmd) tea.Cmd {
+	m.cancelRequests(screen)
+	// A new request of the screen replaces the failure of the previous one
+	m.clearFailure(screen)
+	if m.requests == nil {
+		m.requests = make(map[int]*loadRequest)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.nextRequestID++
+	id := m.nextRequestID
+	m.requests[id] = &loadRequest{screen: screen, text: text, load: load, cancel: cancel}
+
+	cmd := load(ctx)
+	return tea.Batch(func() tea.Msg { return requestMsg{id: id, msg: cmd()} }, m.spinner.Tick)
+}
+
+// finishRequest returns the request of a response and forgets it, nil when the request was cancelled or replaced
+func (m *model) finishRequest(id int) *loadRequest {
+	request, ok := m.requests[id]
+	if !ok {
+		return nil
+	}
+	request.cancel()
+	delete(m.requests, id)
+	return request
+}
+
+// applyResponse applies the response of a request, a failed request can be retried from the failure panel
+func (m *model) applyResponse(request *loadRequest, msg tea.Msg) tea.Cmd {
+	if err, failed := msg.(errorMsg); failed {
+		m.showFailure(err, request.screen, func(m *model) tea.Cmd {
+			return m.startRequest(request.screen, request.text, request.load)
+		})
+		return nil
+	}
+	updated, cmd := m.Update(msg)
+	*m = updated.(model)
+	return cmd
+}
+
+// cancelRequests cancels the requests of a screen, reporting whether any was in flight
+func (m *model) cancelRequests(screen string) bool {
+	cancelled := false
+	for id, request := range m.requests {
+		if request.screen == screen {
+			request.cancel()
+			delete(m.requests, id)
+			cancelled = true
+		}
+	}
+	return cancelled
+}
+
+// screenContext returns the context of a screen's background loads, e.g. the roles list and the bulk load of the
+// roles screen. Leaving the screen or opening another account cancels it, later loads get a new one.
+func (m *model) screenContext(screen string) context.Context {
+	if load, ok := m.screenLoads[screen]; ok {
+		return load.ctx
+	}
+	if m.screenLoads == nil {
+		m.screenLoads = make(map[string]*screenLoad)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.screenLoads[screen] = &screenLoad{ctx: ctx, cancel: cancel}
+	return ctx
+}
+
+// cancelScreen cancels the requests and the background loads of a screen
+func (m *model) cancelScreen(screen string) {
+	m.cancelRequests(screen)
+	if load, ok := m.screenLoads[screen]; ok {
+		load.cancel()
+		delete(m.screenLoads, screen)
+	}
+}
+
+// loading reports whether any request is in flight
+func (m model) loading() bool {
+	return len(m.requests) > 0
+}
+
+// requestText is the progress line of the current screen's request, empty when it has none
+func (m model) requestText() string {
+	for _, request := range m.requests {
+		if request.screen == m.currentScreen {
+			return fmt.Sprintf("%s %s (esc to cancel)", m.spinner.View(), request.text)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// requestResponse runs the loader of a started request and returns its tagged response
+func requestResponse(t *testing.T, cmd tea.Cmd) tea.Msg {
+	t.Helper()
+	batch, ok := cmd().(tea.BatchMsg)
+	if !ok || len(batch) == 0 {
+		t.Fatalf("Expected the request and the spinner, got %T", batch)
+	}
+	return batch[0]()
+}
+
+// Test Esc cancels a request through its context and drops its response
+func TestRequestCancelledWithEsc(t *testing.T) {
+	m := createTestModel()
+	m.currentScreen = "policy_document"
+	m.selectedPolicy = &PolicyItem{policyName: "AppPolicy"}
+
+	var requestCtx context.Context
+	cmd := m.startRequest("policy_document", "Loading policy document for AppPolicy...", func(ctx context.Context) tea.Cmd {
+		requestCtx = ctx
+		return func() tea.Msg { return policyDocumentLoadedMsg{document: `{"Version":"2012-10-17"}`} }
+	})
+	if !m.loading() || !strings.HasSuffix(m.requestText(), "Loading policy document for AppPolicy... (esc to cancel)") {
+		t.Fatalf("Expected the progress on the document screen, got '%s'", m.requestText())
+	}
+	response := requestResponse(t, cmd)
+
+	newModel, _ := m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	m = newModel.(model)
+	if m.loading() || m.currentScreen != "policies" || requestCtx.Err() == nil {
+		t.Fatalf("Expected Esc to leave the screen and cancel the request")
+	}
+
+	// The response arrives after Esc
+	newModel, _ = m.Update(response)
+	m = newModel.(model)
+	if m.policyDocument != "" {
+		t.Errorf("Expected the cancelled response to be dropped, got '%s'", m.policyDocument)
+	}
+}
+
+// Test a request replaces the previous request of its screen, whose response is stale
+func TestRequestReplaced(t *testing.T) {
+	f := newTestBackend()
+	f.addRole(fakeRole{name: "OtherRole"})
+	m := createTestModel()
+	m.backend = f
+	first, second := &RoleItem{roleName: "TestRole"}, &RoleItem{roleName: "OtherRole"}
+	m.rolesList.SetItems([]list.Item{first, second})
+
+	newModel, firstCmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = newModel.(model)
+	m.currentScreen = "roles"
+	m.rolesList.Select(1)
+	newModel, secondCmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = newModel.(model)
+	if len(m.requests) != 1 || m.selectedRole != second {
+		t.Fatalf("Expected one request for the second role, got %d", len(m.requests))
+	}
+
+	newModel, _ = m.Update(requestResponse(t, firstCmd))
+	m = newModel.(model)
+	if len(m.policiesList.Items()) != 0 || second.policiesLoaded {
+		t.Errorf("Expected the policies of the first role to be dropped")
+	}
+	newModel, _ = m.Update(requestResponse(t, secondCmd))
+	m = newModel.(model)
+	if !second.policiesLoaded || m.loading() || m.requestText() != "" {
+		t.Errorf("Expected the second role's policies to load")
+	}
+}
+
+// Test Esc on the roles screen keeps the role listing and the bulk load, switching profile cancels them
+func TestEscKeepsRolesLoads(t *testing.T) {
+	m := createTestModel()
+	m.backend = newTestBackend()
+	m.startRefresh()
+	ctx, stream, load := m.screenContext("roles"), m.rolesStream, m.accountLoad
+	var requestCtx context.Context
+	m.startRequest("roles", "Exporting snapshot...", func(ctx context.Context) tea.Cmd {
+		requestCtx = ctx
+		return nil
+	})
+
+	newModel, _ := m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	m = newModel.(model)
+	if requestCtx.Err() == nil || m.loading() {
+		t.Errorf("Expected Esc to cancel the request of the roles screen")
+	}
+	if ctx.Err() != nil || m.rolesStream != stream || m.accountLoad != load {
+		t.Fatalf("Expected the roles and the account to keep loading")
+	}
+
+	m.switchProfile("other")
+	if ctx.Err() == nil {
+		t.Errorf("Expected switching profile to cancel the loads of the previous profile")
+	}
+	if m.screenContext("roles").Err() != nil {
+		t.Errorf("Expected later loads to get a new context")
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// rolesStream is a roles list loading page by page
+type rolesStream struct {
+	live   bool       // Pages are shown as they arrive, the list was empty when loading started
+	roles  []RoleItem // Pages kept until the last one when the list shows other data meanwhile
+	loaded int
+	cancel context.CancelFunc // Stops the pager once the stream was replaced or reset
+}
+
+// rolesPageMsg is sent for every page of roles, or when loading a page failed
+type rolesPageMsg struct {
+	stream *rolesStream
+	roles  []RoleItem
+	next   tea.Cmd // Loads the next page, nil after the last page
+	err    error
+}
+
+// loadIAMRolesCmd loads the first page of roles, each page message loads the next one
+func loadIAMRolesCmd(ctx context.Context, session awsSession, stream *rolesStream) tea.Cmd {
+	return func() tea.Msg {
+		// Load AWS configuration with shared config
+		cfg, err := session.loadConfig(ctx)
+		if err != nil {
+			return rolesPageMsg{stream: stream, err: fmt.Errorf("error loading AWS configuration: %w", err)}
+		}
+
+		paginator := iam.NewListRolesPaginator(session.iamClient(cfg), &iam.ListRolesInput{})
+		return loadRolesPageCmd(ctx, stream, paginator)()
+	}
+}
+
+// loadRolesPageCmd loads the next page of a roles paginator
+func loadRolesPageCmd(ctx context.Context, stream *rolesStream, paginator *iam.ListRolesPaginator) tea.Cmd {
+	return func() tea.Msg {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return rolesPageMsg{stream: stream, err: fmt.Errorf("error listing IAM roles: %w", err)}
+		}
+		msg := rolesPageMsg{stream: stream, roles: roleItems(page.Roles)}
+		if paginator.HasMorePages() {
+			msg.next = loadRolesPageCmd(ctx, stream, paginator)
+		}
+		return msg
+	}
+}
+
+// startRolesStream starts loading the roles list, replacing a running load
+func (m *model) startRolesStream(session awsSession) tea.Cmd {
+	m.stopRolesStream()
+	ctx, cancel := context.WithCancel(m.screenContext("roles"))
+	// Pages are appended right away unless cached roles are shown until the new list is complete
+	m.rolesStream = &rolesStream{live: len(m.rolesList.Items()) == 0, cancel: cancel}
+	return loadIAMRolesCmd(ctx, session, m.rolesStream)
+}
+
+// stopRolesStream stops loading the roles list, pages already requested are dropped
+func (m *model) stopRolesStream() {
+	if m.rolesStream != nil {
+		m.rolesStream.cancel()
+		m.rolesStream = nil
+	}
+}
+
+// addRolesPage shows a page of roles and loads the next one
+func (m *model) addRolesPage(msg rolesPageMsg) tea.Cmd {
+	stream := msg.stream
+	// Pages of a replaced load, e.g. of the previous profile, are dropped
+	if stream != m.rolesStream {
+		return nil
+	}
+	if msg.err != nil {
+		// Roles loaded so far stay listed
+		m.stopRolesStream()
+		m.showFailure(msg.err, "", func(m *model) tea.Cmd {
+			m.refreshPending++
+			return m.startRolesStream(m.session())
+		})
+		return m.finishRefreshStep()
+	}
+	stream.loaded += len(msg.roles)
+
+	var cmds []tea.Cmd
+	if stream.live && !m.aggregated() {
+		items := m.rolesList.Items()
+		for i := range msg.roles {
+			items = append(items, &msg.roles[i])
+		}
+		// The list filters the new items when a filter is applied
+		cmds = append(cmds, m.rolesList.SetItems(items))
+	} else {
+		stream.roles = append(stream.roles, msg.roles...)
+	}
+	if msg.next != nil {
+		return tea.Batch(append(cmds, msg.next)...)
+	}
+
+	m.stopRolesStream()
+	if !stream.live || m.aggregated() {
+		updated, cmd := m.Update(rolesLoadedMsg(stream.roles))
+		*m = updated.(model)
+		return tea.Batch(append(cmds, cmd)...)
+	}
+	// Roles listed after the bulk load finished still get its details
+	m.mergeAccountRoles()
+	return tea.Batch(append(cmds, m.finishRefreshStep(), m.startPolicyCounts())...)
+}
+
+// progressText is the header counter of a live load
+func (s *rolesStream) progressText() string {
+	return fmt.Sprintf("Loaded %s roles…", formatThousands(s.loaded))
+}
+
+// formatThousands formats a count with thousands separators, e.g. 1,200
+func formatThousands(n int) string {
+	digits := strconv.Itoa(n)
+	for i := len(digits) - 3; i > 0; i -= 3 {
+		digits = digits[:i] + "," + digits[i:]
+	}
+	return digits
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// collectRolePages loads every page of a roles load and returns all roles
+func collectRolePages(t *testing.T, cmd tea.Cmd) []RoleItem {
+	t.Helper()
+	var roles []RoleItem
+	for cmd != nil {
+		page, ok := cmd().(rolesPageMsg)
+		if !ok {
+			t.Fatalf("Expected a page of roles")
+		}
+		roles = append(roles, page.roles...)
+		cmd = page.next
+	}
+	return roles
+}
+
+// nextPage runs the command that loads the next page, batched with the list's filter command when filtering
+func nextPage(cmd tea.Cmd) tea.Msg {
+	msg := cmd()
+	if batch, ok := msg.(tea.BatchMsg); ok {
+		return batch[len(batch)-1]()
+	}
+	return msg
+}
+
+// Test pages are appended while they arrive and the counter shows until the last page
+func TestRolesStreamLive(t *testing.T) {
+	f := newTestBackend()
+	f.addRole(fakeRole{name: "OtherRole"})
+	f.addRole(fakeRole{name: "ThirdRole"})
+	f.pageSize = 2
+
+	m := createTestModel()
+	m.backend = f
+	m.refreshPending = 1
+	cmd := m.startRolesStream(m.session())
+	if !m.rolesStream.live {
+		t.Fatalf("Expected an empty list to show pages as they arrive")
+	}
+
+	newModel, next := m.Update(cmd())
+	m = newModel.(model)
+	if len(m.rolesList.Items()) != 2 || m.rolesStream == nil || m.rolesStream.progressText() != "Loaded 2 roles…" {
+		t.Fatalf("Expected the first page shown while loading, got %d roles", len(m.rolesList.Items()))
+	}
+	first := m.rolesList.Items()[0]
+
+	newModel, _ = m.Update(nextPage(next))
+	m = newModel.(model)
+	if len(m.rolesList.Items()) != 3 || m.rolesStream != nil || m.refreshPending != 0 {
+		t.Fatalf("Expected all roles and the refresh done, got %d roles", len(m.rolesList.Items()))
+	}
+	if m.rolesList.Items()[0] != first {
+		t.Errorf("Expected roles shown earlier to stay the same items")
+	}
+}
+
+// Test a list showing cached roles is replaced once the last page arrived, and pages of a replaced load are dropped
+func TestRolesStreamReplacesCachedRoles(t *testing.T) {
+	f := newTestBackend()
+	f.addRole(fakeRole{name: "OtherRole"})
+	f.pageSize = 1
+
+	m := createTestModel()
+	m.backend = f
+	m.rolesList.SetItems([]list.Item{&RoleItem{roleName: "CachedRole"}})
+	stale := m.startRolesStream(m.session())
+	cmd := m.startRolesStream(m.session())
+
+	newModel, _ := m.Update(stale())
+	m = newModel.(model)
+	newModel, next := m.Update(cmd())
+	m = newModel.(model)
+	if len(m.rolesList.Items()) != 1 || m.rolesList.Items()[0].(*RoleItem).roleName != "CachedRole" {
+		t.Fatalf("Expected the cached roles to stay until the last page")
+	}
+
+	newModel, _ = m.Update(nextPage(next))
+	m = newModel.(model)
+	var names []string
+	for _, item := range m.rolesList.Items() {
+		names = append(names, item.(*RoleItem).roleName)
+	}
+	if strings.Join(names, ",") != "OtherRole,TestRole" {
+		t.Errorf("Expected the loaded roles only, got %v", names)
+	}
+}
+
+// Test the pager of a replaced or reset stream is stopped, so it does not list the remaining pages
+func TestRolesStreamStopped(t *testing.T) {
+	m := createTestModel()
+	m.backend = newTestBackend()
+	cmd := m.startRolesStream(m.session())
+	stopped := 0
+	m.rolesStream.cancel = func() { stopped++ }
+
+	m.startRolesStream(m.session())
+	m.rolesStream.cancel = func() { stopped++ }
+	m.switchProfile("other")
+	if stopped != 2 || m.rolesStream != nil {
+		t.Fatalf("Expected both streams to be stopped, got %d", stopped)
+	}
+
+	// A page already requested is dropped
+	newModel, _ := m.Update(cmd())
+	if len(newModel.(model).rolesList.Items()) != 0 {
+		t.Errorf("Expected the page of the stopped stream to be dropped")
+	}
+}
+
+// Test counts get thousands separators
+func TestFormatThousands(t *testing.T) {
+	for n, expected := range map[int]string{0: "0", 999: "999", 1200: "1,200", 1234567: "1,234,567"} {
+		if text := formatThousands(n); text != expected {
+			t.Errorf("Expected %s, got %s", expected, text)
+		}
+	}
+}
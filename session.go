@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+)
+
+// awsSession is the identity AWS requests are made with
+type awsSession struct {
+	profile     string                  // Shared config profile, empty for the environment default
+	region      string                  // Region picked in the TUI, empty for the profile's region
+	credentials aws.CredentialsProvider // Temporary credentials of an assumed role, nil for the profile's own
+	backend     awsBackend              // Where requests go, nil for AWS
+}
+
+// api returns the backend of the session
+func (s awsSession) api() awsBackend {
+	if s.backend == nil {
+		return sdkBackend{}
+	}
+	return s.backend
+}
+
+// loadConfig loads the AWS configuration of the session
+func (s awsSession) loadConfig(ctx context.Context) (aws.Config, error) {
+	cfg, err := s.api().loadConfig(ctx, s.profile, s.region)
+	if err != nil {
+		return cfg, err
+	}
+	if s.credentials != nil {
+		cfg.Credentials = s.credentials
+	}
+	return cfg, nil
+}
+
+// iamClient creates the IAM client of a configuration loaded for the session
+func (s awsSession) iamClient(cfg aws.Config) iamAPI {
+	return s.api().iamClient(cfg)
+}
+
+// stsClient creates the STS client of a configuration loaded for the session
+func (s awsSession) stsClient(cfg aws.Config) stsAPI {
+	return s.api().stsClient(cfg)
+}
+
+// organizationsClient creates the Organizations client of a configuration loaded for the session
+func (s awsSession) organizationsClient(cfg aws.Config) organizationsAPI {
+	return s.api().organizationsClient(cfg)
+}
+
+// loadAWSConfig loads the shared AWS configuration of a profile, or the environment default when profile is empty,
+// in a region or the profile's region when region is empty. MFA codes of the profile are asked for in the TUI.
+func loadAWSConfig(ctx context.Context, profile, region string) (aws.Config, error) {
+	options := []func(*config.LoadOptions) error{
+		config.WithAssumeRoleCredentialOptions(func(o *stscreds.AssumeRoleOptions) {
+			if o.SerialNumber != nil {
+				o.TokenProvider = mfaTokenProvider(profile, aws.ToString(o.SerialNumber))
+			}
+		}),
+		config.WithRetryer(apiCalls.retryer),
+	}
+	if profile != "" {
+		options = append(options, config.WithSharedConfigProfile(profile))
+	}
+	if region != "" {
+		options = append(options, config.WithRegion(region))
+	}
+	if endpointOverride != "" {
+		// Also used by the STS and SSO clients the SDK creates for credentials
+		options = append(options, config.WithBaseEndpoint(endpointOverride))
+	}
+
+	configMu.Lock()
+	cfg, err := config.LoadDefaultConfig(ctx, options...)
+	configMu.Unlock()
+	if err != nil {
+		return cfg, err
+	}
+	if cfg.Region == "" {
+		// STS is called at a regional endpoint, IAM is global
+		cfg.Region = defaultRegion
+	}
+	if endpoint := configEndpoint(profile); endpoint != "" && cfg.BaseEndpoint == nil {
+		// Set after loading so AWS_ENDPOINT_URL_<SERVICE> still takes precedence in each client
+		cfg.BaseEndpoint = aws.String(endpoint)
+	}
+	// The login waits for approval in the browser, so it only blocks loaders of the same SSO session
+	if err := ensureSSOLogin(ctx, cfg); err != nil {
+		return cfg, err
+	}
+
+	// Loaders of a profile share one MFA session, so the user is only asked once
+	configMu.Lock()
+	defer configMu.Unlock()
+	if cached, ok := mfaSessions[profile]; ok {
+		cfg.Credentials = cached
+		return cfg, nil
+	}
+	if provider := mfaSessionProvider(cfg, profile); provider != nil {
+		cfg.Credentials = provider
+		mfaSessions[profile] = provider
+	}
+	return cfg, nil
+}
+
+// sharedConfigOf returns the shared config profile a configuration was loaded from, empty without one
+func sharedConfigOf(cfg aws.Config) config.SharedConfig {
+	for _, source := range cfg.ConfigSources {
+		if shared, ok := source.(config.SharedConfig); ok {
+			return shared
+		}
+	}
+	return config.SharedConfig{}
+}
+
+// session returns the identity of the current account view, the last assumed role if any
+func (m model) session() awsSession {
+	session := m.profileSession(m.sessionProfile)
+	if len(m.identities) > 0 {
+		session = m.identities[len(m.identities)-1].session
+		session.region = m.region
+	}
+	return session
+}
+
+// viewKey names the account view: its generation, the profile and the chain of assumed roles. Loads of the view are
+// tagged with it and dropped once the view was reset, even when the same profile is shown again.
+func (m model) viewKey() string {
+	key := fmt.Sprintf("%d:%s", m.viewGeneration, m.sessionProfile)
+	for _, identity := range m.identities {
+		key += " › " + identity.arn
+	}
+	return key
+}
+
+// profileSession returns the session of a profile's own credentials in the picked region
+func (m model) profileSession(profile string) awsSession {
+	return awsSession{profile: profile, region: m.region, backend: m.backend}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// snapshotVersion is bumped whenever the snapshot format changes incompatibly
+const snapshotVersion = 1
+
+// snapshot is a versioned export of the full IAM state atui can load
+type snapshot struct {
+	Version   int           `json:"version"`
+	CreatedAt time.Time     `json:"createdAt"`
+	Profile   string        `json:"profile,omitempty"`
+	AccountID string        `json:"accountId,omitempty"`
+	UserArn   string        `json:"userArn,omitempty"`
+	Complete  bool          `json:"complete"` // False when the bulk account load was unavailable
+	Account   accountRecord `json:"account"`
+}
+
+// snapshotExportedMsg reports the result of a snapshot export
+type snapshotExportedMsg struct {
+	path string
+	err  error
+}
+
+// readSnapshot loads a snapshot file and checks its version
+func readSnapshot(path string) (*snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+
+	var snap snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot JSON: %w", err)
+	}
+	if snap.Version == 0 || snap.Version > snapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d", snap.Version)
+	}
+	return &snap, nil
+}
+
+// writeSnapshot saves a snapshot as indented JSON
+func writeSnapshot(path string, snap snapshot) error {
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal snapshot: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	return nil
+}
+
+// Export a snapshot to a file
+func exportSnapshotCmd(path string, snap snapshot) tea.Cmd {
+	return func() tea.Msg {
+		return snapshotExportedMsg{path: path, err: writeSnapshot(path, snap)}
+	}
+}
+
+// currentSnapshot captures the loaded data together with its identity metadata
+func (m model) currentSnapshot() snapshot {
+	return snapshot{
+		Version:   snapshotVersion,
+		CreatedAt: time.Now().UTC(),
+		Profile:   m.currentProfile,
+		AccountID: m.accountID,
+		UserArn:   m.userArn,
+		Complete:  m.account != nil,
+		Account:   m.currentAccountData().record(),
+	}
+}
+
+// snapshotFileName returns the default file name for a new snapshot
+func snapshotFileName(accountID string, createdAt time.Time) string {
+	if accountID == "" {
+		accountID = "unknown"
+	}
+	return fmt.Sprintf("atui-snapshot-%s-%s.json", accountID, createdAt.Format("20060102-150405"))
+}
+
+// openSnapshot switches the model to browse a snapshot without AWS access
+func (m *model) openSnapshot(path string, snap *snapshot) {
+	m.offline = true
+	m.sourceLabel = fmt.Sprintf("Snapshot: %s", filepath.Base(path))
+	m.currentProfile = snap.Profile
+	m.accountID = snap.AccountID
+	m.userArn = snap.UserArn
+	m.showAccountData(snap.Account.data())
+	m.statusMsg = fmt.Sprintf("Browsing snapshot taken %s", snap.CreatedAt.Local().Format("2006-01-02 15:04"))
+}
+
+// showAccountData replaces the roles list with the roles of loaded account data
+func (m *model) showAccountData(data *accountData) {
+	m.account = data
+	items := []list.Item{}
+	for _, role := range data.roles {
+		roleCopy := role // Create a copy to avoid issues with loop variables in closures
+		items = append(items, &roleCopy)
+	}
+	m.rolesList.SetItems(items)
+}
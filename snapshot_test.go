@@ -0,0 +1,100 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Test exporting and reading back a snapshot
+func TestSnapshotRoundTrip(t *testing.T) {
+	m := createTestModel()
+	m.currentProfile = "prod"
+	m.accountID = "123456789012"
+	m.userArn = "arn:aws:iam::123456789012:user/alice"
+	m.rolesList.SetItems([]list.Item{&RoleItem{
+		roleName:       "AppRole",
+		trustPolicy:    `{"Version":"2012-10-17"}`,
+		policies:       []PolicyItem{{policyName: "Inline", policyType: "Inline", rawDocument: "{}"}},
+		policiesLoaded: true,
+	}})
+	m.account = &accountData{groups: []GroupItem{{groupName: "admins"}}}
+
+	snap := m.currentSnapshot()
+	path := filepath.Join(t.TempDir(), snapshotFileName(snap.AccountID, snap.CreatedAt))
+	msg := exportSnapshotCmd(path, snap)().(snapshotExportedMsg)
+	if msg.err != nil {
+		t.Fatalf("Expected export to succeed, got %v", msg.err)
+	}
+
+	loaded, err := readSnapshot(path)
+	if err != nil {
+		t.Fatalf("Expected snapshot to be readable, got %v", err)
+	}
+	if loaded.Version != snapshotVersion || !loaded.Complete || loaded.Profile != "prod" || loaded.UserArn != m.userArn {
+		t.Errorf("Expected snapshot metadata to be kept, got %+v", loaded)
+	}
+	role, ok := loaded.Account.data().findRole("AppRole")
+	if !ok || role.trustPolicy != `{"Version":"2012-10-17"}` || len(role.policies) != 1 {
+		t.Errorf("Expected role with trust policy and policies, got %+v", role)
+	}
+	if len(loaded.Account.Groups) != 1 {
+		t.Errorf("Expected groups to be exported")
+	}
+}
+
+// Test rejecting snapshots of unknown versions
+func TestReadSnapshotVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	if err := os.WriteFile(path, []byte(`{"version": 99, "account": {}}`), 0600); err != nil {
+		t.Fatalf("Failed to write snapshot: %v", err)
+	}
+
+	if _, err := readSnapshot(path); err == nil || !strings.Contains(err.Error(), "unsupported snapshot version") {
+		t.Errorf("Expected unsupported version error, got %v", err)
+	}
+}
+
+// Test browsing a snapshot without AWS access
+func TestOpenSnapshot(t *testing.T) {
+	m := createTestModel()
+	snap := &snapshot{
+		Version:   snapshotVersion,
+		CreatedAt: time.Now(),
+		Profile:   "prod",
+		Account: accountRecord{Roles: []roleRecord{
+			{RoleName: "Loaded", PoliciesLoaded: true, Policies: []policyRecord{{PolicyName: "Inline", PolicyType: "Inline", Document: "{}"}}},
+			{RoleName: "Unloaded"},
+		}},
+	}
+	m.openSnapshot("/tmp/prod.json", snap)
+
+	if !m.offline || m.sourceLabel != "Snapshot: prod.json" {
+		t.Errorf("Expected offline snapshot mode, got label '%s'", m.sourceLabel)
+	}
+	if len(m.rolesList.Items()) != 2 {
+		t.Fatalf("Expected 2 roles, got %d", len(m.rolesList.Items()))
+	}
+
+	// Roles without policies in the snapshot never hit AWS
+	m.rolesList.Select(1)
+	newModel, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	updatedModel := newModel.(model)
+	if cmd != nil || updatedModel.loading() {
+		t.Errorf("Expected no AWS request while offline")
+	}
+
+	// Policy documents open from the snapshot
+	m.rolesList.Select(0)
+	newModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	newModel, cmd = newModel.(model).Update(tea.KeyMsg{Type: tea.KeyEnter})
+	updatedModel = newModel.(model)
+	if cmd != nil || updatedModel.currentScreen != "policy_document" || !updatedModel.selectedPolicy.documentLoaded {
+		t.Errorf("Expected policy document to open from the snapshot")
+	}
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/ssocreds"
+	"github.com/aws/aws-sdk-go-v2/service/ssooidc"
+	ssotypes "github.com/aws/aws-sdk-go-v2/service/ssooidc/types"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ssoLogin is a running device authorization of IAM Identity Center
+type ssoLogin struct {
+	startURL        string
+	verificationURL string // Page to approve the login on
+	userCode        string // Code shown on the page
+	cancel          context.CancelFunc
+}
+
+// ssoLoginStartedMsg is sent when a login waits for approval in the browser
+type ssoLoginStartedMsg struct {
+	login *ssoLogin
+}
+
+// ssoLoginFinishedMsg is sent when a login was approved, failed or cancelled
+type ssoLoginFinishedMsg struct {
+	login *ssoLogin
+	err   error
+}
+
+var (
+	// ssoLoginsMu guards ssoLogins
+	ssoLoginsMu sync.Mutex
+	// ssoLogins serializes logins by token cache key, so loaders of one SSO session wait for a single login
+	ssoLogins = make(map[string]*sync.Mutex)
+)
+
+// ssoLoginLock returns the lock of logins to the SSO session or start URL of a token cache key
+func ssoLoginLock(cacheKey string) *sync.Mutex {
+	ssoLoginsMu.Lock()
+	defer ssoLoginsMu.Unlock()
+	lock, ok := ssoLogins[cacheKey]
+	if !ok {
+		lock = &sync.Mutex{}
+		ssoLogins[cacheKey] = lock
+	}
+	return lock
+}
+
+// ssoEvents carries login progress from configuration loading to the TUI
+var ssoEvents = make(chan tea.Msg)
+
+// Wait for the next SSO login event
+func waitForSSOEventCmd() tea.Cmd {
+	return func() tea.Msg {
+		return <-ssoEvents
+	}
+}
+
+// ssoCachedToken is the token cache file written by the AWS CLI and read by the SDK
+type ssoCachedToken struct {
+	StartURL              string `json:"startUrl"`
+	Region                string `json:"region"`
+	AccessToken           string `json:"accessToken"`
+	ExpiresAt             string `json:"expiresAt"`
+	ClientID              string `json:"clientId,omitempty"`
+	ClientSecret          string `json:"clientSecret,omitempty"`
+	RegistrationExpiresAt string `json:"registrationExpiresAt,omitempty"`
+	RefreshToken          string `json:"refreshToken,omitempty"`
+}
+
+// ensureSSOLogin logs in to IAM Identity Center when the profile of a configuration uses SSO without a usable token
+func ensureSSOLogin(ctx context.Context, cfg aws.Config) error {
+	shared := sharedConfigOf(cfg)
+
+	// Sessions are cached by sso-session name, legacy profiles by start URL
+	startURL, region, cacheKey := shared.SSOStartURL, shared.SSORegion, shared.SSOStartURL
+	var scopes []string
+	if shared.SSOSession != nil {
+		startURL, region, cacheKey = shared.SSOSession.SSOStartURL, shared.SSOSession.SSORegion, shared.SSOSession.Name
+		scopes = []string{"sso:account:access"}
+	}
+	if startURL == "" {
+		return nil
+	}
+
+	path, err := ssocreds.StandardCachedTokenFilepath(cacheKey)
+	if err != nil {
+		return err
+	}
+	// A loader that waited for the login of another one finds its token
+	lock := ssoLoginLock(cacheKey)
+	lock.Lock()
+	defer lock.Unlock()
+
+	// Refresh tokens are only used by the SDK for sso-session profiles
+	if ssoTokenUsable(path, shared.SSOSession != nil, time.Now()) {
+		return nil
+	}
+
+	client := ssooidc.NewFromConfig(cfg, func(o *ssooidc.Options) {
+		o.Region = region
+	})
+	return ssoDeviceLogin(ctx, client, path, startURL, region, scopes)
+}
+
+// ssoTokenUsable reports whether a cached token is valid or can be refreshed
+func ssoTokenUsable(path string, refreshable bool, now time.Time) bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	var token ssoCachedToken
+	if err := json.Unmarshal(data, &token); err != nil {
+		return false
+	}
+	if expiresAt, err := time.Parse(time.RFC3339, token.ExpiresAt); err == nil && token.AccessToken != "" && expiresAt.After(now.Add(time.Minute)) {
+		return true
+	}
+	if !refreshable || token.RefreshToken == "" {
+		return false
+	}
+	registrationExpiresAt, err := time.Parse(time.RFC3339, token.RegistrationExpiresAt)
+	return err == nil && registrationExpiresAt.After(now)
+}
+
+// ssoDeviceLogin runs the OIDC device authorization, showing the code in the TUI, and caches the token
+func ssoDeviceLogin(ctx context.Context, client *ssooidc.Client, path, startURL, region string, scopes []string) error {
+	registration, err := client.RegisterClient(ctx, &ssooidc.RegisterClientInput{
+		ClientName: aws.String("atui"),
+		ClientType: aws.String("public"),
+		Scopes:     scopes,
+	})
+	if err != nil {
+		return fmt.Errorf("error registering SSO client: %w", err)
+	}
+	authorization, err := client.StartDeviceAuthorization(ctx, &ssooidc.StartDeviceAuthorizationInput{
+		ClientId:     registration.ClientId,
+		ClientSecret: registration.ClientSecret,
+		StartUrl:     aws.String(startURL),
+	})
+	if err != nil {
+		return fmt.Errorf("error starting SSO login: %w", err)
+	}
+
+	verificationURL := aws.ToString(authorization.VerificationUriComplete)
+	if verificationURL == "" {
+		verificationURL = aws.ToString(authorization.VerificationUri)
+	}
+	loginCtx, cancel := context.WithTimeout(ctx, time.Duration(authorization.ExpiresIn)*time.Second)
+	defer cancel()
+	login := &ssoLogin{startURL: startURL, verificationURL: verificationURL, userCode: aws.ToString(authorization.UserCode), cancel: cancel}
+	ssoEvents <- ssoLoginStartedMsg{login: login}
+
+	token, err := pollSSOToken(loginCtx, client, registration, authorization)
+	if err == nil {
+		now := time.Now()
+		err = writeSSOToken(path, ssoCachedToken{
+			StartURL:              startURL,
+			Region:                region,
+			AccessToken:           aws.ToString(token.AccessToken),
+			ExpiresAt:             now.Add(time.Duration(token.ExpiresIn) * time.Second).UTC().Format(time.RFC3339),
+			ClientID:              aws.ToString(registration.ClientId),
+			ClientSecret:          aws.ToString(registration.ClientSecret),
+			RegistrationExpiresAt: time.Unix(registration.ClientSecretExpiresAt, 0).UTC().Format(time.RFC3339),
+			RefreshToken:          aws.ToString(token.RefreshToken),
+		})
+	}
+	ssoEvents <- ssoLoginFinishedMsg{login: login, err: err}
+	return err
+}
+
+// ssoTokenCreator creates tokens of an OIDC device authorization
+type ssoTokenCreator interface {
+	CreateToken(ctx context.Context, params *ssooidc.CreateTokenInput, optFns ...func(*ssooidc.Options)) (*ssooidc.CreateTokenOutput, error)
+}
+
+// pollSSOToken waits until the device authorization is approved, at the interval the service asks for
+func pollSSOToken(ctx context.Context, client ssoTokenCreator, registration *ssooidc.RegisterClientOutput, authorization *ssooidc.StartDeviceAuthorizationOutput) (*ssooidc.CreateTokenOutput, error) {
+	interval := time.Duration(authorization.Interval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return nil, fmt.Errorf("SSO login was not approved in time")
+			}
+			return nil, fmt.Errorf("SSO login cancelled")
+		case <-time.After(interval):
+		}
+
+		token, err := client.CreateToken(ctx, &ssooidc.CreateTokenInput{
+			ClientId:     registration.ClientId,
+			ClientSecret: registration.ClientSecret,
+			DeviceCode:   authorization.DeviceCode,
+			GrantType:    aws.String("urn:ietf:params:oauth:grant-type:device_code"),
+		})
+		var pending *ssotypes.AuthorizationPendingException
+		var slowDown *ssotypes.SlowDownException
+		switch {
+		case err == nil:
+			return token, nil
+		case errors.As(err, &pending):
+		case errors.As(err, &slowDown):
+			interval += 5 * time.Second
+		case ctx.Err() != nil:
+			// Cancelled while the request was running, reported by the next loop
+		default:
+			return nil, fmt.Errorf("error completing SSO login: %w", err)
+		}
+	}
+}
+
+// writeSSOToken writes a token to the cache shared with the AWS CLI
+func writeSSOToken(path string, token ssoCachedToken) error {
+	data, err := json.MarshalIndent(token, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("error creating SSO cache directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("error writing SSO token cache: %w", err)
+	}
+	return nil
+}
+
+// openSSOLogin shows the login code over the current screen
+func (m *model) openSSOLogin(login *ssoLogin) {
+	m.ssoLogin = login
+	if m.currentScreen != "sso" {
+		m.ssoReturnScreen = m.currentScreen
+	}
+	m.currentScreen = "sso"
+	updateKeyBindingsForScreen(m.currentScreen)
+	m.statusMsg = "Waiting for the login to be approved in the browser..."
+}
+
+// closeSSOLogin returns to the screen the login was shown over
+func (m *model) closeSSOLogin() {
+	m.ssoLogin = nil
+	m.currentScreen = m.ssoReturnScreen
+	updateKeyBindingsForScreen(m.currentScreen)
+}
+
+// finishSSOLogin reports the end of a login and waits for the next one
+func (m *model) finishSSOLogin(msg ssoLoginFinishedMsg) tea.Cmd {
+	if msg.login == m.ssoLogin {
+		m.closeSSOLogin()
+	}
+	if msg.err != nil {
+		m.statusMsg = msg.err.Error()
+	} else {
+		m.statusMsg = fmt.Sprintf("Logged in to %s", msg.login.startURL)
+	}
+	return waitForSSOEventCmd()
+}
+
+// ssoLoginText describes how to approve the open login
+func (m model) ssoLoginText() string {
+	return fmt.Sprintf("Sign in to %s\n\n  Open %s\n  and confirm the code %s", m.ssoLogin.startURL, m.ssoLogin.verificationURL, m.ssoLogin.userCode)
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/ssocreds"
+	"github.com/aws/aws-sdk-go-v2/service/ssooidc"
+	ssotypes "github.com/aws/aws-sdk-go-v2/service/ssooidc/types"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Test checking cached SSO tokens
+func TestSSOTokenUsable(t *testing.T) {
+	now := time.Now()
+	path := filepath.Join(t.TempDir(), "cache", "token.json")
+	if ssoTokenUsable(path, true, now) {
+		t.Errorf("Expected a missing token to be unusable")
+	}
+
+	token := ssoCachedToken{
+		StartURL:    "https://example.awsapps.com/start",
+		Region:      "eu-west-1",
+		AccessToken: "access",
+		ExpiresAt:   now.Add(time.Hour).UTC().Format(time.RFC3339),
+	}
+	if err := writeSSOToken(path, token); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !ssoTokenUsable(path, false, now) {
+		t.Errorf("Expected a valid token to be usable")
+	}
+
+	// Expired tokens are only usable when the SDK can refresh them
+	token.ExpiresAt = now.Add(-time.Hour).UTC().Format(time.RFC3339)
+	token.RefreshToken = "refresh"
+	token.RegistrationExpiresAt = now.Add(24 * time.Hour).UTC().Format(time.RFC3339)
+	if err := writeSSOToken(path, token); err != nil {
+		t.Fatal(err)
+	}
+	if ssoTokenUsable(path, false, now) || !ssoTokenUsable(path, true, now) {
+		t.Errorf("Expected the refresh token to be used for sso-session profiles only")
+	}
+}
+
+// fakeTokenCreator answers CreateToken with a list of errors before succeeding
+type fakeTokenCreator struct {
+	errs  []error
+	calls int
+}
+
+func (f *fakeTokenCreator) CreateToken(ctx context.Context, params *ssooidc.CreateTokenInput, optFns ...func(*ssooidc.Options)) (*ssooidc.CreateTokenOutput, error) {
+	f.calls++
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+		return nil, err
+	}
+	return &ssooidc.CreateTokenOutput{AccessToken: aws.String("access"), ExpiresIn: 3600}, nil
+}
+
+// Test polling until the login is approved
+func TestPollSSOToken(t *testing.T) {
+	registration := &ssooidc.RegisterClientOutput{ClientId: aws.String("client")}
+	authorization := &ssooidc.StartDeviceAuthorizationOutput{DeviceCode: aws.String("device"), Interval: 1}
+
+	client := &fakeTokenCreator{errs: []error{&ssotypes.AuthorizationPendingException{}}}
+	token, err := pollSSOToken(context.Background(), client, registration, authorization)
+	if err != nil || aws.ToString(token.AccessToken) != "access" || client.calls != 2 {
+		t.Errorf("Expected the token after a pending answer, got %v after %d calls", err, client.calls)
+	}
+
+	client = &fakeTokenCreator{errs: []error{&ssotypes.AccessDeniedException{}}}
+	if _, err := pollSSOToken(context.Background(), client, registration, authorization); err == nil || !strings.Contains(err.Error(), "error completing SSO login") {
+		t.Errorf("Expected a denied login to fail, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := pollSSOToken(ctx, &fakeTokenCreator{}, registration, authorization); err == nil || err.Error() != "SSO login cancelled" {
+		t.Errorf("Expected a cancelled login, got %v", err)
+	}
+}
+
+// Test a login waiting for approval only blocks loaders of the same SSO session, which then reuse its token
+func TestSSOLoginLock(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	configFile := filepath.Join(dir, "config")
+	config := "[profile sso]\nsso_session = corp\nsso_account_id = 111111111111\nsso_role_name = Admin\nregion = eu-west-1\n\n[sso-session corp]\nsso_start_url = https://example.awsapps.com/start\nsso_region = eu-west-1\n\n[profile plain]\nregion = eu-west-1\n"
+	if err := os.WriteFile(configFile, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AWS_CONFIG_FILE", configFile)
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "credentials"))
+
+	// Another loader is logging in to the session
+	lock := ssoLoginLock("corp")
+	lock.Lock()
+	ssoDone := make(chan error, 1)
+	go func() {
+		_, err := loadAWSConfig(context.Background(), "sso", "")
+		ssoDone <- err
+	}()
+
+	plainDone := make(chan error, 1)
+	go func() {
+		_, err := loadAWSConfig(context.Background(), "plain", "")
+		plainDone <- err
+	}()
+	select {
+	case err := <-plainDone:
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected a profile without SSO to load during the login")
+	}
+	select {
+	case <-ssoDone:
+		t.Fatalf("Expected the loader of the session to wait for the login")
+	default:
+	}
+
+	// The login wrote the token
+	path, err := ssocreds.StandardCachedTokenFilepath("corp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := ssoCachedToken{AccessToken: "access", ExpiresAt: time.Now().Add(time.Hour).UTC().Format(time.RFC3339)}
+	if err := writeSSOToken(path, token); err != nil {
+		t.Fatal(err)
+	}
+	lock.Unlock()
+	if err := <-ssoDone; err != nil {
+		t.Errorf("Expected the waiting loader to use the new token, got %v", err)
+	}
+}
+
+// Test showing and cancelling the login modal
+func TestSSOLoginModal(t *testing.T) {
+	m := createTestModel()
+	cancelled := false
+	login := &ssoLogin{
+		startURL:        "https://example.awsapps.com/start",
+		verificationURL: "https://device.sso.eu-west-1.amazonaws.com/?user_code=ABCD-EFGH",
+		userCode:        "ABCD-EFGH",
+		cancel:          func() { cancelled = true },
+	}
+
+	newModel, cmd := m.Update(ssoLoginStartedMsg{login: login})
+	m = newModel.(model)
+	if cmd == nil || m.currentScreen != "sso" || !strings.Contains(m.ssoLoginText(), "ABCD-EFGH") {
+		t.Fatalf("Expected the login code to be shown, got screen '%s'", m.currentScreen)
+	}
+
+	newModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'p'}})
+	if newModel.(model).currentScreen != "sso" {
+		t.Errorf("Expected keys to be ignored while logging in")
+	}
+	newModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	m = newModel.(model)
+	if !cancelled || m.currentScreen != "roles" {
+		t.Errorf("Expected esc to cancel the login")
+	}
+
+	newModel, cmd = m.Update(ssoLoginFinishedMsg{login: login, err: context.Canceled})
+	if cmd == nil || newModel.(model).statusMsg != context.Canceled.Error() {
+		t.Errorf("Expected the login error in the status, got '%s'", newModel.(model).statusMsg)
+	}
+}